@@ -0,0 +1,60 @@
+-- Kasir, shift, laci kas, dan tender pembayaran
+
+CREATE TABLE IF NOT EXISTS cashiers (
+	id         SERIAL PRIMARY KEY,
+	name       TEXT NOT NULL,
+	role       TEXT NOT NULL DEFAULT 'cashier' CHECK (role IN ('cashier', 'manager')),
+	active     BOOLEAN NOT NULL DEFAULT TRUE,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS shifts (
+	id            SERIAL PRIMARY KEY,
+	cashier_id    INT NOT NULL REFERENCES cashiers(id),
+	terminal      TEXT NOT NULL DEFAULT '',
+	status        TEXT NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'closed')),
+	opening_float INT NOT NULL DEFAULT 0 CHECK (opening_float >= 0),
+	opened_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+	closed_at     TIMESTAMPTZ,
+	expected_cash INT,
+	counted_cash  INT,
+	over_short    INT,
+	notes         TEXT NOT NULL DEFAULT ''
+);
+
+-- satu kasir hanya boleh punya satu shift terbuka
+CREATE UNIQUE INDEX IF NOT EXISTS shifts_one_open_per_cashier
+	ON shifts (cashier_id) WHERE status = 'open';
+
+CREATE TABLE IF NOT EXISTS shift_cash_counts (
+	shift_id     INT NOT NULL REFERENCES shifts(id) ON DELETE CASCADE,
+	denomination INT NOT NULL CHECK (denomination > 0),
+	quantity     INT NOT NULL CHECK (quantity >= 0),
+	PRIMARY KEY (shift_id, denomination)
+);
+
+CREATE TABLE IF NOT EXISTS cash_movements (
+	id         SERIAL PRIMARY KEY,
+	shift_id   INT NOT NULL REFERENCES shifts(id) ON DELETE CASCADE,
+	type       TEXT NOT NULL CHECK (type IN ('pay_in', 'pay_out')),
+	amount     INT NOT NULL CHECK (amount > 0),
+	reason     TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+ALTER TABLE transactions
+	ADD COLUMN IF NOT EXISTS shift_id      INT REFERENCES shifts(id),
+	ADD COLUMN IF NOT EXISTS cashier_id    INT REFERENCES cashiers(id),
+	ADD COLUMN IF NOT EXISTS paid_amount   INT NOT NULL DEFAULT 0,
+	ADD COLUMN IF NOT EXISTS change_amount INT NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS transactions_shift_id_idx ON transactions (shift_id);
+
+CREATE TABLE IF NOT EXISTS transaction_payments (
+	id             SERIAL PRIMARY KEY,
+	transaction_id INT NOT NULL REFERENCES transactions(id) ON DELETE CASCADE,
+	method         TEXT NOT NULL,
+	amount         INT NOT NULL CHECK (amount >= 0)
+);
+
+CREATE INDEX IF NOT EXISTS transaction_payments_transaction_id_idx ON transaction_payments (transaction_id);
@@ -0,0 +1,60 @@
+-- Z-report: penutupan hari bisnis (immutable)
+
+CREATE TABLE IF NOT EXISTS z_reports (
+	z_number        INT PRIMARY KEY,
+	business_date   DATE NOT NULL UNIQUE,
+	period_start    TIMESTAMPTZ NOT NULL,
+	period_end      TIMESTAMPTZ NOT NULL,
+	total_sales     INT NOT NULL,
+	total_transaksi INT NOT NULL,
+	snapshot        JSONB NOT NULL,
+	closed_by       INT REFERENCES cashiers(id),
+	closed_at       TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE OR REPLACE FUNCTION z_reports_immutable() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'z_report % sudah final, tidak bisa diubah/dihapus', OLD.z_number;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS z_reports_immutable ON z_reports;
+CREATE TRIGGER z_reports_immutable
+	BEFORE UPDATE OR DELETE ON z_reports
+	FOR EACH ROW EXECUTE FUNCTION z_reports_immutable();
+
+-- Tolak insert/ubah/hapus transaksi yang jatuh di hari yang sudah di-Z
+CREATE OR REPLACE FUNCTION transactions_reject_closed_day() RETURNS trigger AS $$
+DECLARE
+	ts TIMESTAMPTZ;
+	z  INT;
+BEGIN
+	IF TG_OP = 'DELETE' THEN
+		ts := OLD.created_at;
+	ELSE
+		ts := NEW.created_at;
+	END IF;
+
+	SELECT z_number INTO z FROM z_reports WHERE period_start <= ts AND ts < period_end;
+	IF FOUND THEN
+		RAISE EXCEPTION 'hari bisnis sudah ditutup (Z #%)', z;
+	END IF;
+
+	IF TG_OP = 'UPDATE' THEN
+		SELECT z_number INTO z FROM z_reports WHERE period_start <= OLD.created_at AND OLD.created_at < period_end;
+		IF FOUND THEN
+			RAISE EXCEPTION 'hari bisnis sudah ditutup (Z #%)', z;
+		END IF;
+	END IF;
+
+	IF TG_OP = 'DELETE' THEN
+		RETURN OLD;
+	END IF;
+	RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS transactions_reject_closed_day ON transactions;
+CREATE TRIGGER transactions_reject_closed_day
+	BEFORE INSERT OR UPDATE OR DELETE ON transactions
+	FOR EACH ROW EXECUTE FUNCTION transactions_reject_closed_day();
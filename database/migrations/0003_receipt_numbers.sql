@@ -0,0 +1,14 @@
+-- Nomor struk berurutan per outlet per hari bisnis
+
+CREATE TABLE IF NOT EXISTS receipt_counters (
+	outlet_code   TEXT NOT NULL,
+	business_date DATE NOT NULL,
+	last_seq      INT NOT NULL,
+	PRIMARY KEY (outlet_code, business_date)
+);
+
+ALTER TABLE transactions
+	ADD COLUMN IF NOT EXISTS outlet_code TEXT,
+	ADD COLUMN IF NOT EXISTS receipt_no  TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS transactions_receipt_no_key ON transactions (receipt_no);
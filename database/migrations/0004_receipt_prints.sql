@@ -0,0 +1,10 @@
+-- Log cetak struk; cetakan ke-2 dst ditandai COPY
+
+CREATE TABLE IF NOT EXISTS receipt_prints (
+	id             SERIAL PRIMARY KEY,
+	transaction_id INT NOT NULL REFERENCES transactions(id) ON DELETE CASCADE,
+	print_no       INT NOT NULL,
+	format         TEXT NOT NULL,
+	printed_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+	UNIQUE (transaction_id, print_no)
+);
@@ -0,0 +1,18 @@
+-- Direktori pelanggan
+
+CREATE TABLE IF NOT EXISTS customers (
+	id         SERIAL PRIMARY KEY,
+	name       TEXT NOT NULL,
+	phone      TEXT NOT NULL UNIQUE,
+	email      TEXT NOT NULL DEFAULT '',
+	birthday   DATE,
+	notes      TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS customers_name_idx ON customers (lower(name));
+
+ALTER TABLE transactions
+	ADD COLUMN IF NOT EXISTS customer_id INT REFERENCES customers(id);
+
+CREATE INDEX IF NOT EXISTS transactions_customer_id_idx ON transactions (customer_id);
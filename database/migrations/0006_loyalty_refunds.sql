@@ -0,0 +1,55 @@
+-- Refund transaksi (penuh) + program poin loyalitas
+
+CREATE TABLE IF NOT EXISTS refunds (
+	id             SERIAL PRIMARY KEY,
+	transaction_id INT NOT NULL UNIQUE REFERENCES transactions(id),
+	shift_id       INT NOT NULL REFERENCES shifts(id),
+	cashier_id     INT NOT NULL REFERENCES cashiers(id),
+	total_amount   INT NOT NULL,
+	reason         TEXT NOT NULL DEFAULT '',
+	created_at     TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS refunds_shift_id_idx ON refunds (shift_id);
+CREATE INDEX IF NOT EXISTS refunds_created_at_idx ON refunds (created_at);
+
+CREATE TABLE IF NOT EXISTS refund_payments (
+	id        SERIAL PRIMARY KEY,
+	refund_id INT NOT NULL REFERENCES refunds(id) ON DELETE CASCADE,
+	method    TEXT NOT NULL,
+	amount    INT NOT NULL CHECK (amount >= 0)
+);
+
+CREATE TABLE IF NOT EXISTS loyalty_settings (
+	id               BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+	enabled          BOOLEAN NOT NULL DEFAULT TRUE,
+	amount_per_point INT NOT NULL DEFAULT 10000 CHECK (amount_per_point > 0),
+	point_value      INT NOT NULL DEFAULT 100 CHECK (point_value > 0),
+	expiry_days      INT NOT NULL DEFAULT 365 CHECK (expiry_days >= 0)
+);
+
+INSERT INTO loyalty_settings (id) VALUES (TRUE) ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS loyalty_rules (
+	id          SERIAL PRIMARY KEY,
+	name        TEXT NOT NULL,
+	category_id INT REFERENCES categories(id) ON DELETE CASCADE,
+	multiplier  NUMERIC(6,2) NOT NULL CHECK (multiplier > 0),
+	starts_at   TIMESTAMPTZ,
+	ends_at     TIMESTAMPTZ,
+	active      BOOLEAN NOT NULL DEFAULT TRUE
+);
+
+CREATE TABLE IF NOT EXISTS loyalty_ledger (
+	id             SERIAL PRIMARY KEY,
+	customer_id    INT NOT NULL REFERENCES customers(id),
+	transaction_id INT REFERENCES transactions(id),
+	type           TEXT NOT NULL CHECK (type IN ('earn', 'redeem', 'expire', 'reverse_earn', 'restore_redeem')),
+	points         INT NOT NULL,
+	remaining      INT NOT NULL DEFAULT 0 CHECK (remaining >= 0), -- sisa poin masuk yang belum terpakai (FIFO)
+	expires_at     TIMESTAMPTZ,
+	created_at     TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS loyalty_ledger_customer_idx ON loyalty_ledger (customer_id, expires_at);
+CREATE INDEX IF NOT EXISTS loyalty_ledger_transaction_idx ON loyalty_ledger (transaction_id);
@@ -0,0 +1,35 @@
+-- Price list: retail (harga umum + grosir bertingkat), member/wholesale (lewat pelanggan), per-outlet
+
+CREATE TABLE IF NOT EXISTS price_lists (
+	id          SERIAL PRIMARY KEY,
+	name        TEXT NOT NULL,
+	kind        TEXT NOT NULL CHECK (kind IN ('retail', 'member', 'wholesale', 'outlet')),
+	outlet_code TEXT,
+	active      BOOLEAN NOT NULL DEFAULT TRUE,
+	created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+	CHECK ((kind = 'outlet') = (outlet_code IS NOT NULL))
+);
+
+-- Maksimal satu price list retail dan satu price list per outlet
+CREATE UNIQUE INDEX IF NOT EXISTS price_lists_retail_uniq ON price_lists (kind) WHERE kind = 'retail';
+CREATE UNIQUE INDEX IF NOT EXISTS price_lists_outlet_uniq ON price_lists (outlet_code) WHERE kind = 'outlet';
+
+-- min_qty = batas bawah quantity break (1 = harga satuan biasa)
+CREATE TABLE IF NOT EXISTS price_list_items (
+	price_list_id INT NOT NULL REFERENCES price_lists(id) ON DELETE CASCADE,
+	product_id    INT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+	min_qty       INT NOT NULL DEFAULT 1 CHECK (min_qty > 0),
+	price         INT NOT NULL CHECK (price >= 0),
+	PRIMARY KEY (price_list_id, product_id, min_qty)
+);
+
+CREATE INDEX IF NOT EXISTS price_list_items_product_idx ON price_list_items (product_id);
+
+-- Tier member / grosir pelanggan
+ALTER TABLE customers
+	ADD COLUMN IF NOT EXISTS price_list_id INT REFERENCES price_lists(id) ON DELETE SET NULL;
+
+-- Harga satuan yang benar-benar dipakai saat checkout
+ALTER TABLE transaction_details
+	ADD COLUMN IF NOT EXISTS unit_price INT,
+	ADD COLUMN IF NOT EXISTS price_list_id INT REFERENCES price_lists(id) ON DELETE SET NULL;
@@ -0,0 +1,23 @@
+-- Kasbon (bayar nanti) + buku piutang pelanggan
+
+ALTER TABLE customers
+	ADD COLUMN IF NOT EXISTS credit_limit INT NOT NULL DEFAULT 0 CHECK (credit_limit >= 0);
+
+-- amount bertanda: charge (+) menambah utang, payment / refund (-) mengurangi.
+-- remaining hanya dipakai di baris charge: sisa utang per transaksi untuk aging (FIFO).
+CREATE TABLE IF NOT EXISTS receivable_entries (
+	id             SERIAL PRIMARY KEY,
+	customer_id    INT NOT NULL REFERENCES customers(id),
+	transaction_id INT REFERENCES transactions(id),
+	shift_id       INT REFERENCES shifts(id),
+	type           TEXT NOT NULL CHECK (type IN ('charge', 'payment', 'refund')),
+	method         TEXT NOT NULL DEFAULT '',
+	amount         INT NOT NULL,
+	remaining      INT NOT NULL DEFAULT 0 CHECK (remaining >= 0),
+	notes          TEXT NOT NULL DEFAULT '',
+	created_at     TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS receivable_entries_customer_idx ON receivable_entries (customer_id, created_at);
+CREATE INDEX IF NOT EXISTS receivable_entries_open_idx ON receivable_entries (customer_id) WHERE remaining > 0;
+CREATE INDEX IF NOT EXISTS receivable_entries_shift_idx ON receivable_entries (shift_id);
@@ -0,0 +1,31 @@
+-- Gift card / saldo tersimpan
+
+CREATE TABLE IF NOT EXISTS gift_cards (
+	id          SERIAL PRIMARY KEY,
+	code        TEXT NOT NULL UNIQUE,
+	balance     INT NOT NULL DEFAULT 0 CHECK (balance >= 0),
+	customer_id INT REFERENCES customers(id),
+	active      BOOLEAN NOT NULL DEFAULT TRUE,
+	expires_at  TIMESTAMPTZ,
+	created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- amount bertanda: issue/topup/refund (+), redeem (-)
+CREATE TABLE IF NOT EXISTS gift_card_entries (
+	id             SERIAL PRIMARY KEY,
+	gift_card_id   INT NOT NULL REFERENCES gift_cards(id),
+	transaction_id INT REFERENCES transactions(id),
+	shift_id       INT REFERENCES shifts(id),
+	type           TEXT NOT NULL CHECK (type IN ('issue', 'topup', 'redeem', 'refund')),
+	method         TEXT NOT NULL DEFAULT '',
+	amount         INT NOT NULL,
+	created_at     TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS gift_card_entries_card_idx ON gift_card_entries (gift_card_id, id);
+CREATE INDEX IF NOT EXISTS gift_card_entries_tx_idx ON gift_card_entries (transaction_id);
+CREATE INDEX IF NOT EXISTS gift_card_entries_shift_idx ON gift_card_entries (shift_id);
+
+-- Referensi tender (mis. kode gift card yang disamarkan)
+ALTER TABLE transaction_payments
+	ADD COLUMN IF NOT EXISTS reference TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,43 @@
+-- Voucher diskon + cart server-side (bisa di-park & dilanjutkan di terminal lain)
+
+CREATE TABLE IF NOT EXISTS vouchers (
+	id           SERIAL PRIMARY KEY,
+	code         TEXT NOT NULL UNIQUE,
+	name         TEXT NOT NULL DEFAULT '',
+	type         TEXT NOT NULL CHECK (type IN ('percent', 'fixed')),
+	value        INT NOT NULL CHECK (value > 0),
+	min_spend    INT NOT NULL DEFAULT 0 CHECK (min_spend >= 0),
+	max_discount INT NOT NULL DEFAULT 0 CHECK (max_discount >= 0), -- 0 = tanpa batas
+	starts_at    TIMESTAMPTZ,
+	ends_at      TIMESTAMPTZ,
+	usage_limit  INT NOT NULL DEFAULT 0 CHECK (usage_limit >= 0),  -- 0 = tanpa batas
+	used_count   INT NOT NULL DEFAULT 0 CHECK (used_count >= 0),
+	active       BOOLEAN NOT NULL DEFAULT TRUE,
+	created_at   TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+ALTER TABLE transactions
+	ADD COLUMN IF NOT EXISTS discount_amount INT NOT NULL DEFAULT 0,
+	ADD COLUMN IF NOT EXISTS voucher_code TEXT;
+
+CREATE TABLE IF NOT EXISTS carts (
+	id             SERIAL PRIMARY KEY,
+	status         TEXT NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'parked', 'checked_out', 'cancelled')),
+	terminal       TEXT NOT NULL DEFAULT '',
+	customer_id    INT REFERENCES customers(id),
+	voucher_code   TEXT NOT NULL DEFAULT '',
+	note           TEXT NOT NULL DEFAULT '',
+	transaction_id INT REFERENCES transactions(id),
+	created_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+	updated_at     TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS carts_status_idx ON carts (status);
+
+CREATE TABLE IF NOT EXISTS cart_items (
+	cart_id    INT NOT NULL REFERENCES carts(id) ON DELETE CASCADE,
+	product_id INT NOT NULL REFERENCES products(id),
+	quantity   INT NOT NULL CHECK (quantity > 0),
+	added_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+	PRIMARY KEY (cart_id, product_id)
+);
@@ -0,0 +1,34 @@
+-- Override harga / diskon per baris dengan approval manager + audit trail
+
+ALTER TABLE cashiers
+	ADD COLUMN IF NOT EXISTS pin_hash TEXT NOT NULL DEFAULT '';
+
+-- Token approval sekali pakai yang dibuat manager dari PIN-nya
+CREATE TABLE IF NOT EXISTS approval_tokens (
+	token      TEXT PRIMARY KEY,
+	manager_id INT NOT NULL REFERENCES cashiers(id),
+	expires_at TIMESTAMPTZ NOT NULL,
+	used_at    TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+ALTER TABLE transaction_details
+	ADD COLUMN IF NOT EXISTS discount_amount INT NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS price_overrides (
+	id                    SERIAL PRIMARY KEY,
+	transaction_id        INT NOT NULL REFERENCES transactions(id),
+	transaction_detail_id INT NOT NULL REFERENCES transaction_details(id),
+	product_id            INT NOT NULL,
+	quantity              INT NOT NULL,
+	cashier_id            INT NOT NULL REFERENCES cashiers(id),
+	approved_by           INT NOT NULL REFERENCES cashiers(id),
+	original_price        INT NOT NULL,
+	override_price        INT NOT NULL,
+	discount_amount       INT NOT NULL DEFAULT 0,
+	reason                TEXT NOT NULL DEFAULT '',
+	created_at            TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS price_overrides_created_at_idx ON price_overrides (created_at);
+CREATE INDEX IF NOT EXISTS price_overrides_approved_by_idx ON price_overrides (approved_by);
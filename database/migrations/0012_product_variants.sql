@@ -0,0 +1,15 @@
+-- Varian produk: varian = baris products dengan parent_id, punya SKU/barcode/harga/stok sendiri
+
+ALTER TABLE products
+	ADD COLUMN IF NOT EXISTS parent_id  INT REFERENCES products(id) ON DELETE CASCADE,
+	ADD COLUMN IF NOT EXISTS sku        TEXT,
+	ADD COLUMN IF NOT EXISTS barcode    TEXT,
+	-- atribut varian, mis. {"size": "L", "color": "Hitam"}
+	ADD COLUMN IF NOT EXISTS attributes JSONB NOT NULL DEFAULT '{}',
+	-- nama atribut yang dipakai varian di produk induk, mis. {size,color}
+	ADD COLUMN IF NOT EXISTS options    TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE UNIQUE INDEX IF NOT EXISTS products_sku_uniq ON products (sku) WHERE sku IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS products_barcode_uniq ON products (barcode) WHERE barcode IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS products_variant_attributes_uniq ON products (parent_id, attributes) WHERE parent_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS products_parent_id_idx ON products (parent_id);
@@ -0,0 +1,45 @@
+-- Modifier / add-on F&B: "extra shot +5000", "less sugar", "oat milk +7000"
+
+CREATE TABLE IF NOT EXISTS modifier_groups (
+	id         SERIAL PRIMARY KEY,
+	name       TEXT NOT NULL,
+	required   BOOLEAN NOT NULL DEFAULT FALSE,
+	min_select INT NOT NULL DEFAULT 0,
+	max_select INT NOT NULL DEFAULT 0, -- 0 = tanpa batas
+	active     BOOLEAN NOT NULL DEFAULT TRUE,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Modifier tidak pernah dihapus (dipakai histori transaksi), cukup dinonaktifkan
+CREATE TABLE IF NOT EXISTS modifiers (
+	id          SERIAL PRIMARY KEY,
+	group_id    INT NOT NULL REFERENCES modifier_groups(id) ON DELETE CASCADE,
+	name        TEXT NOT NULL,
+	price_delta INT NOT NULL DEFAULT 0,
+	-- opsional: modifier memotong stok produk lain (mis. oat milk) sebanyak stock_qty per item
+	product_id  INT REFERENCES products(id) ON DELETE SET NULL,
+	stock_qty   INT NOT NULL DEFAULT 1,
+	position    INT NOT NULL DEFAULT 0,
+	active      BOOLEAN NOT NULL DEFAULT TRUE
+);
+
+CREATE TABLE IF NOT EXISTS product_modifier_groups (
+	product_id INT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+	group_id   INT NOT NULL REFERENCES modifier_groups(id) ON DELETE CASCADE,
+	PRIMARY KEY (product_id, group_id)
+);
+
+-- Snapshot modifier per baris transaksi (nama & harga saat dijual)
+CREATE TABLE IF NOT EXISTS transaction_detail_modifiers (
+	id                    SERIAL PRIMARY KEY,
+	transaction_detail_id INT NOT NULL REFERENCES transaction_details(id) ON DELETE CASCADE,
+	modifier_id           INT REFERENCES modifiers(id) ON DELETE SET NULL,
+	group_name            TEXT NOT NULL DEFAULT '',
+	name                  TEXT NOT NULL,
+	price_delta           INT NOT NULL DEFAULT 0,
+	quantity              INT NOT NULL, -- = qty baris
+	stock_product_id      INT,
+	stock_qty             INT NOT NULL DEFAULT 0 -- total stok yang dipotong
+);
+
+CREATE INDEX IF NOT EXISTS transaction_detail_modifiers_detail_idx ON transaction_detail_modifiers (transaction_detail_id);
@@ -0,0 +1,26 @@
+-- Resep / BOM: produk komposit memotong stok bahan, bukan stok barang jadi
+
+-- Satuan stok bahan (g, ml, pcs). Stok & qty resep disimpan dalam satuan terkecil.
+ALTER TABLE products
+	ADD COLUMN IF NOT EXISTS unit TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS product_recipes (
+	product_id    INT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+	ingredient_id INT NOT NULL REFERENCES products(id),
+	quantity      INT NOT NULL CHECK (quantity > 0), -- per 1 produk
+	PRIMARY KEY (product_id, ingredient_id),
+	CHECK (product_id <> ingredient_id)
+);
+
+CREATE INDEX IF NOT EXISTS product_recipes_ingredient_idx ON product_recipes (ingredient_id);
+
+-- Pemakaian bahan per baris transaksi (untuk laporan & pengembalian saat refund)
+CREATE TABLE IF NOT EXISTS transaction_ingredient_usage (
+	id                    SERIAL PRIMARY KEY,
+	transaction_id        INT NOT NULL REFERENCES transactions(id),
+	transaction_detail_id INT NOT NULL REFERENCES transaction_details(id) ON DELETE CASCADE,
+	ingredient_id         INT NOT NULL,
+	quantity              INT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS transaction_ingredient_usage_tx_idx ON transaction_ingredient_usage (transaction_id);
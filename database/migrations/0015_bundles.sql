@@ -0,0 +1,27 @@
+-- Bundle / paket: satu item jual berisi beberapa produk, stok dipotong per komponen
+
+CREATE TABLE IF NOT EXISTS bundle_items (
+	bundle_id  INT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+	product_id INT NOT NULL REFERENCES products(id),
+	quantity   INT NOT NULL CHECK (quantity > 0), -- per 1 bundle
+	PRIMARY KEY (bundle_id, product_id),
+	CHECK (bundle_id <> product_id)
+);
+
+CREATE INDEX IF NOT EXISTS bundle_items_product_idx ON bundle_items (product_id);
+
+-- Pemakaian stok per baris sekarang juga mencatat komponen bundle
+ALTER TABLE transaction_ingredient_usage
+	ADD COLUMN IF NOT EXISTS source TEXT NOT NULL DEFAULT 'recipe'; -- recipe | bundle
+
+-- Pendapatan baris bundle dibagi proporsional ke komponennya (untuk laporan per produk)
+CREATE TABLE IF NOT EXISTS transaction_bundle_allocations (
+	id                    SERIAL PRIMARY KEY,
+	transaction_id        INT NOT NULL REFERENCES transactions(id),
+	transaction_detail_id INT NOT NULL REFERENCES transaction_details(id) ON DELETE CASCADE,
+	product_id            INT NOT NULL,
+	quantity              INT NOT NULL,
+	amount                INT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS transaction_bundle_allocations_tx_idx ON transaction_bundle_allocations (transaction_id);
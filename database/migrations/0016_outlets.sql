@@ -0,0 +1,100 @@
+-- Multi-outlet: outlet sebagai entitas, stok per outlet, harga per outlet (opsional)
+
+CREATE TABLE IF NOT EXISTS outlets (
+	id         SERIAL PRIMARY KEY,
+	code       TEXT NOT NULL UNIQUE, -- dipakai di nomor struk & price list outlet
+	name       TEXT NOT NULL,
+	address    TEXT NOT NULL DEFAULT '',
+	active     BOOLEAN NOT NULL DEFAULT TRUE,
+	is_default BOOLEAN NOT NULL DEFAULT FALSE,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Tepat satu outlet default (tujuan stok lama & shift tanpa outlet_id)
+CREATE UNIQUE INDEX IF NOT EXISTS outlets_default_uniq ON outlets (is_default) WHERE is_default;
+
+-- Outlet awal diambil dari kode outlet yang sudah dipakai di struk / price list
+INSERT INTO outlets (code, name)
+SELECT code, code FROM (
+	SELECT outlet_code AS code FROM transactions WHERE outlet_code IS NOT NULL
+	UNION
+	SELECT outlet_code FROM price_lists WHERE outlet_code IS NOT NULL
+) c
+ON CONFLICT (code) DO NOTHING;
+
+INSERT INTO outlets (code, name)
+SELECT 'OUTLET01', 'OUTLET01' WHERE NOT EXISTS (SELECT 1 FROM outlets);
+
+UPDATE outlets SET is_default = TRUE
+WHERE id = (
+	SELECT COALESCE(
+		(SELECT o.id FROM transactions t JOIN outlets o ON o.code = t.outlet_code ORDER BY t.id DESC LIMIT 1),
+		(SELECT MIN(id) FROM outlets))
+)
+AND NOT EXISTS (SELECT 1 FROM outlets WHERE is_default);
+
+-- Stok per outlet; price NULL = pakai products.price
+CREATE TABLE IF NOT EXISTS outlet_products (
+	outlet_id  INT NOT NULL REFERENCES outlets(id) ON DELETE CASCADE,
+	product_id INT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+	stock      INT NOT NULL DEFAULT 0,
+	price      INT CHECK (price >= 0),
+	PRIMARY KEY (outlet_id, product_id)
+);
+
+CREATE INDEX IF NOT EXISTS outlet_products_product_idx ON outlet_products (product_id);
+
+-- Stok global lama disalin ke outlet default (sekali, selama outlet_products masih kosong).
+-- products.stock tidak dihapus supaya migrasi bisa dibatalkan, tapi tidak dipakai lagi.
+DO $$
+BEGIN
+	IF EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'products' AND column_name = 'stock')
+	   AND NOT EXISTS (SELECT 1 FROM outlet_products) THEN
+		INSERT INTO outlet_products (outlet_id, product_id, stock)
+		SELECT o.id, p.id, p.stock FROM products p, outlets o WHERE o.is_default
+		ON CONFLICT (outlet_id, product_id) DO NOTHING;
+	END IF;
+	IF EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'products' AND column_name = 'stock') THEN
+		ALTER TABLE products ALTER COLUMN stock SET DEFAULT 0;
+	END IF;
+END $$;
+
+-- Stok produk di satu outlet; outlet NULL = jumlah semua outlet
+CREATE OR REPLACE FUNCTION outlet_stock(p_product INT, p_outlet INT) RETURNS INT
+LANGUAGE sql STABLE AS $$
+	SELECT COALESCE(SUM(stock), 0)::int FROM outlet_products
+	WHERE product_id = p_product AND (p_outlet IS NULL OR outlet_id = p_outlet)
+$$;
+
+-- Kasir boleh terikat ke satu outlet (NULL = bisa buka shift di outlet mana saja)
+ALTER TABLE cashiers
+	ADD COLUMN IF NOT EXISTS outlet_id INT REFERENCES outlets(id) ON DELETE SET NULL;
+
+ALTER TABLE shifts
+	ADD COLUMN IF NOT EXISTS outlet_id INT REFERENCES outlets(id);
+
+UPDATE shifts SET outlet_id = (SELECT id FROM outlets WHERE is_default) WHERE outlet_id IS NULL;
+
+ALTER TABLE shifts ALTER COLUMN outlet_id SET NOT NULL;
+
+ALTER TABLE transactions
+	ADD COLUMN IF NOT EXISTS outlet_id INT REFERENCES outlets(id);
+
+-- Backfill hari yang sudah di-Z juga: trigger penolak dimatikan sementara
+ALTER TABLE transactions DISABLE TRIGGER transactions_reject_closed_day;
+
+UPDATE transactions t SET outlet_id = COALESCE(
+	(SELECT o.id FROM outlets o WHERE o.code = t.outlet_code),
+	(SELECT id FROM outlets WHERE is_default))
+WHERE t.outlet_id IS NULL;
+
+ALTER TABLE transactions ENABLE TRIGGER transactions_reject_closed_day;
+
+CREATE INDEX IF NOT EXISTS transactions_outlet_created_idx ON transactions (outlet_id, created_at);
+
+ALTER TABLE carts
+	ADD COLUMN IF NOT EXISTS outlet_id INT REFERENCES outlets(id);
+
+UPDATE carts SET outlet_id = (SELECT id FROM outlets WHERE is_default) WHERE outlet_id IS NULL;
+
+ALTER TABLE carts ALTER COLUMN outlet_id SET NOT NULL;
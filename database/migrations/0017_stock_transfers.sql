@@ -0,0 +1,39 @@
+-- Transfer stok antar outlet (draft -> sent -> received) + buku mutasi stok
+
+CREATE TABLE IF NOT EXISTS stock_transfers (
+	id             SERIAL PRIMARY KEY,
+	from_outlet_id INT NOT NULL REFERENCES outlets(id),
+	to_outlet_id   INT NOT NULL REFERENCES outlets(id),
+	status         TEXT NOT NULL DEFAULT 'draft' CHECK (status IN ('draft', 'sent', 'received', 'cancelled')),
+	note           TEXT NOT NULL DEFAULT '',
+	created_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+	sent_at        TIMESTAMPTZ,
+	received_at    TIMESTAMPTZ,
+	CHECK (from_outlet_id <> to_outlet_id)
+);
+
+CREATE INDEX IF NOT EXISTS stock_transfers_status_idx ON stock_transfers (status);
+
+-- received_quantity terisi saat diterima; selisih dengan quantity = barang hilang/rusak di jalan
+CREATE TABLE IF NOT EXISTS stock_transfer_items (
+	transfer_id       INT NOT NULL REFERENCES stock_transfers(id) ON DELETE CASCADE,
+	product_id        INT NOT NULL REFERENCES products(id),
+	quantity          INT NOT NULL CHECK (quantity > 0),
+	received_quantity INT CHECK (received_quantity >= 0),
+	note              TEXT NOT NULL DEFAULT '',
+	PRIMARY KEY (transfer_id, product_id)
+);
+
+-- Mutasi stok per outlet (quantity bertanda: + masuk, - keluar)
+CREATE TABLE IF NOT EXISTS stock_movements (
+	id          SERIAL PRIMARY KEY,
+	outlet_id   INT NOT NULL REFERENCES outlets(id),
+	product_id  INT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+	quantity    INT NOT NULL,
+	kind        TEXT NOT NULL, -- transfer_out | transfer_in | adjustment | receipt (0020) | sale | refund (0024)
+	transfer_id INT REFERENCES stock_transfers(id),
+	note        TEXT NOT NULL DEFAULT '',
+	created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS stock_movements_outlet_product_idx ON stock_movements (outlet_id, product_id, created_at);
@@ -0,0 +1,72 @@
+-- Zona waktu & jam tutup buku per outlet. Hari bisnis dihitung di jam lokal outlet:
+-- dengan day_cutoff_hour = 2, transaksi jam 01:30 masih masuk hari sebelumnya.
+
+ALTER TABLE outlets
+	ADD COLUMN IF NOT EXISTS timezone        TEXT NOT NULL DEFAULT 'Asia/Jakarta',
+	ADD COLUMN IF NOT EXISTS day_cutoff_hour INT  NOT NULL DEFAULT 0 CHECK (day_cutoff_hour BETWEEN 0 AND 23);
+
+-- Hari bisnis waktu ts di outlet
+CREATE OR REPLACE FUNCTION outlet_business_date(ts TIMESTAMPTZ, outlet INT) RETURNS DATE AS $$
+	SELECT ((ts AT TIME ZONE o.timezone) - make_interval(hours => o.day_cutoff_hour))::date
+	FROM outlets o WHERE o.id = outlet
+$$ LANGUAGE sql STABLE;
+
+-- Awal hari bisnis d di outlet
+CREATE OR REPLACE FUNCTION outlet_day_start(d DATE, outlet INT) RETURNS TIMESTAMPTZ AS $$
+	SELECT (d + make_interval(hours => o.day_cutoff_hour)) AT TIME ZONE o.timezone
+	FROM outlets o WHERE o.id = outlet
+$$ LANGUAGE sql STABLE;
+
+-- Hari bisnis disimpan di transaksi & refund (refund ikut outlet transaksi aslinya),
+-- laporan dan Z-report memfilter lewat kolom ini.
+ALTER TABLE transactions
+	ADD COLUMN IF NOT EXISTS business_date DATE;
+
+ALTER TABLE transactions DISABLE TRIGGER transactions_reject_closed_day;
+
+UPDATE transactions SET business_date = outlet_business_date(created_at, outlet_id)
+WHERE business_date IS NULL;
+
+ALTER TABLE transactions ENABLE TRIGGER transactions_reject_closed_day;
+
+ALTER TABLE transactions ALTER COLUMN business_date SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS transactions_business_date_idx ON transactions (business_date, outlet_id);
+
+ALTER TABLE refunds
+	ADD COLUMN IF NOT EXISTS business_date DATE;
+
+UPDATE refunds r SET business_date = outlet_business_date(r.created_at, t.outlet_id)
+FROM transactions t
+WHERE t.id = r.transaction_id AND r.business_date IS NULL;
+
+ALTER TABLE refunds ALTER COLUMN business_date SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS refunds_business_date_idx ON refunds (business_date);
+
+-- Penolakan hari yang sudah di-Z sekarang berdasarkan hari bisnis outlet, bukan periode jam server
+CREATE OR REPLACE FUNCTION transactions_reject_closed_day() RETURNS trigger AS $$
+DECLARE
+	z INT;
+BEGIN
+	IF TG_OP <> 'DELETE' THEN
+		NEW.business_date := COALESCE(NEW.business_date, outlet_business_date(NEW.created_at, NEW.outlet_id));
+		SELECT z_number INTO z FROM z_reports WHERE business_date = NEW.business_date;
+		IF FOUND THEN
+			RAISE EXCEPTION 'hari bisnis sudah ditutup (Z #%)', z;
+		END IF;
+	END IF;
+
+	IF TG_OP <> 'INSERT' THEN
+		SELECT z_number INTO z FROM z_reports WHERE business_date = OLD.business_date;
+		IF FOUND THEN
+			RAISE EXCEPTION 'hari bisnis sudah ditutup (Z #%)', z;
+		END IF;
+	END IF;
+
+	IF TG_OP = 'DELETE' THEN
+		RETURN OLD;
+	END IF;
+	RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
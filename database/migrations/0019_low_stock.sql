@@ -0,0 +1,17 @@
+-- Reorder point per produk + saran pesan ulang dari kecepatan jual
+
+ALTER TABLE products
+	ADD COLUMN IF NOT EXISTS reorder_point  INT CHECK (reorder_point >= 0), -- NULL = tanpa alert
+	ADD COLUMN IF NOT EXISTS lead_time_days INT NOT NULL DEFAULT 0 CHECK (lead_time_days >= 0);
+
+-- Diisi job latar belakang: rata-rata stok terjual per hari di tiap outlet dan
+-- target stok (reorder point + kebutuhan selama lead time & periode cover).
+-- Saran pesan = target_stock - stok sekarang.
+CREATE TABLE IF NOT EXISTS stock_reorder_suggestions (
+	outlet_id       INT NOT NULL REFERENCES outlets(id) ON DELETE CASCADE,
+	product_id      INT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+	avg_daily_sales NUMERIC(12, 2) NOT NULL,
+	target_stock    INT NOT NULL,
+	computed_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+	PRIMARY KEY (outlet_id, product_id)
+);
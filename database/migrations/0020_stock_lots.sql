@@ -0,0 +1,54 @@
+-- Lot / batch & tanggal kedaluwarsa. Untuk produk track_lots, outlet_products.stock = jumlah
+-- quantity semua lot di outlet itu; penjualan memotong lot yang paling cepat kedaluwarsa (FEFO).
+
+ALTER TABLE products
+	ADD COLUMN IF NOT EXISTS track_lots BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS stock_lots (
+	id          SERIAL PRIMARY KEY,
+	outlet_id   INT NOT NULL REFERENCES outlets(id),
+	product_id  INT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+	lot_number  TEXT NOT NULL,
+	expiry_date DATE, -- NULL = tidak kedaluwarsa
+	quantity    INT NOT NULL DEFAULT 0 CHECK (quantity >= 0),
+	received_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	UNIQUE (outlet_id, product_id, lot_number)
+);
+
+CREATE INDEX IF NOT EXISTS stock_lots_expiry_idx ON stock_lots (expiry_date) WHERE quantity > 0;
+
+-- Penerimaan barang dari supplier
+CREATE TABLE IF NOT EXISTS goods_receipts (
+	id         SERIAL PRIMARY KEY,
+	outlet_id  INT NOT NULL REFERENCES outlets(id),
+	supplier   TEXT NOT NULL DEFAULT '',
+	note       TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS goods_receipt_items (
+	receipt_id  INT NOT NULL REFERENCES goods_receipts(id) ON DELETE CASCADE,
+	line_no     INT NOT NULL,
+	product_id  INT NOT NULL REFERENCES products(id),
+	quantity    INT NOT NULL CHECK (quantity > 0),
+	lot_id      INT REFERENCES stock_lots(id),
+	lot_number  TEXT NOT NULL DEFAULT '',
+	expiry_date DATE,
+	PRIMARY KEY (receipt_id, line_no)
+);
+
+-- Lot yang dipotong checkout (dikembalikan saat refund) dan dikirim lewat transfer
+CREATE TABLE IF NOT EXISTS transaction_lot_usage (
+	transaction_id INT NOT NULL REFERENCES transactions(id) ON DELETE CASCADE,
+	lot_id         INT NOT NULL REFERENCES stock_lots(id),
+	quantity       INT NOT NULL CHECK (quantity > 0),
+	PRIMARY KEY (transaction_id, lot_id)
+);
+
+CREATE TABLE IF NOT EXISTS stock_transfer_lots (
+	transfer_id INT NOT NULL REFERENCES stock_transfers(id) ON DELETE CASCADE,
+	lot_id      INT NOT NULL REFERENCES stock_lots(id),
+	product_id  INT NOT NULL REFERENCES products(id),
+	quantity    INT NOT NULL CHECK (quantity > 0),
+	PRIMARY KEY (transfer_id, lot_id)
+);
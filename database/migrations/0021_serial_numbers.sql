@@ -0,0 +1,45 @@
+-- Serial number / IMEI per unit. Untuk produk track_serials, outlet_products.stock = jumlah
+-- serial berstatus in_stock di outlet itu; checkout wajib menyebut serial yang dijual.
+
+ALTER TABLE products
+	ADD COLUMN IF NOT EXISTS track_serials BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS product_serials (
+	id            SERIAL PRIMARY KEY,
+	product_id    INT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+	serial_number TEXT NOT NULL,
+	outlet_id     INT NOT NULL REFERENCES outlets(id), -- outlet terakhir unit ini berada
+	status        TEXT NOT NULL DEFAULT 'in_stock' CHECK (status IN ('in_stock', 'sold')),
+	received_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+	UNIQUE (product_id, serial_number)
+);
+
+CREATE INDEX IF NOT EXISTS product_serials_number_idx ON product_serials (serial_number);
+
+-- Serial yang terjual di tiap baris transaksi
+CREATE TABLE IF NOT EXISTS transaction_serials (
+	transaction_id        INT NOT NULL REFERENCES transactions(id) ON DELETE CASCADE,
+	transaction_detail_id INT NOT NULL REFERENCES transaction_details(id) ON DELETE CASCADE,
+	serial_id             INT NOT NULL REFERENCES product_serials(id) ON DELETE CASCADE,
+	PRIMARY KEY (transaction_id, serial_id)
+);
+
+-- Riwayat satu unit: diterima, terjual, dikembalikan
+CREATE TABLE IF NOT EXISTS serial_events (
+	id               SERIAL PRIMARY KEY,
+	serial_id        INT NOT NULL REFERENCES product_serials(id) ON DELETE CASCADE,
+	kind             TEXT NOT NULL CHECK (kind IN ('received', 'sold', 'returned')),
+	outlet_id        INT NOT NULL REFERENCES outlets(id),
+	transaction_id   INT REFERENCES transactions(id) ON DELETE SET NULL,
+	goods_receipt_id INT REFERENCES goods_receipts(id) ON DELETE SET NULL,
+	created_at       TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS serial_events_serial_idx ON serial_events (serial_id, id);
+
+ALTER TABLE goods_receipt_items
+	ADD COLUMN IF NOT EXISTS serial_numbers TEXT[] NOT NULL DEFAULT '{}';
+
+-- Cart menyimpan serial yang sudah di-scan kasir untuk baris itu
+ALTER TABLE cart_items
+	ADD COLUMN IF NOT EXISTS serial_numbers TEXT[] NOT NULL DEFAULT '{}';
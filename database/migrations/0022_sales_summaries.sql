@@ -0,0 +1,31 @@
+-- Ringkasan penjualan harian untuk laporan rentang panjang. Diisi job latar belakang
+-- (lihat SALES_SUMMARY_INTERVAL) dan `go run . rebuild-summaries`; laporan membaca ringkasan
+-- untuk hari bisnis < summarized_until outlet itu dan data mentah untuk sisanya.
+
+CREATE TABLE IF NOT EXISTS daily_sales (
+	business_date DATE NOT NULL,
+	outlet_id     INT NOT NULL REFERENCES outlets(id) ON DELETE CASCADE,
+	transactions  INT NOT NULL,
+	total_amount  BIGINT NOT NULL, -- sebelum refund, sama seperti /api/report
+	items         INT NOT NULL,    -- jumlah quantity baris transaksi
+	PRIMARY KEY (business_date, outlet_id)
+);
+
+-- Per produk: paket dipecah ke komponennya (pendapatan yang sudah dialokasikan), varian tetap per varian
+CREATE TABLE IF NOT EXISTS daily_product_sales (
+	business_date DATE NOT NULL,
+	outlet_id     INT NOT NULL REFERENCES outlets(id) ON DELETE CASCADE,
+	product_id    INT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+	quantity      INT NOT NULL,
+	amount        BIGINT NOT NULL, -- subtotal baris, sebelum diskon voucher
+	PRIMARY KEY (business_date, outlet_id, product_id)
+);
+
+CREATE INDEX IF NOT EXISTS daily_product_sales_product_idx ON daily_product_sales (product_id, business_date);
+
+-- Semua hari bisnis outlet sebelum summarized_until sudah ada di ringkasan
+CREATE TABLE IF NOT EXISTS sales_summary_state (
+	outlet_id        INT PRIMARY KEY REFERENCES outlets(id) ON DELETE CASCADE,
+	summarized_until DATE,
+	updated_at       TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1,15 @@
+-- Snapshot Z per outlet, dibuat bersamaan dengan z_reports supaya Z per outlet
+-- tidak dihitung ulang dari data yang bisa berubah (nama produk, cart dibatalkan belakangan).
+-- Z yang dibuat sebelum migrasi ini hanya punya rekap per_outlet di snapshot utamanya.
+
+CREATE TABLE IF NOT EXISTS z_report_outlets (
+	z_number  INT NOT NULL REFERENCES z_reports(z_number),
+	outlet_id INT NOT NULL REFERENCES outlets(id),
+	snapshot  JSONB NOT NULL,
+	PRIMARY KEY (z_number, outlet_id)
+);
+
+DROP TRIGGER IF EXISTS z_report_outlets_immutable ON z_report_outlets;
+CREATE TRIGGER z_report_outlets_immutable
+	BEFORE UPDATE OR DELETE ON z_report_outlets
+	FOR EACH ROW EXECUTE FUNCTION z_reports_immutable();
@@ -0,0 +1,6 @@
+-- Penjualan dan refund ikut dicatat di buku mutasi stok (kind sale / refund),
+-- ditautkan ke transaksinya.
+
+ALTER TABLE stock_movements ADD COLUMN IF NOT EXISTS transaction_id INT REFERENCES transactions(id);
+
+CREATE INDEX IF NOT EXISTS stock_movements_transaction_idx ON stock_movements (transaction_id) WHERE transaction_id IS NOT NULL;
@@ -0,0 +1,15 @@
+-- Baris cart punya id sendiri supaya satu produk bisa muncul di beberapa baris
+-- dengan modifier berbeda (mis. kopi less sugar + kopi extra shot).
+
+ALTER TABLE cart_items
+	ADD COLUMN IF NOT EXISTS modifiers INT[] NOT NULL DEFAULT '{}'; -- id modifier, urut, boleh berulang
+
+DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'cart_items' AND column_name = 'id') THEN
+		ALTER TABLE cart_items DROP CONSTRAINT cart_items_pkey;
+		ALTER TABLE cart_items ADD COLUMN id SERIAL PRIMARY KEY;
+	END IF;
+END $$;
+
+CREATE INDEX IF NOT EXISTS cart_items_cart_idx ON cart_items (cart_id, added_at, id);
@@ -0,0 +1,16 @@
+-- Proteksi brute-force PIN manager: percobaan gagal dihitung per manager,
+-- setelah beberapa kali salah PIN dikunci sementara. Semua percobaan gagal masuk audit trail.
+
+ALTER TABLE cashiers
+	ADD COLUMN IF NOT EXISTS pin_failed_attempts INT NOT NULL DEFAULT 0,
+	ADD COLUMN IF NOT EXISTS pin_locked_until    TIMESTAMPTZ;
+
+CREATE TABLE IF NOT EXISTS approval_failures (
+	id         SERIAL PRIMARY KEY,
+	manager_id INT NOT NULL REFERENCES cashiers(id),
+	source     TEXT NOT NULL,                  -- approvals | checkout | cart_checkout
+	locked     BOOLEAN NOT NULL DEFAULT FALSE, -- PIN sedang / jadi terkunci karena percobaan ini
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS approval_failures_created_at_idx ON approval_failures (created_at);
@@ -0,0 +1,6 @@
+-- Override harga / diskon baris di cart, approval manager diminta saat checkout cart
+
+ALTER TABLE cart_items
+	ADD COLUMN IF NOT EXISTS price_override INT CHECK (price_override >= 0),
+	ADD COLUMN IF NOT EXISTS discount       INT NOT NULL DEFAULT 0 CHECK (discount >= 0),
+	ADD COLUMN IF NOT EXISTS reason         TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"encoding/json"
+	"kasir-api/models"
+	"kasir-api/services"
+	"net/http"
+	"strconv"
+)
+
+type ApprovalHandler struct {
+	service *services.ApprovalService
+}
+
+func NewApprovalHandler(service *services.ApprovalService) *ApprovalHandler {
+	return &ApprovalHandler{service: service}
+}
+
+// POST /api/approvals {manager_id, pin} -> token sekali pakai
+func (h *ApprovalHandler) HandleApprovals(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req models.Approval
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	t, err := h.service.IssueToken(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(t)
+}
+
+// GET /api/price-overrides?from=&to=&cashier_id=&approved_by=
+func (h *ApprovalHandler) HandlePriceOverrides(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	cashierID, approvedBy := 0, 0
+	if v := q.Get("cashier_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "Invalid cashier_id", http.StatusBadRequest)
+			return
+		}
+		cashierID = id
+	}
+	if v := q.Get("approved_by"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "Invalid approved_by", http.StatusBadRequest)
+			return
+		}
+		approvedBy = id
+	}
+
+	data, err := h.service.PriceOverrides(q.Get("from"), q.Get("to"), cashierID, approvedBy)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(data)
+}
+
+// GET /api/approval-failures?from=&to=&manager_id=
+func (h *ApprovalHandler) HandleFailures(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	managerID := 0
+	if v := q.Get("manager_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "Invalid manager_id", http.StatusBadRequest)
+			return
+		}
+		managerID = id
+	}
+
+	data, err := h.service.Failures(q.Get("from"), q.Get("to"), managerID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(data)
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"encoding/json"
+	"kasir-api/models"
+	"kasir-api/services"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type CartHandler struct {
+	service *services.CartService
+}
+
+func NewCartHandler(service *services.CartService) *CartHandler {
+	return &CartHandler{service: service}
+}
+
+// GET /api/carts?status=parked, POST /api/carts
+func (h *CartHandler) HandleCarts(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		data, err := h.service.GetAll(r.URL.Query().Get("status"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(data)
+	case http.MethodPost:
+		var req models.CartRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		c, err := h.service.Create(req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(c)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// /api/carts/{id}, /api/carts/{id}/items, /api/carts/{id}/items/{item_id},
+// /api/carts/{id}/park, /api/carts/{id}/resume, /api/carts/{id}/checkout
+func (h *CartHandler) HandleCartByID(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/carts/"), "/"), "/")
+	id, err := strconv.Atoi(parts[0])
+	if err != nil {
+		http.Error(w, "Invalid Cart ID", http.StatusBadRequest)
+		return
+	}
+
+	action := ""
+	if len(parts) > 1 {
+		action = parts[1]
+	}
+	itemID := 0
+	if action == "items" && len(parts) > 2 {
+		itemID, err = strconv.Atoi(parts[2])
+		if err != nil {
+			http.Error(w, "Invalid Item ID", http.StatusBadRequest)
+			return
+		}
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		h.respond(w)(h.service.GetByID(id))
+	case action == "" && r.Method == http.MethodPut:
+		var req models.CartRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		h.respond(w)(h.service.Update(id, req))
+	case action == "" && r.Method == http.MethodDelete:
+		h.respond(w)(h.service.Cancel(id))
+	case action == "items" && itemID == 0 && r.Method == http.MethodPost:
+		var item models.CheckoutItem
+		if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		h.respond(w)(h.service.AddItem(id, item))
+	case action == "items" && itemID > 0 && r.Method == http.MethodPut:
+		var item models.CheckoutItem
+		if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		h.respond(w)(h.service.SetItem(id, itemID, item))
+	case action == "items" && itemID > 0 && r.Method == http.MethodDelete:
+		h.respond(w)(h.service.RemoveItem(id, itemID))
+	case action == "park" && r.Method == http.MethodPost:
+		h.respond(w)(h.service.Park(id))
+	case action == "resume" && r.Method == http.MethodPost:
+		var req models.CartRequest
+		_ = json.NewDecoder(r.Body).Decode(&req) // body opsional: {"terminal": "POS-2"}
+		h.respond(w)(h.service.Resume(id, req.Terminal))
+	case action == "checkout" && r.Method == http.MethodPost:
+		h.Checkout(w, r, id)
+	case action == "" || action == "items" || action == "park" || action == "resume" || action == "checkout":
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (h *CartHandler) respond(w http.ResponseWriter) func(*models.Cart, error) {
+	return func(c *models.Cart, err error) {
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(c)
+	}
+}
+
+// POST /api/carts/{id}/checkout {"shift_id": 1, "payments": [...]}
+func (h *CartHandler) Checkout(w http.ResponseWriter, r *http.Request, id int) {
+	var req models.CartCheckoutRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := h.service.Checkout(id, req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(tx)
+}
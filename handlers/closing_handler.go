@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"encoding/json"
+	"kasir-api/models"
+	"kasir-api/services"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type ClosingHandler struct {
+	service *services.ClosingService
+}
+
+func NewClosingHandler(service *services.ClosingService) *ClosingHandler {
+	return &ClosingHandler{service: service}
+}
+
+// /api/report/z: GET daftar Z-report, POST tutup hari bisnis
+func (h *ClosingHandler) HandleZReports(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.GetAll(w, r)
+	case http.MethodPost:
+		h.CloseDay(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *ClosingHandler) HandleZReportByNumber(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	numStr := strings.TrimPrefix(r.URL.Path, "/api/report/z/")
+	number, err := strconv.Atoi(numStr)
+	if err != nil {
+		http.Error(w, "Invalid Z number", http.StatusBadRequest)
+		return
+	}
+	outletID, err := outletParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	data, err := h.service.GetByNumber(number, outletID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(data)
+}
+
+func (h *ClosingHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	data, err := h.service.GetAll()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(data)
+}
+
+func (h *ClosingHandler) CloseDay(w http.ResponseWriter, r *http.Request) {
+	var req models.CloseDayRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	z, err := h.service.CloseDay(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(z)
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"encoding/json"
+	"kasir-api/models"
+	"kasir-api/services"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type CustomerHandler struct {
+	service *services.CustomerService
+	loyalty *services.LoyaltyService
+}
+
+func NewCustomerHandler(service *services.CustomerService, loyalty *services.LoyaltyService) *CustomerHandler {
+	return &CustomerHandler{service: service, loyalty: loyalty}
+}
+
+func (h *CustomerHandler) HandleCustomers(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.GetAll(w, r)
+	case http.MethodPost:
+		h.Create(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// /api/customers/{id}, /api/customers/{id}/transactions, /api/customers/{id}/stats,
+// /api/customers/{id}/points
+func (h *CustomerHandler) HandleCustomerByID(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/customers/"), "/"), "/")
+	id, err := strconv.Atoi(parts[0])
+	if err != nil {
+		http.Error(w, "Invalid Customer ID", http.StatusBadRequest)
+		return
+	}
+
+	action := ""
+	if len(parts) > 1 {
+		action = parts[1]
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		h.GetByID(w, r, id)
+	case action == "" && r.Method == http.MethodPut:
+		h.Update(w, r, id)
+	case action == "" && r.Method == http.MethodDelete:
+		h.Delete(w, r, id)
+	case action == "transactions" && r.Method == http.MethodGet:
+		h.Transactions(w, r, id)
+	case action == "stats" && r.Method == http.MethodGet:
+		h.Stats(w, r, id)
+	case action == "points" && r.Method == http.MethodGet:
+		h.Points(w, r, id)
+	case action == "" || action == "transactions" || action == "stats" || action == "points":
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// GET /api/customers?q=nama-atau-hp, atau ?phone=08xx untuk lookup persis
+func (h *CustomerHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	if phone := r.URL.Query().Get("phone"); phone != "" {
+		c, err := h.service.GetByPhone(phone)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(c)
+		return
+	}
+
+	data, err := h.service.GetAll(r.URL.Query().Get("q"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(data)
+}
+
+func (h *CustomerHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var c models.Customer
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := h.service.Create(&c); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(c)
+}
+
+func (h *CustomerHandler) GetByID(w http.ResponseWriter, r *http.Request, id int) {
+	data, err := h.service.GetByID(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(data)
+}
+
+func (h *CustomerHandler) Update(w http.ResponseWriter, r *http.Request, id int) {
+	var c models.Customer
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	c.ID = id
+
+	if err := h.service.Update(&c); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(c)
+}
+
+func (h *CustomerHandler) Delete(w http.ResponseWriter, r *http.Request, id int) {
+	if err := h.service.Delete(id); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{"message": "sukses delete"})
+}
+
+func (h *CustomerHandler) Transactions(w http.ResponseWriter, r *http.Request, id int) {
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	data, err := h.service.Transactions(id, limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(data)
+}
+
+func (h *CustomerHandler) Stats(w http.ResponseWriter, r *http.Request, id int) {
+	data, err := h.service.Stats(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(data)
+}
+
+func (h *CustomerHandler) Points(w http.ResponseWriter, r *http.Request, id int) {
+	data, err := h.loyalty.Balance(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(data)
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"encoding/json"
+	"kasir-api/models"
+	"kasir-api/services"
+	"net/http"
+	"strings"
+)
+
+type GiftCardHandler struct {
+	service *services.GiftCardService
+}
+
+func NewGiftCardHandler(service *services.GiftCardService) *GiftCardHandler {
+	return &GiftCardHandler{service: service}
+}
+
+// POST /api/gift-cards {"amount": 100000, "shift_id": 1, "method": "cash"}
+func (h *GiftCardHandler) HandleGiftCards(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req models.IssueGiftCardRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	g, err := h.service.Issue(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(g)
+}
+
+// /api/gift-cards/{code} (cek saldo), /api/gift-cards/{code}/topup
+func (h *GiftCardHandler) HandleGiftCardByCode(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/gift-cards/"), "/"), "/")
+	code := parts[0]
+	if code == "" {
+		http.Error(w, "Invalid Gift Card Code", http.StatusBadRequest)
+		return
+	}
+
+	action := ""
+	if len(parts) > 1 {
+		action = parts[1]
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		h.GetByCode(w, r, code)
+	case action == "topup" && r.Method == http.MethodPost:
+		h.TopUp(w, r, code)
+	case action == "" || action == "topup":
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (h *GiftCardHandler) GetByCode(w http.ResponseWriter, r *http.Request, code string) {
+	data, err := h.service.GetByCode(code)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(data)
+}
+
+// POST /api/gift-cards/{code}/topup {"amount": 50000, "shift_id": 1, "method": "cash"}
+func (h *GiftCardHandler) TopUp(w http.ResponseWriter, r *http.Request, code string) {
+	var req models.TopUpGiftCardRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	g, err := h.service.TopUp(code, req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(g)
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"encoding/json"
+	"kasir-api/models"
+	"kasir-api/services"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type ModifierHandler struct {
+	service *services.ModifierService
+}
+
+func NewModifierHandler(service *services.ModifierService) *ModifierHandler {
+	return &ModifierHandler{service: service}
+}
+
+func (h *ModifierHandler) HandleModifierGroups(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		data, err := h.service.GetAll()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(data)
+	case http.MethodPost:
+		g := models.ModifierGroup{Active: true}
+		if err := json.NewDecoder(r.Body).Decode(&g); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := h.service.Create(&g); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(g)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// /api/modifier-groups/{id}, /{id}/modifiers, /{id}/products
+func (h *ModifierHandler) HandleModifierGroupByID(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/modifier-groups/"), "/"), "/")
+	id, err := strconv.Atoi(parts[0])
+	if err != nil {
+		http.Error(w, "Invalid Modifier Group ID", http.StatusBadRequest)
+		return
+	}
+
+	action := ""
+	if len(parts) > 1 {
+		action = parts[1]
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		h.GetByID(w, r, id)
+	case action == "" && r.Method == http.MethodPut:
+		h.Update(w, r, id)
+	case action == "" && r.Method == http.MethodDelete:
+		h.Delete(w, r, id)
+	case action == "modifiers" && r.Method == http.MethodPut:
+		h.SetModifiers(w, r, id)
+	case action == "products" && r.Method == http.MethodPut:
+		h.SetProducts(w, r, id)
+	case action == "" || action == "modifiers" || action == "products":
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (h *ModifierHandler) GetByID(w http.ResponseWriter, r *http.Request, id int) {
+	data, err := h.service.GetByID(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(data)
+}
+
+func (h *ModifierHandler) Update(w http.ResponseWriter, r *http.Request, id int) {
+	var g models.ModifierGroup
+	if err := json.NewDecoder(r.Body).Decode(&g); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	g.ID = id
+
+	if err := h.service.Update(&g); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	h.GetByID(w, r, id)
+}
+
+func (h *ModifierHandler) Delete(w http.ResponseWriter, r *http.Request, id int) {
+	if err := h.service.Delete(id); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{"message": "sukses delete"})
+}
+
+// PUT /api/modifier-groups/{id}/modifiers [{"name": "Extra shot", "price_delta": 5000}, {"id": 3, ...}]
+func (h *ModifierHandler) SetModifiers(w http.ResponseWriter, r *http.Request, id int) {
+	var raw []json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&raw); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	mods := make([]models.Modifier, 0, len(raw))
+	for _, b := range raw {
+		m := models.Modifier{Active: true}
+		if err := json.Unmarshal(b, &m); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		mods = append(mods, m)
+	}
+
+	if err := h.service.SetModifiers(id, mods); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	h.GetByID(w, r, id)
+}
+
+// PUT /api/modifier-groups/{id}/products {"product_ids": [1, 2]}
+func (h *ModifierHandler) SetProducts(w http.ResponseWriter, r *http.Request, id int) {
+	var req models.ModifierProductsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := h.service.SetProducts(id, req.ProductIDs); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	h.GetByID(w, r, id)
+}
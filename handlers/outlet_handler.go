@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"kasir-api/models"
+	"kasir-api/services"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type OutletHandler struct {
+	service *services.OutletService
+}
+
+func NewOutletHandler(service *services.OutletService) *OutletHandler {
+	return &OutletHandler{service: service}
+}
+
+// outletParam membaca ?outlet_id= (opsional) yang dipakai produk & laporan.
+func outletParam(r *http.Request) (*int, error) {
+	v := r.URL.Query().Get("outlet_id")
+	if v == "" {
+		return nil, nil
+	}
+	id, err := strconv.Atoi(v)
+	if err != nil {
+		return nil, errors.New("outlet_id harus angka")
+	}
+	return &id, nil
+}
+
+func (h *OutletHandler) HandleOutlets(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		data, err := h.service.GetAll()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(data)
+	case http.MethodPost:
+		o := models.Outlet{Active: true}
+		if err := json.NewDecoder(r.Body).Decode(&o); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := h.service.Create(&o); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(o)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// /api/outlets/{id}, /api/outlets/{id}/stock
+func (h *OutletHandler) HandleOutletByID(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/outlets/"), "/"), "/")
+	id, err := strconv.Atoi(parts[0])
+	if err != nil {
+		http.Error(w, "Invalid Outlet ID", http.StatusBadRequest)
+		return
+	}
+
+	action := ""
+	if len(parts) > 1 {
+		action = parts[1]
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		h.GetByID(w, r, id)
+	case action == "" && r.Method == http.MethodPut:
+		h.Update(w, r, id)
+	case action == "" && r.Method == http.MethodDelete:
+		h.Delete(w, r, id)
+	case action == "stock" && r.Method == http.MethodGet:
+		h.GetStock(w, r, id)
+	case action == "stock" && r.Method == http.MethodPut:
+		h.SetStock(w, r, id)
+	case action == "" || action == "stock":
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (h *OutletHandler) GetByID(w http.ResponseWriter, r *http.Request, id int) {
+	data, err := h.service.GetByID(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(data)
+}
+
+func (h *OutletHandler) Update(w http.ResponseWriter, r *http.Request, id int) {
+	var o models.Outlet
+	if err := json.NewDecoder(r.Body).Decode(&o); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	o.ID = id
+
+	if err := h.service.Update(&o); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(o)
+}
+
+func (h *OutletHandler) Delete(w http.ResponseWriter, r *http.Request, id int) {
+	if err := h.service.Delete(id); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{"message": "sukses delete"})
+}
+
+func (h *OutletHandler) GetStock(w http.ResponseWriter, r *http.Request, id int) {
+	data, err := h.service.GetStock(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(data)
+}
+
+// PUT /api/outlets/{id}/stock [{"product_id": 1, "stock": 20, "price": 16000}, ...]
+func (h *OutletHandler) SetStock(w http.ResponseWriter, r *http.Request, id int) {
+	var items []models.OutletStock
+	if err := json.NewDecoder(r.Body).Decode(&items); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	data, err := h.service.SetStock(id, items)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(data)
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"encoding/json"
+	"kasir-api/models"
+	"kasir-api/services"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type PriceListHandler struct {
+	service *services.PriceListService
+}
+
+func NewPriceListHandler(service *services.PriceListService) *PriceListHandler {
+	return &PriceListHandler{service: service}
+}
+
+func (h *PriceListHandler) HandlePriceLists(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		data, err := h.service.GetAll()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(data)
+	case http.MethodPost:
+		pl := models.PriceList{Active: true}
+		if err := json.NewDecoder(r.Body).Decode(&pl); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := h.service.Create(&pl); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(pl)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// /api/price-lists/{id}, /api/price-lists/{id}/items
+func (h *PriceListHandler) HandlePriceListByID(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/price-lists/"), "/"), "/")
+	id, err := strconv.Atoi(parts[0])
+	if err != nil {
+		http.Error(w, "Invalid Price List ID", http.StatusBadRequest)
+		return
+	}
+
+	action := ""
+	if len(parts) > 1 {
+		action = parts[1]
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		h.GetByID(w, r, id)
+	case action == "" && r.Method == http.MethodPut:
+		h.Update(w, r, id)
+	case action == "" && r.Method == http.MethodDelete:
+		h.Delete(w, r, id)
+	case action == "items" && r.Method == http.MethodPut:
+		h.SetItems(w, r, id)
+	case action == "" || action == "items":
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (h *PriceListHandler) GetByID(w http.ResponseWriter, r *http.Request, id int) {
+	data, err := h.service.GetByID(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(data)
+}
+
+func (h *PriceListHandler) Update(w http.ResponseWriter, r *http.Request, id int) {
+	var pl models.PriceList
+	if err := json.NewDecoder(r.Body).Decode(&pl); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	pl.ID = id
+
+	if err := h.service.Update(&pl); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(pl)
+}
+
+func (h *PriceListHandler) Delete(w http.ResponseWriter, r *http.Request, id int) {
+	if err := h.service.Delete(id); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{"message": "sukses delete"})
+}
+
+// PUT /api/price-lists/{id}/items [{"product_id": 1, "min_qty": 12, "price": 3000}, ...]
+func (h *PriceListHandler) SetItems(w http.ResponseWriter, r *http.Request, id int) {
+	var items []models.PriceListItem
+	if err := json.NewDecoder(r.Body).Decode(&items); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := h.service.SetItems(id, items); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	h.GetByID(w, r, id)
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"encoding/json"
+	"kasir-api/models"
+	"kasir-api/services"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type ReceivableHandler struct {
+	service *services.ReceivableService
+}
+
+func NewReceivableHandler(service *services.ReceivableService) *ReceivableHandler {
+	return &ReceivableHandler{service: service}
+}
+
+// GET /api/receivables -> aging piutang semua pelanggan
+func (h *ReceivableHandler) HandleReceivables(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	data, err := h.service.GetAll()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(data)
+}
+
+// /api/receivables/{customer_id}, /api/receivables/{customer_id}/payments,
+// /api/receivables/{customer_id}/statement
+func (h *ReceivableHandler) HandleReceivableByCustomer(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/receivables/"), "/"), "/")
+	id, err := strconv.Atoi(parts[0])
+	if err != nil {
+		http.Error(w, "Invalid Customer ID", http.StatusBadRequest)
+		return
+	}
+
+	action := ""
+	if len(parts) > 1 {
+		action = parts[1]
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		h.GetByCustomer(w, r, id)
+	case action == "payments" && r.Method == http.MethodPost:
+		h.RecordPayment(w, r, id)
+	case action == "statement" && r.Method == http.MethodGet:
+		h.Statement(w, r, id)
+	case action == "" || action == "payments" || action == "statement":
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (h *ReceivableHandler) GetByCustomer(w http.ResponseWriter, r *http.Request, id int) {
+	data, err := h.service.GetByCustomer(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(data)
+}
+
+// POST /api/receivables/{customer_id}/payments {"shift_id": 1, "method": "cash", "amount": 50000}
+func (h *ReceivableHandler) RecordPayment(w http.ResponseWriter, r *http.Request, id int) {
+	var req models.ReceivablePaymentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	entry, err := h.service.RecordPayment(id, req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(entry)
+}
+
+// GET /api/receivables/{customer_id}/statement?from=YYYY-MM-DD&to=YYYY-MM-DD&format=json|csv|text
+func (h *ReceivableHandler) Statement(w http.ResponseWriter, r *http.Request, id int) {
+	q := r.URL.Query()
+	st, err := h.service.Statement(id, q.Get("from"), q.Get("to"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	switch q.Get("format") {
+	case "", services.StatementJSON:
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(st)
+	case services.StatementCSV:
+		body, err := h.service.StatementCSV(st)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="kasbon-`+strconv.Itoa(id)+`.csv"`)
+		_, _ = w.Write(body)
+	case services.StatementText:
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = w.Write([]byte(h.service.StatementText(st)))
+	default:
+		http.Error(w, "format harus json, csv atau text", http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"encoding/json"
+	"kasir-api/models"
+	"kasir-api/services"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type RecipeHandler struct {
+	service *services.RecipeService
+}
+
+func NewRecipeHandler(service *services.RecipeService) *RecipeHandler {
+	return &RecipeHandler{service: service}
+}
+
+// GET/PUT /api/recipes/{product_id}
+func (h *RecipeHandler) HandleRecipeByProduct(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/recipes/"), "/")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid Produk ID", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		h.get(w, id)
+	case http.MethodPut:
+		// [{"ingredient_id": 10, "quantity": 18}, ...]
+		var items []models.RecipeItem
+		if err := json.NewDecoder(r.Body).Decode(&items); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := h.service.Set(id, items); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		h.get(w, id)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *RecipeHandler) get(w http.ResponseWriter, id int) {
+	data, err := h.service.Get(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(data)
+}
@@ -2,8 +2,10 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"kasir-api/services"
 	"net/http"
+	"strconv"
 	"time"
 )
 
@@ -15,12 +17,50 @@ func NewReportHandler(service *services.ReportService) *ReportHandler {
 	return &ReportHandler{service: service}
 }
 
+// reportFilter membaca ?outlet_id= dan ?group_by=outlet.
+func reportFilter(r *http.Request) (services.ReportFilter, error) {
+	outletID, err := outletParam(r)
+	if err != nil {
+		return services.ReportFilter{}, err
+	}
+	groupBy := r.URL.Query().Get("group_by")
+	if groupBy != "" && groupBy != services.GroupByOutlet {
+		return services.ReportFilter{}, errors.New("group_by yang didukung: outlet")
+	}
+	return services.ReportFilter{OutletID: outletID, GroupBy: groupBy}, nil
+}
+
 func (h *ReportHandler) HandleHariIni(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
-	rep, err := h.service.HariIni()
+	filter, err := reportFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	rep, err := h.service.HariIni(filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(rep)
+}
+
+// X-report: rekap hari bisnis berjalan tanpa menutup hari
+func (h *ReportHandler) HandleXReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	filter, err := reportFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	rep, err := h.service.XReport(filter)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -29,7 +69,7 @@ func (h *ReportHandler) HandleHariIni(w http.ResponseWriter, r *http.Request) {
 	_ = json.NewEncoder(w).Encode(rep)
 }
 
-// Optional: /api/report?start_date=YYYY-MM-DD&end_date=YYYY-MM-DD
+// Optional: /api/report?start_date=YYYY-MM-DD&end_date=YYYY-MM-DD[&outlet_id=1][&group_by=outlet]
 func (h *ReportHandler) HandleReportRange(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -53,10 +93,15 @@ func (h *ReportHandler) HandleReportRange(w http.ResponseWriter, r *http.Request
 		http.Error(w, "format end_date harus YYYY-MM-DD", http.StatusBadRequest)
 		return
 	}
-	// end exclusive: + 1 hari
-	end = end.Add(24 * time.Hour)
+	// end exclusive: + 1 hari bisnis
+	end = end.AddDate(0, 0, 1)
 
-	rep, err := h.service.Range(start, end)
+	filter, err := reportFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	rep, err := h.service.Range(start, end, filter)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -64,3 +109,207 @@ func (h *ReportHandler) HandleReportRange(w http.ResponseWriter, r *http.Request
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(rep)
 }
+
+// /api/report/ingredients?start_date=YYYY-MM-DD&end_date=YYYY-MM-DD (default hari ini)
+func (h *ReportHandler) HandleIngredientUsage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// kosong = hari bisnis berjalan outlet, ditentukan service
+	var start, end time.Time
+	if v := r.URL.Query().Get("start_date"); v != "" {
+		d, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			http.Error(w, "format start_date harus YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		start = d
+	}
+	if v := r.URL.Query().Get("end_date"); v != "" {
+		d, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			http.Error(w, "format end_date harus YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		end = d.AddDate(0, 0, 1)
+	}
+
+	filter, err := reportFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	data, err := h.service.IngredientUsage(start, end, filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(data)
+}
+
+// /api/report/expiry?days=30[&outlet_id=1]: lot yang mendekati / sudah lewat kedaluwarsa
+func (h *ReportHandler) HandleExpiringLots(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	days := 0
+	if v := r.URL.Query().Get("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			http.Error(w, "days harus angka >= 0", http.StatusBadRequest)
+			return
+		}
+		days = n
+	}
+	outletID, err := outletParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	lots, err := h.service.ExpiringLots(days, outletID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(lots)
+}
+
+// /api/report/sales?granularity=day&start_date=YYYY-MM-DD&end_date=YYYY-MM-DD[&outlet_id=1]
+// granularity: hour, day (default), week, month. Tanpa tanggal = periode default granularity.
+func (h *ReportHandler) HandleSales(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	startStr, endStr := q.Get("start_date"), q.Get("end_date")
+	if (startStr == "") != (endStr == "") {
+		http.Error(w, "start_date dan end_date diisi berdua", http.StatusBadRequest)
+		return
+	}
+	var start, end time.Time
+	if startStr != "" {
+		var err error
+		if start, err = time.Parse("2006-01-02", startStr); err != nil {
+			http.Error(w, "format start_date harus YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		if end, err = time.Parse("2006-01-02", endStr); err != nil {
+			http.Error(w, "format end_date harus YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		// end exclusive: + 1 hari bisnis
+		end = end.AddDate(0, 0, 1)
+	}
+
+	outletID, err := outletParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	rep, err := h.service.Sales(q.Get("granularity"), start, end, outletID)
+	if err != nil {
+		reportError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(rep)
+}
+
+// reportError: parameter tidak valid = 400, selebihnya (database, dll.) = 500.
+func reportError(w http.ResponseWriter, err error) {
+	var invalid *services.InvalidParamError
+	if errors.As(err, &invalid) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
+
+// dateRange membaca start_date/end_date opsional (YYYY-MM-DD, inklusif); end dikembalikan exclusive.
+func dateRange(r *http.Request) (time.Time, time.Time, error) {
+	var start, end time.Time
+	if v := r.URL.Query().Get("start_date"); v != "" {
+		d, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			return start, end, errors.New("format start_date harus YYYY-MM-DD")
+		}
+		start = d
+	}
+	if v := r.URL.Query().Get("end_date"); v != "" {
+		d, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			return start, end, errors.New("format end_date harus YYYY-MM-DD")
+		}
+		end = d.AddDate(0, 0, 1)
+	}
+	return start, end, nil
+}
+
+// /api/report/products?sort_by=qty|revenue&order=top|bottom&limit=10&start_date=&end_date=[&outlet_id=1]
+func (h *ReportHandler) HandleProductRanking(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	start, end, err := dateRange(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	outletID, err := outletParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	q := r.URL.Query()
+	limit := 0
+	if v := q.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil {
+			http.Error(w, "limit harus angka", http.StatusBadRequest)
+			return
+		}
+	}
+
+	rep, err := h.service.ProductRanking(start, end, outletID, q.Get("sort_by"), q.Get("order"), limit)
+	if err != nil {
+		reportError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(rep)
+}
+
+// /api/report/breakdown?by=category|cashier|payment&start_date=&end_date=[&outlet_id=1]
+func (h *ReportHandler) HandleBreakdown(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	start, end, err := dateRange(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	outletID, err := outletParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	rep, err := h.service.Breakdown(r.URL.Query().Get("by"), start, end, outletID)
+	if err != nil {
+		reportError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(rep)
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"encoding/json"
+	"kasir-api/models"
+	"kasir-api/services"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type SerialHandler struct {
+	service *services.SerialService
+}
+
+func NewSerialHandler(service *services.SerialService) *SerialHandler {
+	return &SerialHandler{service: service}
+}
+
+// GET /api/serials?outlet_id=1&product_id=3&status=in_stock
+func (h *SerialHandler) HandleSerials(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	outletID, err := outletParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	f := models.SerialFilter{OutletID: outletID, Status: r.URL.Query().Get("status")}
+	if v := r.URL.Query().Get("product_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "Invalid product_id", http.StatusBadRequest)
+			return
+		}
+		f.ProductID = id
+	}
+	data, err := h.service.GetAll(f)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(data)
+}
+
+// GET /api/serials/{serial_number}: riwayat unit (diterima, terjual di transaksi mana, dikembalikan)
+func (h *SerialHandler) HandleSerialLookup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	data, err := h.service.Lookup(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/serials/"), "/"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(data)
+}
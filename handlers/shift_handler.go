@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"encoding/json"
+	"kasir-api/models"
+	"kasir-api/services"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type ShiftHandler struct {
+	service *services.ShiftService
+}
+
+func NewShiftHandler(service *services.ShiftService) *ShiftHandler {
+	return &ShiftHandler{service: service}
+}
+
+func (h *ShiftHandler) HandleShifts(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.GetAll(w, r)
+	case http.MethodPost:
+		h.Open(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// /api/shifts/{id}, /api/shifts/{id}/summary, /api/shifts/{id}/close,
+// /api/shifts/{id}/cash-movements
+func (h *ShiftHandler) HandleShiftByID(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/shifts/"), "/"), "/")
+	id, err := strconv.Atoi(parts[0])
+	if err != nil {
+		http.Error(w, "Invalid Shift ID", http.StatusBadRequest)
+		return
+	}
+
+	action := ""
+	if len(parts) > 1 {
+		action = parts[1]
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		h.GetByID(w, r, id)
+	case action == "summary" && r.Method == http.MethodGet:
+		h.Summary(w, r, id)
+	case action == "close" && r.Method == http.MethodPost:
+		h.Close(w, r, id)
+	case action == "cash-movements" && r.Method == http.MethodGet:
+		h.GetCashMovements(w, r, id)
+	case action == "cash-movements" && r.Method == http.MethodPost:
+		h.AddCashMovement(w, r, id)
+	case action == "" || action == "summary" || action == "close" || action == "cash-movements":
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (h *ShiftHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+
+	data, err := h.service.GetAll(status)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(data)
+}
+
+func (h *ShiftHandler) Open(w http.ResponseWriter, r *http.Request) {
+	var req models.OpenShiftRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	s, err := h.service.Open(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(s)
+}
+
+func (h *ShiftHandler) GetByID(w http.ResponseWriter, r *http.Request, id int) {
+	data, err := h.service.GetByID(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(data)
+}
+
+func (h *ShiftHandler) Summary(w http.ResponseWriter, r *http.Request, id int) {
+	data, err := h.service.Summary(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(data)
+}
+
+func (h *ShiftHandler) Close(w http.ResponseWriter, r *http.Request, id int) {
+	var req models.CloseShiftRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	data, err := h.service.Close(id, req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(data)
+}
+
+func (h *ShiftHandler) GetCashMovements(w http.ResponseWriter, r *http.Request, id int) {
+	data, err := h.service.GetCashMovements(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(data)
+}
+
+func (h *ShiftHandler) AddCashMovement(w http.ResponseWriter, r *http.Request, id int) {
+	var m models.CashMovement
+	if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	m.ShiftID = id
+
+	if err := h.service.AddCashMovement(&m); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(m)
+}
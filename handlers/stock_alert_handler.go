@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"encoding/json"
+	"kasir-api/services"
+	"net/http"
+)
+
+type StockAlertHandler struct {
+	service *services.StockAlertService
+}
+
+func NewStockAlertHandler(service *services.StockAlertService) *StockAlertHandler {
+	return &StockAlertHandler{service: service}
+}
+
+// GET /api/stock/low?outlet_id=2
+func (h *StockAlertHandler) HandleLowStock(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	outletID, err := outletParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	data, err := h.service.GetLow(outletID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(data)
+}
+
+// POST /api/stock/reorder/refresh — jalankan job saran pesan ulang sekarang
+func (h *StockAlertHandler) HandleReorderRefresh(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	n, err := h.service.RefreshSuggestions()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]int{"updated": n})
+}
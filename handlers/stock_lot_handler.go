@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"encoding/json"
+	"kasir-api/models"
+	"kasir-api/services"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type StockLotHandler struct {
+	service *services.StockLotService
+}
+
+func NewStockLotHandler(service *services.StockLotService) *StockLotHandler {
+	return &StockLotHandler{service: service}
+}
+
+// GET /api/goods-receipts?outlet_id=1, POST /api/goods-receipts
+func (h *StockLotHandler) HandleReceipts(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		outletID, err := outletParam(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		data, err := h.service.GetReceipts(outletID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(data)
+	case http.MethodPost:
+		var g models.GoodsReceipt
+		if err := json.NewDecoder(r.Body).Decode(&g); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		data, err := h.service.CreateReceipt(&g)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(data)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// GET /api/goods-receipts/{id}
+func (h *StockLotHandler) HandleReceiptByID(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id, err := strconv.Atoi(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/goods-receipts/"), "/"))
+	if err != nil {
+		http.Error(w, "Invalid Goods Receipt ID", http.StatusBadRequest)
+		return
+	}
+	data, err := h.service.GetReceipt(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(data)
+}
+
+// GET /api/stock-lots?outlet_id=1&product_id=3[&all=true]
+func (h *StockLotHandler) HandleLots(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	outletID, err := outletParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	productID := 0
+	if v := r.URL.Query().Get("product_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "Invalid product_id", http.StatusBadRequest)
+			return
+		}
+		productID = id
+	}
+	data, err := h.service.GetLots(outletID, productID, r.URL.Query().Get("all") == "true")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(data)
+}
+
+// PUT /api/stock-lots/{id}: koreksi quantity lot
+func (h *StockLotHandler) HandleLotByID(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id, err := strconv.Atoi(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/stock-lots/"), "/"))
+	if err != nil {
+		http.Error(w, "Invalid Lot ID", http.StatusBadRequest)
+		return
+	}
+	var req models.LotAdjustRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	data, err := h.service.AdjustLot(id, req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(data)
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"encoding/json"
+	"kasir-api/models"
+	"kasir-api/services"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type StockTransferHandler struct {
+	service *services.StockTransferService
+}
+
+func NewStockTransferHandler(service *services.StockTransferService) *StockTransferHandler {
+	return &StockTransferHandler{service: service}
+}
+
+// GET /api/stock-transfers?status=sent&outlet_id=2, POST /api/stock-transfers
+func (h *StockTransferHandler) HandleTransfers(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		outletID := 0
+		if v := r.URL.Query().Get("outlet_id"); v != "" {
+			id, err := strconv.Atoi(v)
+			if err != nil {
+				http.Error(w, "Invalid outlet_id", http.StatusBadRequest)
+				return
+			}
+			outletID = id
+		}
+		data, err := h.service.GetAll(r.URL.Query().Get("status"), outletID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(data)
+	case http.MethodPost:
+		var t models.StockTransfer
+		if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		data, err := h.service.Create(&t)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(data)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// /api/stock-transfers/{id}, /{id}/send, /{id}/receive, /{id}/cancel
+func (h *StockTransferHandler) HandleTransferByID(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/stock-transfers/"), "/"), "/")
+	id, err := strconv.Atoi(parts[0])
+	if err != nil {
+		http.Error(w, "Invalid Transfer ID", http.StatusBadRequest)
+		return
+	}
+
+	action := ""
+	if len(parts) > 1 {
+		action = parts[1]
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		data, err := h.service.GetByID(id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(data)
+	case action == "" && r.Method == http.MethodPut:
+		var t models.StockTransfer
+		if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		t.ID = id
+		h.respond(w)(h.service.Update(&t))
+	case action == "send" && r.Method == http.MethodPost:
+		h.respond(w)(h.service.Send(id))
+	case action == "receive" && r.Method == http.MethodPost:
+		var req models.ReceiveTransferRequest
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+		}
+		h.respond(w)(h.service.Receive(id, req))
+	case action == "cancel" && r.Method == http.MethodPost:
+		h.respond(w)(h.service.Cancel(id))
+	case action == "" || action == "send" || action == "receive" || action == "cancel":
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (h *StockTransferHandler) respond(w http.ResponseWriter) func(*models.StockTransfer, error) {
+	return func(t *models.StockTransfer, err error) {
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(t)
+	}
+}
+
+// GET /api/stock-movements?outlet_id=1&product_id=3&from=YYYY-MM-DD&to=YYYY-MM-DD
+func (h *StockTransferHandler) HandleMovements(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	outletID, productID := 0, 0
+	if v := q.Get("outlet_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "Invalid outlet_id", http.StatusBadRequest)
+			return
+		}
+		outletID = id
+	}
+	if v := q.Get("product_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "Invalid product_id", http.StatusBadRequest)
+			return
+		}
+		productID = id
+	}
+
+	data, err := h.service.Movements(q.Get("from"), q.Get("to"), outletID, productID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(data)
+}
@@ -5,14 +5,17 @@ import (
 	"kasir-api/models"
 	"kasir-api/services"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
 type TransactionHandler struct {
-	service *services.TransactionService
+	service  *services.TransactionService
+	receipts *services.ReceiptService
 }
 
-func NewTransactionHandler(service *services.TransactionService) *TransactionHandler {
-	return &TransactionHandler{service: service}
+func NewTransactionHandler(service *services.TransactionService, receipts *services.ReceiptService) *TransactionHandler {
+	return &TransactionHandler{service: service, receipts: receipts}
 }
 
 func (h *TransactionHandler) HandleCheckout(w http.ResponseWriter, r *http.Request) {
@@ -34,8 +37,12 @@ func (h *TransactionHandler) Checkout(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "items wajib diisi", http.StatusBadRequest)
 		return
 	}
+	if req.ShiftID <= 0 {
+		http.Error(w, "shift_id wajib diisi", http.StatusBadRequest)
+		return
+	}
 
-	tx, err := h.service.Checkout(req.Items)
+	tx, err := h.service.Checkout(req)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -44,3 +51,157 @@ func (h *TransactionHandler) Checkout(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(tx)
 }
+
+// GET /api/transactions?receipt_no=...&shift_id=...&limit=...
+func (h *TransactionHandler) HandleTransactions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	f := models.TransactionFilter{ReceiptNo: q.Get("receipt_no")}
+	if v := q.Get("shift_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "Invalid shift_id", http.StatusBadRequest)
+			return
+		}
+		f.ShiftID = id
+	}
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		f.Limit = limit
+	}
+
+	data, err := h.service.GetAll(f)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(data)
+}
+
+// /api/transactions/{id}, /api/transactions/{id}/receipt,
+// /api/transactions/{id}/receipt/email, /api/transactions/{id}/refund
+func (h *TransactionHandler) HandleTransactionByID(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/transactions/"), "/"), "/")
+	id, err := strconv.Atoi(parts[0])
+	if err != nil {
+		http.Error(w, "Invalid Transaction ID", http.StatusBadRequest)
+		return
+	}
+
+	path := strings.Join(parts[1:], "/")
+	switch {
+	case path == "" && r.Method == http.MethodGet:
+		h.GetByID(w, r, id)
+	case path == "receipt" && (r.Method == http.MethodGet || r.Method == http.MethodPost):
+		h.Receipt(w, r, id, r.Method == http.MethodPost)
+	case path == "receipt/email" && r.Method == http.MethodPost:
+		h.EmailReceipt(w, r, id)
+	case path == "refund" && r.Method == http.MethodPost:
+		h.Refund(w, r, id)
+	case path == "" || path == "receipt" || path == "receipt/email" || path == "refund":
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (h *TransactionHandler) GetByID(w http.ResponseWriter, r *http.Request, id int) {
+	data, err := h.service.GetByID(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(data)
+}
+
+// GET /api/transactions/{id}/receipt?format=escpos|text|html|pdf&paper=58|80 = preview,
+// POST dengan query yang sama = cetak (dicatat, cetakan berikutnya jadi COPY).
+func (h *TransactionHandler) Receipt(w http.ResponseWriter, r *http.Request, id int, print bool) {
+	paper := 0
+	if v := r.URL.Query().Get("paper"); v != "" {
+		p, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "Invalid paper", http.StatusBadRequest)
+			return
+		}
+		paper = p
+	}
+
+	out, contentType, err := h.receipts.Render(id, r.URL.Query().Get("format"), paper, print)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	_, _ = w.Write(out)
+}
+
+// POST /api/transactions/{id}/receipt/email {"email": "..."}
+func (h *TransactionHandler) EmailReceipt(w http.ResponseWriter, r *http.Request, id int) {
+	var req struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.receipts.EmailReceipt(id, req.Email); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{"message": "struk terkirim"})
+}
+
+// GET /r/{id}.{sig}?format=html|pdf — struk digital publik dari QR, tanpa login
+func (h *TransactionHandler) HandlePublicReceipt(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	idStr, sig, ok := strings.Cut(strings.TrimPrefix(r.URL.Path, "/r/"), ".")
+	id, err := strconv.Atoi(idStr)
+	if !ok || err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	out, contentType, err := h.receipts.RenderPublic(id, sig, r.URL.Query().Get("format"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "private, no-store")
+	_, _ = w.Write(out)
+}
+
+// POST /api/transactions/{id}/refund {"shift_id": 1, "reason": "..."}
+func (h *TransactionHandler) Refund(w http.ResponseWriter, r *http.Request, id int) {
+	var req models.RefundRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	refund, err := h.service.Refund(id, req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(refund)
+}
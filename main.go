@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"kasir-api/database"
@@ -11,13 +12,40 @@ import (
 	"net/http"
 	"os"
 	"strings"
+	"time"
+	_ "time/tzdata" // validasi timezone outlet tanpa bergantung zoneinfo di container
 
 	"github.com/spf13/viper"
 )
 
 type Config struct {
-	Port   string `mapstructure:"PORT"`
-	DBConn string `mapstructure:"DB_CONN"`
+	Port          string `mapstructure:"PORT"`
+	DBConn        string `mapstructure:"DB_CONN"`
+	ReceiptFormat string `mapstructure:"RECEIPT_FORMAT"`
+
+	StoreName     string  `mapstructure:"STORE_NAME"`
+	StoreAddress  string  `mapstructure:"STORE_ADDRESS"`
+	StoreNPWP     string  `mapstructure:"STORE_NPWP"`
+	ReceiptFooter string  `mapstructure:"RECEIPT_FOOTER"`
+	ReceiptLogo   string  `mapstructure:"RECEIPT_LOGO"`
+	ReceiptPaper  int     `mapstructure:"RECEIPT_PAPER"`
+	TaxRate       float64 `mapstructure:"TAX_RATE"` // PPN (%), harga sudah termasuk pajak
+
+	ReceiptSecret string `mapstructure:"RECEIPT_SECRET"`
+	PublicBaseURL string `mapstructure:"PUBLIC_BASE_URL"`
+
+	SMTPHost string `mapstructure:"SMTP_HOST"`
+	SMTPPort int    `mapstructure:"SMTP_PORT"`
+	SMTPUser string `mapstructure:"SMTP_USER"`
+	SMTPPass string `mapstructure:"SMTP_PASS"`
+	SMTPFrom string `mapstructure:"SMTP_FROM"`
+
+	StockAlertEmail  string        `mapstructure:"STOCK_ALERT_EMAIL"` // kosong = alert ke log
+	ReorderInterval  time.Duration `mapstructure:"REORDER_INTERVAL"`
+	ReorderSalesDays int           `mapstructure:"REORDER_SALES_DAYS"`
+	ReorderCoverDays int           `mapstructure:"REORDER_COVER_DAYS"`
+
+	SalesSummaryInterval time.Duration `mapstructure:"SALES_SUMMARY_INTERVAL"`
 }
 
 func loadConfig() Config {
@@ -30,8 +58,33 @@ func loadConfig() Config {
 	}
 
 	return Config{
-		Port:   viper.GetString("PORT"),
-		DBConn: viper.GetString("DB_CONN"),
+		Port:          viper.GetString("PORT"),
+		DBConn:        viper.GetString("DB_CONN"),
+		ReceiptFormat: viper.GetString("RECEIPT_FORMAT"),
+
+		StoreName:     viper.GetString("STORE_NAME"),
+		StoreAddress:  viper.GetString("STORE_ADDRESS"),
+		StoreNPWP:     viper.GetString("STORE_NPWP"),
+		ReceiptFooter: viper.GetString("RECEIPT_FOOTER"),
+		ReceiptLogo:   viper.GetString("RECEIPT_LOGO"),
+		ReceiptPaper:  viper.GetInt("RECEIPT_PAPER"),
+		TaxRate:       viper.GetFloat64("TAX_RATE"),
+
+		ReceiptSecret: viper.GetString("RECEIPT_SECRET"),
+		PublicBaseURL: viper.GetString("PUBLIC_BASE_URL"),
+
+		SMTPHost: viper.GetString("SMTP_HOST"),
+		SMTPPort: viper.GetInt("SMTP_PORT"),
+		SMTPUser: viper.GetString("SMTP_USER"),
+		SMTPPass: viper.GetString("SMTP_PASS"),
+		SMTPFrom: viper.GetString("SMTP_FROM"),
+
+		StockAlertEmail:  viper.GetString("STOCK_ALERT_EMAIL"),
+		ReorderInterval:  viper.GetDuration("REORDER_INTERVAL"),
+		ReorderSalesDays: viper.GetInt("REORDER_SALES_DAYS"),
+		ReorderCoverDays: viper.GetInt("REORDER_COVER_DAYS"),
+
+		SalesSummaryInterval: viper.GetDuration("SALES_SUMMARY_INTERVAL"),
 	}
 }
 
@@ -43,6 +96,21 @@ func main() {
 	if cfg.DBConn == "" {
 		log.Fatal("DB_CONN kosong. Pastikan .env kebaca.")
 	}
+	if cfg.ReceiptFormat == "" {
+		cfg.ReceiptFormat = repositories.DefaultReceiptFormat
+	}
+	if err := (repositories.ReceiptNumbering{Format: cfg.ReceiptFormat}).Validate(); err != nil {
+		log.Fatal(err)
+	}
+	if cfg.ReceiptPaper == 0 {
+		cfg.ReceiptPaper = services.Paper58mm
+	}
+	if cfg.ReorderInterval <= 0 {
+		cfg.ReorderInterval = time.Hour
+	}
+	if cfg.SalesSummaryInterval <= 0 {
+		cfg.SalesSummaryInterval = time.Hour
+	}
 
 	// Init DB pool (pgxpool)
 	dbPool, err := database.InitDBPool(cfg.DBConn)
@@ -51,25 +119,153 @@ func main() {
 	}
 	defer dbPool.Close()
 
+	// Ringkasan penjualan harian: `go run . rebuild-summaries [YYYY-MM-DD]` menghitung ulang lalu keluar
+	summarySvc := services.NewSalesSummaryService(repositories.NewSalesSummaryRepository(dbPool))
+	if len(os.Args) > 1 && os.Args[1] == "rebuild-summaries" {
+		from := ""
+		if len(os.Args) > 2 {
+			from = os.Args[2]
+		}
+		n, err := summarySvc.Rebuild(from)
+		if err != nil {
+			log.Fatal("Rebuild ringkasan penjualan gagal: ", err)
+		}
+		log.Printf("Ringkasan penjualan dibangun ulang: %d hari-outlet", n)
+		return
+	}
+	go summarySvc.RunSummaryJob(context.Background(), cfg.SalesSummaryInterval)
+
 	// DI
 	productRepo := repositories.NewProductRepository(dbPool)
 	productSvc := services.NewProductService(productRepo)
 	productHandler := handlers.NewProductHandler(productSvc)
 
+	recipeRepo := repositories.NewRecipeRepository(dbPool)
+	recipeSvc := services.NewRecipeService(recipeRepo)
+	recipeHandler := handlers.NewRecipeHandler(recipeSvc)
+
+	bundleRepo := repositories.NewBundleRepository(dbPool)
+	bundleSvc := services.NewBundleService(bundleRepo)
+	bundleHandler := handlers.NewBundleHandler(bundleSvc)
+
+	modifierRepo := repositories.NewModifierRepository(dbPool)
+	modifierSvc := services.NewModifierService(modifierRepo)
+	modifierHandler := handlers.NewModifierHandler(modifierSvc)
+
+	// Hari bisnis per outlet (zona waktu & jam tutup buku) dipakai filter periode di banyak service
+	reportRepo := repositories.NewReportRepository(dbPool)
+
+	outletRepo := repositories.NewOutletRepository(dbPool)
+	outletSvc := services.NewOutletService(outletRepo)
+	outletHandler := handlers.NewOutletHandler(outletSvc)
+
+	transferRepo := repositories.NewStockTransferRepository(dbPool)
+	transferSvc := services.NewStockTransferService(transferRepo, reportRepo)
+	transferHandler := handlers.NewStockTransferHandler(transferSvc)
+
+	lotRepo := repositories.NewStockLotRepository(dbPool)
+	lotSvc := services.NewStockLotService(lotRepo)
+	lotHandler := handlers.NewStockLotHandler(lotSvc)
+
+	serialRepo := repositories.NewSerialRepository(dbPool)
+	serialSvc := services.NewSerialService(serialRepo)
+	serialHandler := handlers.NewSerialHandler(serialSvc)
+
 	categoryRepo := repositories.NewCategoryRepository(dbPool)
 	categorySvc := services.NewCategoryService(categoryRepo)
 	categoryHandler := handlers.NewCategoryHandler(categorySvc)
 
+	// Kasir & shift
+	cashierRepo := repositories.NewCashierRepository(dbPool)
+	cashierSvc := services.NewCashierService(cashierRepo)
+	cashierHandler := handlers.NewCashierHandler(cashierSvc)
+
+	shiftRepo := repositories.NewShiftRepository(dbPool)
+	shiftSvc := services.NewShiftService(shiftRepo)
+	shiftHandler := handlers.NewShiftHandler(shiftSvc)
+
+	var mailer services.Mailer
+	if cfg.SMTPHost != "" {
+		mailer = services.NewSMTPMailer(services.SMTPConfig{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUser,
+			Password: cfg.SMTPPass,
+			From:     cfg.SMTPFrom,
+		})
+	}
+
+	// Stok menipis: alert setelah checkout + job saran pesan ulang
+	var stockNotifier services.StockNotifier = services.LogStockNotifier{}
+	if mailer != nil && cfg.StockAlertEmail != "" {
+		stockNotifier = services.NewMailStockNotifier(mailer, strings.Split(cfg.StockAlertEmail, ","))
+	}
+	lowStockRepo := repositories.NewLowStockRepository(dbPool)
+	stockAlertSvc := services.NewStockAlertService(lowStockRepo, stockNotifier, services.ReorderConfig{
+		SalesDays: cfg.ReorderSalesDays,
+		CoverDays: cfg.ReorderCoverDays,
+	})
+	stockAlertHandler := handlers.NewStockAlertHandler(stockAlertSvc)
+	go stockAlertSvc.RunReorderJob(context.Background(), cfg.ReorderInterval)
+
 	// Transaction
-	transactionRepo := repositories.NewTransactionRepository(dbPool)
-	transactionService := services.NewTransactionService(transactionRepo)
-	transactionHandler := handlers.NewTransactionHandler(transactionService)
+	transactionRepo := repositories.NewTransactionRepository(dbPool, repositories.ReceiptNumbering{
+		Format: cfg.ReceiptFormat,
+	})
+	approvalRepo := repositories.NewApprovalRepository(dbPool)
+	approvalSvc := services.NewApprovalService(approvalRepo, reportRepo)
+	approvalHandler := handlers.NewApprovalHandler(approvalSvc)
+	transactionService := services.NewTransactionService(transactionRepo, approvalSvc, stockAlertSvc)
+
+	voucherRepo := repositories.NewVoucherRepository(dbPool)
+	voucherSvc := services.NewVoucherService(voucherRepo)
+	voucherHandler := handlers.NewVoucherHandler(voucherSvc)
+
+	cartRepo := repositories.NewCartRepository(dbPool, transactionRepo)
+	cartSvc := services.NewCartService(cartRepo, approvalSvc, stockAlertSvc, cfg.TaxRate)
+	cartHandler := handlers.NewCartHandler(cartSvc)
+
+	receiptSvc := services.NewReceiptService(transactionRepo, services.ReceiptTemplate{
+		StoreName: cfg.StoreName,
+		Address:   cfg.StoreAddress,
+		NPWP:      cfg.StoreNPWP,
+		Footer:    cfg.ReceiptFooter,
+		LogoPath:  cfg.ReceiptLogo,
+		Paper:     cfg.ReceiptPaper,
+	}, services.ReceiptLinkConfig{
+		Secret:  cfg.ReceiptSecret,
+		BaseURL: cfg.PublicBaseURL,
+	}, mailer)
+	transactionHandler := handlers.NewTransactionHandler(transactionService, receiptSvc)
+
+	priceListRepo := repositories.NewPriceListRepository(dbPool)
+	priceListSvc := services.NewPriceListService(priceListRepo)
+	priceListHandler := handlers.NewPriceListHandler(priceListSvc)
+
+	loyaltyRepo := repositories.NewLoyaltyRepository(dbPool)
+	loyaltySvc := services.NewLoyaltyService(loyaltyRepo)
+	loyaltyHandler := handlers.NewLoyaltyHandler(loyaltySvc)
+
+	giftCardRepo := repositories.NewGiftCardRepository(dbPool)
+	giftCardSvc := services.NewGiftCardService(giftCardRepo)
+	giftCardHandler := handlers.NewGiftCardHandler(giftCardSvc)
+
+	receivableRepo := repositories.NewReceivableRepository(dbPool)
+	receivableSvc := services.NewReceivableService(receivableRepo, reportRepo, cfg.StoreName)
+	receivableHandler := handlers.NewReceivableHandler(receivableSvc)
+
+	customerRepo := repositories.NewCustomerRepository(dbPool)
+	customerSvc := services.NewCustomerService(customerRepo, transactionRepo)
+	customerHandler := handlers.NewCustomerHandler(customerSvc, loyaltySvc)
 
 	// Report
-	reportRepo := repositories.NewReportRepository(dbPool)
-	reportSvc := services.NewReportService(reportRepo)
+	reportSvc := services.NewReportService(reportRepo, cfg.TaxRate)
 	reportHandler := handlers.NewReportHandler(reportSvc)
 
+	closingRepo := repositories.NewClosingRepository(dbPool)
+	closingSvc := services.NewClosingService(closingRepo, cfg.TaxRate)
+	closingHandler := handlers.NewClosingHandler(closingSvc)
+
 	// Routes
 	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
@@ -82,13 +278,73 @@ func main() {
 	http.HandleFunc("/api/produk", productHandler.HandleProducts)
 	http.HandleFunc("/api/produk/", productHandler.HandleProductByID)
 
+	http.HandleFunc("/api/recipes/", recipeHandler.HandleRecipeByProduct)
+	http.HandleFunc("/api/bundles/", bundleHandler.HandleBundleByProduct)
+	http.HandleFunc("/api/modifier-groups", modifierHandler.HandleModifierGroups)
+	http.HandleFunc("/api/modifier-groups/", modifierHandler.HandleModifierGroupByID)
+
+	http.HandleFunc("/api/outlets", outletHandler.HandleOutlets)
+	http.HandleFunc("/api/outlets/", outletHandler.HandleOutletByID)
+	http.HandleFunc("/api/stock-transfers", transferHandler.HandleTransfers)
+	http.HandleFunc("/api/stock-transfers/", transferHandler.HandleTransferByID)
+	http.HandleFunc("/api/stock-movements", transferHandler.HandleMovements)
+	http.HandleFunc("/api/goods-receipts", lotHandler.HandleReceipts)
+	http.HandleFunc("/api/goods-receipts/", lotHandler.HandleReceiptByID)
+	http.HandleFunc("/api/stock-lots", lotHandler.HandleLots)
+	http.HandleFunc("/api/stock-lots/", lotHandler.HandleLotByID)
+	http.HandleFunc("/api/serials", serialHandler.HandleSerials)
+	http.HandleFunc("/api/serials/", serialHandler.HandleSerialLookup)
+	http.HandleFunc("/api/stock/low", stockAlertHandler.HandleLowStock)
+	http.HandleFunc("/api/stock/reorder/refresh", stockAlertHandler.HandleReorderRefresh)
+
 	http.HandleFunc("/api/categories", categoryHandler.HandleCategories)
 	http.HandleFunc("/api/categories/", categoryHandler.HandleCategoryByID)
 
+	http.HandleFunc("/api/cashiers", cashierHandler.HandleCashiers)
+	http.HandleFunc("/api/cashiers/", cashierHandler.HandleCashierByID)
+
+	http.HandleFunc("/api/customers", customerHandler.HandleCustomers)
+	http.HandleFunc("/api/customers/", customerHandler.HandleCustomerByID)
+
+	http.HandleFunc("/api/gift-cards", giftCardHandler.HandleGiftCards)
+	http.HandleFunc("/api/gift-cards/", giftCardHandler.HandleGiftCardByCode)
+
+	http.HandleFunc("/api/receivables", receivableHandler.HandleReceivables)
+	http.HandleFunc("/api/receivables/", receivableHandler.HandleReceivableByCustomer)
+
+	http.HandleFunc("/api/price-lists", priceListHandler.HandlePriceLists)
+	http.HandleFunc("/api/price-lists/", priceListHandler.HandlePriceListByID)
+
+	http.HandleFunc("/api/loyalty/settings", loyaltyHandler.HandleSettings)
+	http.HandleFunc("/api/loyalty/rules", loyaltyHandler.HandleRules)
+	http.HandleFunc("/api/loyalty/rules/", loyaltyHandler.HandleRuleByID)
+
+	http.HandleFunc("/api/shifts", shiftHandler.HandleShifts)
+	http.HandleFunc("/api/shifts/", shiftHandler.HandleShiftByID)
+
 	http.HandleFunc("/api/checkout", transactionHandler.HandleCheckout) // POST
+	http.HandleFunc("/api/transactions", transactionHandler.HandleTransactions)
+	http.HandleFunc("/api/transactions/", transactionHandler.HandleTransactionByID)
+	http.HandleFunc("/r/", transactionHandler.HandlePublicReceipt)     // struk digital publik
+	http.HandleFunc("/api/approvals", approvalHandler.HandleApprovals) // POST
+	http.HandleFunc("/api/price-overrides", approvalHandler.HandlePriceOverrides)
+	http.HandleFunc("/api/approval-failures", approvalHandler.HandleFailures)
+
+	http.HandleFunc("/api/carts", cartHandler.HandleCarts)
+	http.HandleFunc("/api/carts/", cartHandler.HandleCartByID)
+	http.HandleFunc("/api/vouchers", voucherHandler.HandleVouchers)
+	http.HandleFunc("/api/vouchers/", voucherHandler.HandleVoucherByID)
 
 	http.HandleFunc("/api/report/hari-ini", reportHandler.HandleHariIni)
 	http.HandleFunc("/api/report", reportHandler.HandleReportRange) // optional
+	http.HandleFunc("/api/report/x", reportHandler.HandleXReport)
+	http.HandleFunc("/api/report/ingredients", reportHandler.HandleIngredientUsage)
+	http.HandleFunc("/api/report/expiry", reportHandler.HandleExpiringLots)
+	http.HandleFunc("/api/report/sales", reportHandler.HandleSales)
+	http.HandleFunc("/api/report/products", reportHandler.HandleProductRanking)
+	http.HandleFunc("/api/report/breakdown", reportHandler.HandleBreakdown)
+	http.HandleFunc("/api/report/z", closingHandler.HandleZReports)
+	http.HandleFunc("/api/report/z/", closingHandler.HandleZReportByNumber)
 
 	addr := "0.0.0.0:" + cfg.Port
 	fmt.Println("Server running di", addr)
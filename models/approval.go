@@ -0,0 +1,67 @@
+package models
+
+import "time"
+
+// Approval = persetujuan manager untuk override harga / diskon baris.
+// Isi manager_id + pin, atau token dari POST /api/approvals.
+type Approval struct {
+	ManagerID int    `json:"manager_id,omitempty"`
+	PIN       string `json:"pin,omitempty"`
+	Token     string `json:"token,omitempty"`
+}
+
+// Asal percobaan PIN manager (audit percobaan gagal)
+const (
+	ApprovalSourceToken    = "approvals"
+	ApprovalSourceCheckout = "checkout"
+	ApprovalSourceCart     = "cart_checkout"
+)
+
+type ApprovalToken struct {
+	Token     string    `json:"token"`
+	ManagerID int       `json:"manager_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// PriceOverride = audit trail override harga / diskon per baris.
+type PriceOverride struct {
+	ID                  int       `json:"id"`
+	TransactionID       int       `json:"transaction_id"`
+	ReceiptNo           string    `json:"receipt_no"`
+	TransactionDetailID int       `json:"transaction_detail_id"`
+	ProductID           int       `json:"product_id"`
+	ProductName         string    `json:"product_name"`
+	Quantity            int       `json:"quantity"`
+	CashierID           int       `json:"cashier_id"`
+	CashierName         string    `json:"cashier_name"`
+	ApprovedBy          int       `json:"approved_by"`
+	ApproverName        string    `json:"approver_name"`
+	OriginalPrice       int       `json:"original_price"`
+	OverridePrice       int       `json:"override_price"`
+	DiscountAmount      int       `json:"discount_amount"`
+	Reason              string    `json:"reason"`
+	CreatedAt           time.Time `json:"created_at"`
+}
+
+type PriceOverrideFilter struct {
+	Start      time.Time
+	End        time.Time
+	CashierID  int
+	ApprovedBy int
+}
+
+// ApprovalFailure = audit trail PIN manager yang salah (atau dicoba saat terkunci).
+type ApprovalFailure struct {
+	ID          int       `json:"id"`
+	ManagerID   int       `json:"manager_id"`
+	ManagerName string    `json:"manager_name"`
+	Source      string    `json:"source"`
+	Locked      bool      `json:"locked"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type ApprovalFailureFilter struct {
+	Start     time.Time
+	End       time.Time
+	ManagerID int
+}
@@ -0,0 +1,9 @@
+package models
+
+// BundleItem = isi 1 paket, mis. 2x Mie Goreng.
+type BundleItem struct {
+	ProductID   int    `json:"product_id"`
+	ProductName string `json:"product_name,omitempty"`
+	Quantity    int    `json:"quantity"`
+	Price       int    `json:"price,omitempty"` // harga satuan produk, dasar alokasi pendapatan
+}
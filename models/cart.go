@@ -0,0 +1,73 @@
+package models
+
+import "time"
+
+const (
+	CartOpen       = "open"
+	CartParked     = "parked"
+	CartCheckedOut = "checked_out"
+	CartCancelled  = "cancelled"
+)
+
+type Cart struct {
+	ID            int        `json:"id"`
+	Status        string     `json:"status"`
+	Terminal      string     `json:"terminal"`
+	OutletID      int        `json:"outlet_id"`
+	CustomerID    *int       `json:"customer_id,omitempty"`
+	CustomerName  string     `json:"customer_name,omitempty"`
+	VoucherCode   string     `json:"voucher_code,omitempty"`
+	Note          string     `json:"note,omitempty"`
+	TransactionID *int       `json:"transaction_id,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	Items         []CartItem `json:"items"`
+	Preview       *CartTotal `json:"preview,omitempty"`
+}
+
+// CartItem = satu baris cart. Produk yang sama boleh ada di beberapa baris dengan modifier berbeda.
+type CartItem struct {
+	ID          int              `json:"id"` // id baris untuk /api/carts/{id}/items/{item_id}
+	ProductID   int              `json:"product_id"`
+	ProductName string           `json:"product_name,omitempty"`
+	Quantity    int              `json:"quantity"`
+	UnitPrice   int              `json:"unit_price"` // sudah termasuk modifier
+	PriceListID *int             `json:"price_list_id,omitempty"`
+	Modifiers   []DetailModifier `json:"modifiers,omitempty"`
+	Subtotal    int              `json:"subtotal"`
+
+	// Override harga satuan / diskon Rupiah baris; approval manager diminta saat checkout
+	PriceOverride *int   `json:"price_override,omitempty"`
+	Discount      int    `json:"discount,omitempty"`
+	Reason        string `json:"reason,omitempty"`
+
+	SerialNumbers []string `json:"serial_numbers,omitempty"`
+}
+
+// CartTotal = perkiraan total dengan harga & voucher saat ini.
+// Harga sudah termasuk pajak; Tax hanya rincian PPN di dalam Total.
+type CartTotal struct {
+	Subtotal int      `json:"subtotal"`
+	Discount int      `json:"discount"`
+	Total    int      `json:"total"`
+	TaxRate  float64  `json:"tax_rate"`
+	Tax      int      `json:"tax"`
+	Warnings []string `json:"warnings,omitempty"` // stok kurang, voucher tidak berlaku, dll.
+}
+
+// CartRequest dipakai membuat cart & mengubah header (pelanggan, voucher, catatan).
+type CartRequest struct {
+	Terminal      string  `json:"terminal"`
+	OutletID      *int    `json:"outlet_id,omitempty"` // hanya saat membuat cart; kosong = outlet default
+	CustomerID    *int    `json:"customer_id,omitempty"`
+	CustomerPhone string  `json:"customer_phone,omitempty"`
+	VoucherCode   *string `json:"voucher_code,omitempty"` // "" = lepas voucher
+	Note          *string `json:"note,omitempty"`
+}
+
+type CartCheckoutRequest struct {
+	ShiftID    int               `json:"shift_id"`
+	Approval   *Approval         `json:"approval,omitempty"` // wajib kalau ada baris dengan override / diskon
+	ApprovedBy *int              `json:"-"`                  // diisi service setelah PIN manager dicek
+	Payments   []CheckoutPayment `json:"payments,omitempty"`
+}
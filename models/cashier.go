@@ -0,0 +1,17 @@
+package models
+
+const (
+	RoleCashier = "cashier"
+	RoleManager = "manager"
+)
+
+type Cashier struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	Role     string `json:"role"`
+	Active   bool   `json:"active"`
+	OutletID *int   `json:"outlet_id,omitempty"` // kosong = boleh buka shift di outlet mana saja
+	PIN      string `json:"pin,omitempty"`       // hanya input; disimpan sebagai hash
+	HasPIN   bool   `json:"has_pin"`
+	PINHash  string `json:"-"`
+}
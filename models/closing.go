@@ -0,0 +1,63 @@
+package models
+
+import "time"
+
+type CashierTotal struct {
+	CashierID      *int   `json:"cashier_id"`
+	Nama           string `json:"nama"`
+	TotalTransaksi int    `json:"total_transaksi"`
+	TotalSales     int    `json:"total_sales"`
+}
+
+// ProductTotal = penjualan per produk; produk induk berisi rekap varian-variannya.
+type ProductTotal struct {
+	ProductID int            `json:"product_id"`
+	Nama      string         `json:"nama"`
+	Qty       int            `json:"qty"`
+	Sales     int            `json:"sales"` // subtotal baris, sebelum diskon voucher
+	Variants  []ProductTotal `json:"variants,omitempty"`
+}
+
+// DayReport = rekap satu hari bisnis. Dipakai untuk X-report (live)
+// maupun snapshot Z-report.
+type DayReport struct {
+	BusinessDate   string          `json:"business_date"`
+	OutletID       *int            `json:"outlet_id,omitempty"` // terisi = laporan satu outlet
+	PeriodStart    time.Time       `json:"period_start"`
+	PeriodEnd      time.Time       `json:"period_end"`
+	GeneratedAt    time.Time       `json:"generated_at"`
+	TotalSales     int             `json:"total_sales"`
+	TotalTransaksi int             `json:"total_transaksi"`
+	ItemTerjual    int             `json:"item_terjual"`
+	Payments       []PaymentTotal  `json:"payments"`
+	TotalRefund    int             `json:"total_refund"`
+	TotalRefundTrx int             `json:"total_refund_transaksi"`
+	Refunds        []PaymentTotal  `json:"refunds"`
+	NetSales       int             `json:"net_sales"`
+	DiskonVoucher  int             `json:"diskon_voucher"`
+	DiskonBaris    int             `json:"diskon_baris"`    // diskon per baris (yang butuh approval manager)
+	DiskonOverride int             `json:"diskon_override"` // harga normal - harga override, dikali qty
+	TotalDiskon    int             `json:"total_diskon"`
+	TaxRate        float64         `json:"tax_rate"`
+	PPN            int             `json:"ppn"`        // PPN di dalam net_sales, harga sudah termasuk pajak
+	TotalVoid      int             `json:"total_void"` // cart yang dibatalkan sebelum checkout
+	ItemVoid       int             `json:"item_void"`
+	PerKasir       []CashierTotal  `json:"per_kasir"`
+	PerOutlet      []OutletTotal   `json:"per_outlet"`
+	PerProduk      []ProductTotal  `json:"per_produk"`
+	PerModifier    []ModifierTotal `json:"per_modifier"`
+}
+
+// ZReport = penutupan hari bisnis. Sekali dibuat tidak bisa diubah.
+type ZReport struct {
+	ZNumber      int       `json:"z_number"`
+	BusinessDate string    `json:"business_date"`
+	ClosedAt     time.Time `json:"closed_at"`
+	ClosedBy     *int      `json:"closed_by,omitempty"`
+	Report       DayReport `json:"report"`
+}
+
+type CloseDayRequest struct {
+	Date     string `json:"date"` // YYYY-MM-DD, kosong = hari bisnis berjalan (outlet default)
+	ClosedBy *int   `json:"closed_by,omitempty"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+type Customer struct {
+	ID          int       `json:"id"`
+	Name        string    `json:"name"`
+	Phone       string    `json:"phone"` // dinormalisasi ke format 62xxxx
+	Email       string    `json:"email,omitempty"`
+	Birthday    *string   `json:"birthday,omitempty"` // YYYY-MM-DD
+	Notes       string    `json:"notes,omitempty"`
+	PriceListID *int      `json:"price_list_id,omitempty"` // tier member / grosir
+	CreditLimit int       `json:"credit_limit"`            // limit kasbon, 0 = tidak boleh kasbon
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type FavoriteProduct struct {
+	ProductID  int    `json:"product_id"`
+	Nama       string `json:"nama"`
+	QtyTerjual int    `json:"qty_terjual"`
+	TotalSpend int    `json:"total_spend"`
+}
+
+type CustomerStats struct {
+	CustomerID    int               `json:"customer_id"`
+	Visits        int               `json:"visits"`
+	TotalSpend    int               `json:"total_spend"`
+	AvgBasket     int               `json:"avg_basket"`
+	FirstVisit    *time.Time        `json:"first_visit,omitempty"`
+	LastVisit     *time.Time        `json:"last_visit,omitempty"`
+	FavoriteItems []FavoriteProduct `json:"favorite_products"`
+}
@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+const (
+	GiftCardIssue  = "issue"
+	GiftCardTopUp  = "topup"
+	GiftCardRedeem = "redeem"
+	GiftCardRefund = "refund" // saldo dikembalikan karena transaksi di-refund
+)
+
+type GiftCard struct {
+	ID         int             `json:"id"`
+	Code       string          `json:"code"`
+	Balance    int             `json:"balance"`
+	CustomerID *int            `json:"customer_id,omitempty"`
+	Active     bool            `json:"active"`
+	ExpiresAt  *time.Time      `json:"expires_at,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+	Entries    []GiftCardEntry `json:"entries,omitempty"`
+}
+
+type GiftCardEntry struct {
+	ID            int       `json:"id"`
+	GiftCardID    int       `json:"gift_card_id"`
+	TransactionID *int      `json:"transaction_id,omitempty"`
+	ShiftID       *int      `json:"shift_id,omitempty"`
+	Type          string    `json:"type"`
+	Method        string    `json:"method,omitempty"` // cara bayar saat issue / top-up
+	Amount        int       `json:"amount"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// IssueGiftCardRequest: code kosong = dibuatkan otomatis.
+// Uang pembelian masuk ke laci shift_id lewat method.
+type IssueGiftCardRequest struct {
+	Code       string     `json:"code"`
+	Amount     int        `json:"amount"`
+	ShiftID    int        `json:"shift_id"`
+	Method     string     `json:"method"`
+	CustomerID *int       `json:"customer_id,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+}
+
+type TopUpGiftCardRequest struct {
+	Amount  int    `json:"amount"`
+	ShiftID int    `json:"shift_id"`
+	Method  string `json:"method"`
+}
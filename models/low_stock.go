@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// LowStockItem = produk yang stoknya di satu outlet sudah <= reorder point.
+type LowStockItem struct {
+	OutletID      int        `json:"outlet_id"`
+	OutletCode    string     `json:"outlet_code"`
+	ProductID     int        `json:"product_id"`
+	ProductName   string     `json:"product_name"`
+	Unit          string     `json:"unit,omitempty"`
+	Stock         int        `json:"stock"`
+	ReorderPoint  int        `json:"reorder_point"`
+	LeadTimeDays  int        `json:"lead_time_days"`
+	AvgDailySales float64    `json:"avg_daily_sales"`
+	SuggestedQty  int        `json:"suggested_qty"`         // target stok - stok sekarang
+	ComputedAt    *time.Time `json:"computed_at,omitempty"` // kapan kecepatan jual dihitung; kosong = belum
+}
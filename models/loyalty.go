@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+const (
+	PointsEarn          = "earn"
+	PointsRedeem        = "redeem"
+	PointsExpire        = "expire"
+	PointsReverseEarn   = "reverse_earn"   // poin hasil transaksi yang di-refund
+	PointsRestoreRedeem = "restore_redeem" // poin yang dipakai bayar dikembalikan saat refund
+)
+
+// LoyaltySettings = aturan dasar poin (satu baris untuk seluruh toko).
+type LoyaltySettings struct {
+	Enabled        bool `json:"enabled"`
+	AmountPerPoint int  `json:"amount_per_point"` // belanja Rp sekian = 1 poin
+	PointValue     int  `json:"point_value"`      // nilai Rp 1 poin saat ditukar
+	ExpiryDays     int  `json:"expiry_days"`      // 0 = tidak kedaluwarsa
+}
+
+// LoyaltyRule = bonus multiplier untuk kategori dan/atau periode promo.
+type LoyaltyRule struct {
+	ID         int        `json:"id"`
+	Name       string     `json:"name"`
+	CategoryID *int       `json:"category_id,omitempty"` // kosong = semua kategori
+	Multiplier float64    `json:"multiplier"`
+	StartsAt   *time.Time `json:"starts_at,omitempty"`
+	EndsAt     *time.Time `json:"ends_at,omitempty"`
+	Active     bool       `json:"active"`
+}
+
+type PointsEntry struct {
+	ID            int        `json:"id"`
+	CustomerID    int        `json:"customer_id"`
+	TransactionID *int       `json:"transaction_id,omitempty"`
+	Type          string     `json:"type"`
+	Points        int        `json:"points"` // + masuk, - keluar
+	Remaining     int        `json:"remaining,omitempty"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+type PointsBalance struct {
+	CustomerID int           `json:"customer_id"`
+	Balance    int           `json:"balance"`
+	Value      int           `json:"value"` // nilai Rp saldo poin
+	Ledger     []PointsEntry `json:"ledger"`
+}
@@ -0,0 +1,43 @@
+package models
+
+// ModifierGroup = kumpulan pilihan tambahan untuk produk, mis. "Ukuran gula" atau "Extra".
+// Required / min_select memaksa kasir memilih; max_select 0 = tanpa batas.
+type ModifierGroup struct {
+	ID        int        `json:"id"`
+	Name      string     `json:"name"`
+	Required  bool       `json:"required"`
+	MinSelect int        `json:"min_select"`
+	MaxSelect int        `json:"max_select"`
+	Active    bool       `json:"active"`
+	Modifiers []Modifier `json:"modifiers,omitempty"`
+}
+
+type Modifier struct {
+	ID         int    `json:"id"`
+	GroupID    int    `json:"group_id"`
+	Name       string `json:"name"`
+	PriceDelta int    `json:"price_delta"`
+	ProductID  *int   `json:"product_id,omitempty"` // stok yang ikut dipotong
+	StockQty   int    `json:"stock_qty,omitempty"`
+	Active     bool   `json:"active"`
+}
+
+// DetailModifier = modifier yang dipilih di satu baris transaksi.
+type DetailModifier struct {
+	ModifierID *int   `json:"modifier_id,omitempty"`
+	GroupName  string `json:"group_name,omitempty"`
+	Name       string `json:"name"`
+	PriceDelta int    `json:"price_delta"` // per item
+}
+
+type ModifierProductsRequest struct {
+	ProductIDs []int `json:"product_ids"`
+}
+
+// ModifierTotal = penjualan modifier di laporan.
+type ModifierTotal struct {
+	ModifierID *int   `json:"modifier_id,omitempty"`
+	Nama       string `json:"nama"`
+	Qty        int    `json:"qty"`
+	Sales      int    `json:"sales"`
+}
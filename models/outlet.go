@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+type Outlet struct {
+	ID         int       `json:"id"`
+	Code       string    `json:"code"` // dipakai di nomor struk & price list outlet
+	Name       string    `json:"name"`
+	Address    string    `json:"address,omitempty"`
+	Timezone   string    `json:"timezone"`        // IANA, mis. Asia/Jakarta, Asia/Makassar, Asia/Jayapura
+	CutoffHour int       `json:"day_cutoff_hour"` // jam lokal pergantian hari bisnis (0-23)
+	Active     bool      `json:"active"`
+	IsDefault  bool      `json:"is_default"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// OutletStock = stok (dan harga khusus, opsional) satu produk di satu outlet.
+type OutletStock struct {
+	OutletID    int    `json:"outlet_id"`
+	OutletCode  string `json:"outlet_code,omitempty"`
+	ProductID   int    `json:"product_id"`
+	ProductName string `json:"product_name,omitempty"`
+	Stock       int    `json:"stock"`
+	Price       *int   `json:"price,omitempty"`      // kosong = harga produk
+	InTransit   int    `json:"in_transit,omitempty"` // kiriman transfer yang belum diterima outlet ini
+	Note        string `json:"note,omitempty"`       // input: alasan penyesuaian stok
+}
+
+// OutletTotal = rekap penjualan satu outlet di laporan.
+type OutletTotal struct {
+	OutletID       int    `json:"outlet_id"`
+	Code           string `json:"code"`
+	Nama           string `json:"nama"`
+	TotalTransaksi int    `json:"total_transaksi"`
+	TotalSales     int    `json:"total_sales"`
+	TotalRefund    int    `json:"total_refund"`
+	NetSales       int    `json:"net_sales"`
+}
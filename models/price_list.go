@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+const (
+	PriceListRetail    = "retail"    // harga umum, termasuk quantity break untuk walk-in
+	PriceListMember    = "member"    // dipasang ke pelanggan
+	PriceListWholesale = "wholesale" // dipasang ke pelanggan
+	PriceListOutlet    = "outlet"    // berlaku untuk semua transaksi di satu outlet
+)
+
+type PriceList struct {
+	ID         int             `json:"id"`
+	Name       string          `json:"name"`
+	Kind       string          `json:"kind"`
+	OutletCode *string         `json:"outlet_code,omitempty"` // wajib untuk kind=outlet
+	Active     bool            `json:"active"`
+	CreatedAt  time.Time       `json:"created_at"`
+	Items      []PriceListItem `json:"items,omitempty"`
+}
+
+// PriceListItem = harga produk mulai quantity MinQty.
+// Contoh quantity break: {min_qty: 1, price: 3500}, {min_qty: 12, price: 3000}.
+type PriceListItem struct {
+	ProductID   int    `json:"product_id"`
+	ProductName string `json:"product_name,omitempty"`
+	MinQty      int    `json:"min_qty"`
+	Price       int    `json:"price"`
+}
@@ -4,6 +4,24 @@ type Product struct {
 	ID         int    `json:"id"`
 	Name       string `json:"name"`
 	Price      int    `json:"price"`
-	Stock      int    `json:"stock"`
+	Stock      int    `json:"stock"`                 // stok outlet yang diminta / total semua outlet; induk: total semua varian
 	CategoryID *int   `json:"category_id,omitempty"` // optional
+	Unit       string `json:"unit,omitempty"`        // satuan stok, mis. g / ml / pcs
+
+	ReorderPoint *int `json:"reorder_point,omitempty"`  // stok <= ini di suatu outlet = menipis
+	LeadTimeDays int  `json:"lead_time_days,omitempty"` // lama pesan ulang sampai barang datang
+	TrackLots    bool `json:"track_lots,omitempty"`     // stok per lot + tanggal kedaluwarsa, dijual FEFO
+	TrackSerials bool `json:"track_serials,omitempty"`  // stok per unit (serial / IMEI), checkout wajib menyebut serialnya
+
+	ParentID   *int              `json:"parent_id,omitempty"` // terisi = varian
+	SKU        string            `json:"sku,omitempty"`
+	Barcode    string            `json:"barcode,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"` // varian, mis. {"size": "L"}
+	Options    []string          `json:"options,omitempty"`    // induk, mis. ["size", "color"]
+	Variants   []Product         `json:"variants,omitempty"`
+
+	ModifierGroups []ModifierGroup `json:"modifier_groups,omitempty"`
+	Recipe         []RecipeItem    `json:"recipe,omitempty"`  // terisi = produk komposit, stoknya dari bahan
+	Bundle         []BundleItem    `json:"bundle,omitempty"`  // terisi = paket, stoknya dari komponen
+	Outlets        []OutletStock   `json:"outlets,omitempty"` // stok & harga per outlet
 }
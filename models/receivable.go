@@ -0,0 +1,62 @@
+package models
+
+import "time"
+
+const (
+	ReceivableCharge  = "charge"  // belanja pakai kasbon
+	ReceivablePayment = "payment" // cicilan / pelunasan
+	ReceivableRefund  = "refund"  // transaksi kasbon di-refund
+)
+
+type ReceivableEntry struct {
+	ID            int       `json:"id"`
+	CustomerID    int       `json:"customer_id"`
+	TransactionID *int      `json:"transaction_id,omitempty"`
+	ReceiptNo     string    `json:"receipt_no,omitempty"`
+	ShiftID       *int      `json:"shift_id,omitempty"`
+	Type          string    `json:"type"`
+	Method        string    `json:"method,omitempty"`
+	Amount        int       `json:"amount"` // + utang bertambah, - utang berkurang
+	Remaining     int       `json:"remaining,omitempty"`
+	Notes         string    `json:"notes,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// Aging = sisa utang per umur transaksi kasbon (hari sejak belanja).
+type Aging struct {
+	Current int `json:"0_30"`
+	Days60  int `json:"31_60"`
+	Days90  int `json:"61_90"`
+	Over90  int `json:"90_plus"`
+}
+
+type CustomerReceivable struct {
+	CustomerID   int    `json:"customer_id"`
+	CustomerName string `json:"customer_name"`
+	Phone        string `json:"phone"`
+	CreditLimit  int    `json:"credit_limit"`
+	Balance      int    `json:"balance"`   // sisa utang; minus = titipan/kredit pelanggan
+	Available    int    `json:"available"` // sisa limit kasbon
+	Aging        Aging  `json:"aging"`
+}
+
+type ReceivablePaymentRequest struct {
+	ShiftID int    `json:"shift_id"`
+	Method  string `json:"method"` // default cash
+	Amount  int    `json:"amount"`
+	Notes   string `json:"notes"`
+}
+
+type StatementLine struct {
+	ReceivableEntry
+	Balance int `json:"balance"` // saldo berjalan setelah baris ini
+}
+
+type Statement struct {
+	CustomerReceivable
+	From           time.Time       `json:"from"`
+	To             time.Time       `json:"to"`
+	OpeningBalance int             `json:"opening_balance"`
+	Lines          []StatementLine `json:"lines"`
+	ClosingBalance int             `json:"closing_balance"`
+}
@@ -0,0 +1,22 @@
+package models
+
+// RecipeItem = bahan untuk membuat 1 produk komposit, mis. 18 (g) kopi.
+type RecipeItem struct {
+	IngredientID   int    `json:"ingredient_id"`
+	IngredientName string `json:"ingredient_name,omitempty"`
+	Unit           string `json:"unit,omitempty"`
+	Quantity       int    `json:"quantity"`
+}
+
+// IngredientUsage = pemakaian bahan dalam satu periode.
+type IngredientUsage struct {
+	OutletID     *int   `json:"outlet_id,omitempty"` // terisi kalau laporan dikelompokkan per outlet
+	OutletCode   string `json:"outlet_code,omitempty"`
+	IngredientID int    `json:"ingredient_id"`
+	Nama         string `json:"nama"`
+	Unit         string `json:"unit"`
+	Used         int    `json:"used"`     // dari penjualan di periode
+	Returned     int    `json:"returned"` // dikembalikan lewat refund di periode
+	Net          int    `json:"net"`
+	Stock        int    `json:"stock"` // stok saat ini (di outlet tersebut / semua outlet)
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+type Refund struct {
+	ID            int                  `json:"id"`
+	TransactionID int                  `json:"transaction_id"`
+	ShiftID       int                  `json:"shift_id"`
+	CashierID     int                  `json:"cashier_id"`
+	TotalAmount   int                  `json:"total_amount"`
+	Reason        string               `json:"reason"`
+	CreatedAt     time.Time            `json:"created_at"`
+	Payments      []TransactionPayment `json:"payments"`
+}
+
+type RefundRequest struct {
+	ShiftID int    `json:"shift_id"`
+	Reason  string `json:"reason"`
+}
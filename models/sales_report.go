@@ -0,0 +1,87 @@
+package models
+
+// Granularity /api/report/sales
+const (
+	BucketHour  = "hour"
+	BucketDay   = "day"
+	BucketWeek  = "week"
+	BucketMonth = "month"
+)
+
+// SalesPoint = angka penjualan satu bucket waktu (atau total satu periode, Bucket kosong).
+type SalesPoint struct {
+	Bucket         string `json:"bucket,omitempty"` // awal bucket, jam lokal outlet: YYYY-MM-DD / YYYY-MM-DDTHH:00
+	TotalSales     int    `json:"total_sales"`
+	TotalTransaksi int    `json:"total_transaksi"`
+	ItemTerjual    int    `json:"item_terjual"`
+	AvgBasket      int    `json:"avg_basket"` // total_sales / total_transaksi
+}
+
+type SalesPeriod struct {
+	StartDate string       `json:"start_date"` // hari bisnis, inklusif
+	EndDate   string       `json:"end_date"`
+	Total     SalesPoint   `json:"total"`
+	Series    []SalesPoint `json:"series"`
+}
+
+// SalesChange = perubahan periode ini vs periode pembanding dalam persen; nil = pembandingnya 0.
+type SalesChange struct {
+	TotalSales     *float64 `json:"total_sales"`
+	TotalTransaksi *float64 `json:"total_transaksi"`
+	ItemTerjual    *float64 `json:"item_terjual"`
+	AvgBasket      *float64 `json:"avg_basket"`
+}
+
+type SalesReport struct {
+	Granularity string      `json:"granularity"`
+	OutletID    *int        `json:"outlet_id,omitempty"`
+	Current     SalesPeriod `json:"current"`
+	Previous    SalesPeriod `json:"previous"` // periode sama panjang tepat sebelumnya
+	Change      SalesChange `json:"change"`
+}
+
+// Ranking & breakdown /api/report/products dan /api/report/breakdown
+const (
+	RankByQty     = "qty"
+	RankByRevenue = "revenue"
+
+	BreakdownCategory = "category"
+	BreakdownCashier  = "cashier"
+	BreakdownPayment  = "payment"
+)
+
+type RankedProduct struct {
+	Rank      int    `json:"rank"`
+	ProductID int    `json:"product_id"`
+	Nama      string `json:"nama"`
+	Qty       int    `json:"qty"`
+	Sales     int    `json:"sales"` // subtotal baris, sebelum diskon voucher
+}
+
+type ProductRanking struct {
+	StartDate string          `json:"start_date"`
+	EndDate   string          `json:"end_date"`
+	OutletID  *int            `json:"outlet_id,omitempty"`
+	SortBy    string          `json:"sort_by"`
+	Order     string          `json:"order"` // top / bottom
+	Items     []RankedProduct `json:"items"`
+}
+
+// BreakdownLine = penjualan satu kategori / kasir / metode bayar.
+type BreakdownLine struct {
+	ID             *int    `json:"id,omitempty"` // category_id / cashier_id; kosong untuk metode bayar & tanpa kategori
+	Nama           string  `json:"nama"`
+	TotalTransaksi int     `json:"total_transaksi"`
+	ItemTerjual    int     `json:"item_terjual,omitempty"`
+	TotalSales     int     `json:"total_sales"`
+	SharePct       float64 `json:"share_pct"` // porsi dari total_sales semua baris
+}
+
+type SalesBreakdown struct {
+	By         string          `json:"by"`
+	StartDate  string          `json:"start_date"`
+	EndDate    string          `json:"end_date"`
+	OutletID   *int            `json:"outlet_id,omitempty"`
+	TotalSales int             `json:"total_sales"`
+	Lines      []BreakdownLine `json:"lines"`
+}
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+const (
+	SerialInStock = "in_stock"
+	SerialSold    = "sold"
+)
+
+// Jenis kejadian di riwayat serial
+const (
+	SerialEventReceived = "received"
+	SerialEventSold     = "sold"
+	SerialEventReturned = "returned"
+)
+
+// ProductSerial = satu unit produk track_serials (serial number / IMEI).
+type ProductSerial struct {
+	ID           int           `json:"id"`
+	ProductID    int           `json:"product_id"`
+	ProductName  string        `json:"product_name,omitempty"`
+	SerialNumber string        `json:"serial_number"`
+	Status       string        `json:"status"`
+	OutletID     int           `json:"outlet_id"`
+	OutletCode   string        `json:"outlet_code,omitempty"`
+	ReceivedAt   time.Time     `json:"received_at"`
+	Events       []SerialEvent `json:"events,omitempty"` // riwayat, terisi di lookup serial
+}
+
+// SerialEvent = satu kejadian di riwayat unit (garansi: dijual kapan, ke siapa, di struk mana).
+type SerialEvent struct {
+	Kind           string    `json:"kind"`
+	OutletID       int       `json:"outlet_id"`
+	OutletCode     string    `json:"outlet_code,omitempty"`
+	TransactionID  *int      `json:"transaction_id,omitempty"`
+	ReceiptNo      string    `json:"receipt_no,omitempty"`
+	CustomerID     *int      `json:"customer_id,omitempty"`
+	CustomerName   string    `json:"customer_name,omitempty"`
+	GoodsReceiptID *int      `json:"goods_receipt_id,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type SerialFilter struct {
+	OutletID  *int
+	ProductID int
+	Status    string
+}
@@ -0,0 +1,83 @@
+package models
+
+import "time"
+
+const (
+	ShiftOpen   = "open"
+	ShiftClosed = "closed"
+
+	CashPayIn  = "pay_in"
+	CashPayOut = "pay_out"
+)
+
+type Shift struct {
+	ID           int         `json:"id"`
+	CashierID    int         `json:"cashier_id"`
+	CashierName  string      `json:"cashier_name,omitempty"`
+	OutletID     int         `json:"outlet_id"`
+	OutletCode   string      `json:"outlet_code,omitempty"`
+	Terminal     string      `json:"terminal"`
+	Status       string      `json:"status"`
+	OpeningFloat int         `json:"opening_float"`
+	OpenedAt     time.Time   `json:"opened_at"`
+	ClosedAt     *time.Time  `json:"closed_at,omitempty"`
+	ExpectedCash *int        `json:"expected_cash,omitempty"`
+	CountedCash  *int        `json:"counted_cash,omitempty"`
+	OverShort    *int        `json:"over_short,omitempty"`
+	Notes        string      `json:"notes,omitempty"`
+	CashCounts   []CashCount `json:"cash_counts,omitempty"`
+}
+
+// CashCount = jumlah lembar/keping per pecahan saat hitung laci.
+type CashCount struct {
+	Denomination int `json:"denomination"`
+	Quantity     int `json:"quantity"`
+}
+
+// CashMovement = kas kecil masuk/keluar laci di luar penjualan.
+type CashMovement struct {
+	ID        int       `json:"id"`
+	ShiftID   int       `json:"shift_id"`
+	Type      string    `json:"type"`
+	Amount    int       `json:"amount"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type OpenShiftRequest struct {
+	CashierID    int    `json:"cashier_id"`
+	OutletID     *int   `json:"outlet_id,omitempty"` // kosong = outlet kasir, lalu outlet default
+	Terminal     string `json:"terminal"`
+	OpeningFloat int    `json:"opening_float"`
+}
+
+type CloseShiftRequest struct {
+	CashCounts []CashCount `json:"cash_counts"`
+	Notes      string      `json:"notes"`
+}
+
+type PaymentTotal struct {
+	Method string `json:"method"`
+	Amount int    `json:"amount"`
+}
+
+// ShiftSummary = rekap tutup shift: kas seharusnya vs kas dihitung.
+type ShiftSummary struct {
+	Shift             Shift          `json:"shift"`
+	TotalTransaksi    int            `json:"total_transaksi"`
+	TotalSales        int            `json:"total_sales"`
+	Payments          []PaymentTotal `json:"payments"`
+	OpeningFloat      int            `json:"opening_float"`
+	TotalRefund       int            `json:"total_refund"`
+	CashSales         int            `json:"cash_sales"`
+	CashRefunds       int            `json:"cash_refunds"`
+	PayIns            int            `json:"pay_ins"`
+	PayOuts           int            `json:"pay_outs"`
+	DebtPayments      int            `json:"debt_payments"` // pembayaran kasbon yang diterima di shift ini
+	CashDebtPayments  int            `json:"cash_debt_payments"`
+	GiftCardSales     int            `json:"gift_card_sales"` // penjualan & top-up gift card
+	CashGiftCardSales int            `json:"cash_gift_card_sales"`
+	ExpectedCash      int            `json:"expected_cash"`
+	CountedCash       *int           `json:"counted_cash,omitempty"`
+	OverShort         *int           `json:"over_short,omitempty"`
+}
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+const MovementReceipt = "receipt" // penerimaan barang (goods receipt)
+
+// StockLot = satu lot / batch produk di satu outlet.
+type StockLot struct {
+	ID          int       `json:"id"`
+	OutletID    int       `json:"outlet_id"`
+	OutletCode  string    `json:"outlet_code,omitempty"`
+	ProductID   int       `json:"product_id"`
+	ProductName string    `json:"product_name,omitempty"`
+	LotNumber   string    `json:"lot_number"`
+	ExpiryDate  *string   `json:"expiry_date,omitempty"` // YYYY-MM-DD
+	Quantity    int       `json:"quantity"`
+	ReceivedAt  time.Time `json:"received_at"`
+	DaysLeft    *int      `json:"days_left,omitempty"` // sampai kedaluwarsa, dari hari bisnis outlet
+	Expired     bool      `json:"expired,omitempty"`
+}
+
+type GoodsReceipt struct {
+	ID         int                `json:"id"`
+	OutletID   *int               `json:"outlet_id"` // kosong = outlet default
+	OutletCode string             `json:"outlet_code,omitempty"`
+	Supplier   string             `json:"supplier,omitempty"`
+	Note       string             `json:"note,omitempty"`
+	CreatedAt  time.Time          `json:"created_at"`
+	Items      []GoodsReceiptItem `json:"items"`
+}
+
+type GoodsReceiptItem struct {
+	ProductID   int     `json:"product_id"`
+	ProductName string  `json:"product_name,omitempty"`
+	Quantity    int     `json:"quantity"`
+	LotNumber   string  `json:"lot_number,omitempty"`  // wajib untuk produk track_lots
+	ExpiryDate  *string `json:"expiry_date,omitempty"` // YYYY-MM-DD
+	LotID       *int    `json:"lot_id,omitempty"`
+
+	SerialNumbers []string `json:"serial_numbers,omitempty"` // wajib untuk produk track_serials, sebanyak quantity
+}
+
+// LotAdjustRequest = koreksi quantity satu lot (stok opname, barang rusak / kedaluwarsa dibuang).
+type LotAdjustRequest struct {
+	Quantity int    `json:"quantity"`
+	Note     string `json:"note,omitempty"`
+}
@@ -0,0 +1,67 @@
+package models
+
+import "time"
+
+const (
+	TransferDraft     = "draft"
+	TransferSent      = "sent"     // stok sudah keluar dari outlet asal, sedang di jalan
+	TransferReceived  = "received" // stok masuk ke outlet tujuan
+	TransferCancelled = "cancelled"
+
+	MovementTransferOut = "transfer_out"
+	MovementTransferIn  = "transfer_in"
+	MovementAdjustment  = "adjustment" // stok di-set manual
+	MovementSale        = "sale"       // terjual di checkout (barang, bahan resep / komponen paket, modifier)
+	MovementRefund      = "refund"     // kembali karena transaksi di-refund
+)
+
+type StockTransfer struct {
+	ID             int                 `json:"id"`
+	FromOutletID   int                 `json:"from_outlet_id"`
+	FromOutletCode string              `json:"from_outlet_code,omitempty"`
+	ToOutletID     int                 `json:"to_outlet_id"`
+	ToOutletCode   string              `json:"to_outlet_code,omitempty"`
+	Status         string              `json:"status"`
+	Note           string              `json:"note,omitempty"`
+	CreatedAt      time.Time           `json:"created_at"`
+	SentAt         *time.Time          `json:"sent_at,omitempty"`
+	ReceivedAt     *time.Time          `json:"received_at,omitempty"`
+	Items          []StockTransferItem `json:"items"`
+}
+
+type StockTransferItem struct {
+	ProductID        int    `json:"product_id"`
+	ProductName      string `json:"product_name,omitempty"`
+	Quantity         int    `json:"quantity"`                    // dikirim
+	ReceivedQuantity *int   `json:"received_quantity,omitempty"` // terisi setelah diterima
+	Discrepancy      int    `json:"discrepancy,omitempty"`       // quantity - received_quantity
+	Note             string `json:"note,omitempty"`
+}
+
+// ReceiveTransferRequest: item yang tidak disebut dianggap diterima utuh.
+type ReceiveTransferRequest struct {
+	Items []StockTransferItem `json:"items,omitempty"` // product_id + received_quantity (+ note)
+	Note  string              `json:"note,omitempty"`
+}
+
+// StockMovement = satu baris buku mutasi stok outlet.
+type StockMovement struct {
+	ID            int       `json:"id"`
+	OutletID      int       `json:"outlet_id"`
+	OutletCode    string    `json:"outlet_code"`
+	ProductID     int       `json:"product_id"`
+	ProductName   string    `json:"product_name"`
+	Quantity      int       `json:"quantity"` // + masuk, - keluar
+	Kind          string    `json:"kind"`
+	TransferID    *int      `json:"transfer_id,omitempty"`
+	TransactionID *int      `json:"transaction_id,omitempty"` // sale / refund
+	Note          string    `json:"note,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type StockMovementFilter struct {
+	Start     time.Time
+	End       time.Time
+	OutletID  int
+	ProductID int
+}
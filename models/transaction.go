@@ -2,27 +2,96 @@ package models
 
 import "time"
 
+const (
+	PaymentCash     = "cash"
+	PaymentCard     = "card"
+	PaymentQRIS     = "qris"
+	PaymentTransfer = "transfer"
+	PaymentPoints   = "points"    // amount dalam Rupiah, dikonversi pakai point_value
+	PaymentAccount  = "account"   // kasbon, masuk buku piutang pelanggan
+	PaymentGiftCard = "gift_card" // reference = kode gift card
+)
+
 type Transaction struct {
-	ID          int                 `json:"id"`
-	TotalAmount int                 `json:"total_amount"`
-	CreatedAt   time.Time           `json:"created_at"`
-	Details     []TransactionDetail `json:"details"`
+	ID             int                  `json:"id"`
+	ReceiptNo      string               `json:"receipt_no"`
+	OutletID       *int                 `json:"outlet_id,omitempty"`
+	OutletCode     string               `json:"outlet_code,omitempty"`
+	OutletTimezone string               `json:"outlet_timezone,omitempty"`
+	ShiftID        *int                 `json:"shift_id,omitempty"`
+	CashierID      *int                 `json:"cashier_id,omitempty"`
+	CashierName    string               `json:"cashier_name,omitempty"`
+	CustomerID     *int                 `json:"customer_id,omitempty"`
+	CustomerName   string               `json:"customer_name,omitempty"`
+	VoucherCode    string               `json:"voucher_code,omitempty"`
+	DiscountAmount int                  `json:"discount_amount,omitempty"`
+	TotalAmount    int                  `json:"total_amount"` // sudah dikurangi diskon
+	PaidAmount     int                  `json:"paid_amount"`
+	ChangeAmount   int                  `json:"change_amount"`
+	PointsEarned   int                  `json:"points_earned,omitempty"`
+	PointsUsed     int                  `json:"points_used,omitempty"`
+	CreatedAt      time.Time            `json:"created_at"`
+	Details        []TransactionDetail  `json:"details"`
+	Payments       []TransactionPayment `json:"payments"`
+	LowStock       []LowStockItem       `json:"low_stock,omitempty"` // checkout: stok yang baru menipis
 }
 
 type TransactionDetail struct {
+	ID             int    `json:"id"`
+	TransactionID  int    `json:"transaction_id"`
+	ProductID      int    `json:"product_id"`
+	ProductName    string `json:"product_name,omitempty"`
+	Quantity       int    `json:"quantity"`
+	UnitPrice      int    `json:"unit_price"`
+	PriceListID    *int   `json:"price_list_id,omitempty"` // kosong = harga dasar produk
+	DiscountAmount int    `json:"discount_amount,omitempty"`
+	Subtotal       int    `json:"subtotal"` // unit_price x quantity - discount_amount
+
+	Modifiers     []DetailModifier `json:"modifiers,omitempty"`      // sudah termasuk di unit_price
+	SerialNumbers []string         `json:"serial_numbers,omitempty"` // produk track_serials
+}
+
+// TransactionPayment = tender yang dipakai membayar transaksi.
+// Amount untuk cash sudah dikurangi kembalian.
+type TransactionPayment struct {
 	ID            int    `json:"id"`
 	TransactionID int    `json:"transaction_id"`
+	Method        string `json:"method"`
+	Amount        int    `json:"amount"`
+	Reference     string `json:"reference,omitempty"` // gift card: kode yang disamarkan
+}
+
+type TransactionFilter struct {
+	ReceiptNo  string // cocok sebagian, case-insensitive
+	ShiftID    int
+	CustomerID int
+	Limit      int
+}
+
+type CheckoutItem struct {
 	ProductID     int    `json:"product_id"`
-	ProductName   string `json:"product_name,omitempty"`
 	Quantity      int    `json:"quantity"`
-	Subtotal      int    `json:"subtotal"`
+	PriceOverride *int   `json:"price_override,omitempty"` // harga satuan manual, butuh approval
+	Discount      int    `json:"discount,omitempty"`       // potongan Rupiah per baris, butuh approval
+	Reason        string `json:"reason,omitempty"`
+	Modifiers     []int  `json:"modifiers,omitempty"` // id modifier, boleh berulang (mis. 2x extra shot)
+
+	SerialNumbers []string `json:"serial_numbers,omitempty"` // wajib untuk produk track_serials, sebanyak quantity
 }
 
-type CheckoutItem struct {
-	ProductID int `json:"product_id"`
-	Quantity  int `json:"quantity"`
+type CheckoutPayment struct {
+	Method    string `json:"method"`
+	Amount    int    `json:"amount"`
+	Reference string `json:"reference,omitempty"` // wajib untuk gift_card (kode kartu)
 }
 
 type CheckoutRequest struct {
-	Items []CheckoutItem `json:"items"`
+	ShiftID       int               `json:"shift_id"`
+	CustomerID    *int              `json:"customer_id,omitempty"`
+	CustomerPhone string            `json:"customer_phone,omitempty"` // alternatif customer_id
+	VoucherCode   string            `json:"voucher_code,omitempty"`
+	Approval      *Approval         `json:"approval,omitempty"` // wajib kalau ada override / diskon baris
+	ApprovedBy    *int              `json:"-"`                  // diisi service setelah PIN manager dicek
+	Items         []CheckoutItem    `json:"items"`
+	Payments      []CheckoutPayment `json:"payments,omitempty"` // kosong = cash pas
 }
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+const (
+	VoucherPercent = "percent" // value = persen dari subtotal
+	VoucherFixed   = "fixed"   // value = potongan Rupiah
+)
+
+type Voucher struct {
+	ID          int        `json:"id"`
+	Code        string     `json:"code"`
+	Name        string     `json:"name"`
+	Type        string     `json:"type"`
+	Value       int        `json:"value"`
+	MinSpend    int        `json:"min_spend"`
+	MaxDiscount int        `json:"max_discount"` // 0 = tanpa batas
+	StartsAt    *time.Time `json:"starts_at,omitempty"`
+	EndsAt      *time.Time `json:"ends_at,omitempty"`
+	UsageLimit  int        `json:"usage_limit"` // 0 = tanpa batas
+	UsedCount   int        `json:"used_count"`
+	Active      bool       `json:"active"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
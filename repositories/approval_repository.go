@@ -0,0 +1,254 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"kasir-api/models"
+	"math"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ApprovalRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewApprovalRepository(db *pgxpool.Pool) *ApprovalRepository {
+	return &ApprovalRepository{db: db}
+}
+
+// VerifyManagerPIN mengecek PIN manager aktif dengan baris cashier ter-lock, jadi percobaan paralel
+// tetap dihitung satu per satu. PIN salah menaikkan counter (dikunci lockFor setelah maxFailed kali)
+// dan dicatat ke approval_failures; PIN benar me-reset counter.
+func (r *ApprovalRepository) VerifyManagerPIN(managerID int, source string, check func(hash string) bool, maxFailed int, lockFor time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var role, pinHash string
+	var active bool
+	var failed int
+	var lockedUntil *time.Time
+	err = tx.QueryRow(ctx,
+		`SELECT role, active, pin_hash, pin_failed_attempts, CASE WHEN pin_locked_until > now() THEN pin_locked_until END
+		 FROM cashiers WHERE id=$1 FOR UPDATE`,
+		managerID,
+	).Scan(&role, &active, &pinHash, &failed, &lockedUntil)
+	if err != nil {
+		return errors.New("manager belum ada")
+	}
+	if role != models.RoleManager || !active {
+		return errors.New("approval hanya bisa dari manager aktif")
+	}
+	if pinHash == "" {
+		return errors.New("manager belum punya PIN")
+	}
+
+	// Selama terkunci PIN tidak dicek sama sekali, percobaannya tetap masuk audit
+	if lockedUntil != nil {
+		if err := recordApprovalFailure(ctx, tx, managerID, source, true); err != nil {
+			return err
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return err
+		}
+		return fmt.Errorf("PIN manager terkunci karena terlalu banyak salah, coba lagi %d menit lagi",
+			int(math.Ceil(time.Until(*lockedUntil).Minutes())))
+	}
+
+	if check(pinHash) {
+		if failed > 0 {
+			if _, err := tx.Exec(ctx, `UPDATE cashiers SET pin_failed_attempts = 0 WHERE id = $1`, managerID); err != nil {
+				return err
+			}
+		}
+		return tx.Commit(ctx)
+	}
+
+	failed++
+	locked := failed >= maxFailed
+	if locked {
+		_, err = tx.Exec(ctx,
+			`UPDATE cashiers SET pin_failed_attempts = 0, pin_locked_until = now() + make_interval(secs => $2) WHERE id = $1`,
+			managerID, lockFor.Seconds(),
+		)
+	} else {
+		_, err = tx.Exec(ctx, `UPDATE cashiers SET pin_failed_attempts = $2 WHERE id = $1`, managerID, failed)
+	}
+	if err != nil {
+		return err
+	}
+	if err := recordApprovalFailure(ctx, tx, managerID, source, locked); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	if locked {
+		return fmt.Errorf("pin manager salah %d kali, PIN dikunci %d menit", maxFailed, int(lockFor.Minutes()))
+	}
+	return errors.New("pin manager salah")
+}
+
+func recordApprovalFailure(ctx context.Context, q querier, managerID int, source string, locked bool) error {
+	_, err := q.Exec(ctx,
+		`INSERT INTO approval_failures (manager_id, source, locked) VALUES ($1,$2,$3)`,
+		managerID, source, locked,
+	)
+	return err
+}
+
+// Failures = audit trail PIN manager yang salah / dicoba saat terkunci.
+func (r *ApprovalRepository) Failures(f models.ApprovalFailureFilter) ([]models.ApprovalFailure, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	query := `
+		SELECT a.id, a.manager_id, COALESCE(m.name, ''), a.source, a.locked, a.created_at
+		FROM approval_failures a
+		LEFT JOIN cashiers m ON m.id = a.manager_id
+		WHERE a.created_at >= $1 AND a.created_at < $2`
+	args := []any{f.Start, f.End}
+	if f.ManagerID > 0 {
+		args = append(args, f.ManagerID)
+		query += fmt.Sprintf(` AND a.manager_id = $%d`, len(args))
+	}
+	query += ` ORDER BY a.id`
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]models.ApprovalFailure, 0)
+	for rows.Next() {
+		var a models.ApprovalFailure
+		if err := rows.Scan(&a.ID, &a.ManagerID, &a.ManagerName, &a.Source, &a.Locked, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+func (r *ApprovalRepository) CreateToken(t *models.ApprovalToken) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.db.Exec(ctx,
+		`INSERT INTO approval_tokens (token, manager_id, expires_at) VALUES ($1,$2,$3)`,
+		t.Token, t.ManagerID, t.ExpiresAt,
+	)
+	return err
+}
+
+// consumeApprovalToken memakai token sekali pakai di dalam tx checkout.
+// Kalau checkout gagal, pemakaian token ikut di-rollback.
+func consumeApprovalToken(ctx context.Context, q querier, token string) (int, error) {
+	var managerID int
+	err := q.QueryRow(ctx,
+		`UPDATE approval_tokens SET used_at = now()
+		 WHERE token = $1 AND used_at IS NULL AND expires_at > now()
+		 RETURNING manager_id`,
+		token,
+	).Scan(&managerID)
+	if err != nil {
+		return 0, errors.New("token approval tidak valid atau sudah dipakai")
+	}
+	return managerID, nil
+}
+
+// PriceOverrides = audit trail override harga / diskon baris.
+func (r *ApprovalRepository) PriceOverrides(f models.PriceOverrideFilter) ([]models.PriceOverride, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	query := `
+		SELECT o.id, o.transaction_id, COALESCE(t.receipt_no, ''), o.transaction_detail_id, o.product_id,
+			COALESCE(p.name, ''), o.quantity, o.cashier_id, COALESCE(c.name, ''), o.approved_by, COALESCE(m.name, ''),
+			o.original_price, o.override_price, o.discount_amount, o.reason, o.created_at
+		FROM price_overrides o
+		JOIN transactions t ON t.id = o.transaction_id
+		LEFT JOIN products p ON p.id = o.product_id
+		LEFT JOIN cashiers c ON c.id = o.cashier_id
+		LEFT JOIN cashiers m ON m.id = o.approved_by
+		WHERE o.created_at >= $1 AND o.created_at < $2`
+	args := []any{f.Start, f.End}
+
+	if f.CashierID > 0 {
+		args = append(args, f.CashierID)
+		query += fmt.Sprintf(` AND o.cashier_id = $%d`, len(args))
+	}
+	if f.ApprovedBy > 0 {
+		args = append(args, f.ApprovedBy)
+		query += fmt.Sprintf(` AND o.approved_by = $%d`, len(args))
+	}
+	query += ` ORDER BY o.id`
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]models.PriceOverride, 0)
+	for rows.Next() {
+		var o models.PriceOverride
+		err := rows.Scan(&o.ID, &o.TransactionID, &o.ReceiptNo, &o.TransactionDetailID, &o.ProductID,
+			&o.ProductName, &o.Quantity, &o.CashierID, &o.CashierName, &o.ApprovedBy, &o.ApproverName,
+			&o.OriginalPrice, &o.OverridePrice, &o.DiscountAmount, &o.Reason, &o.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, o)
+	}
+	return out, rows.Err()
+}
+
+// overrideLine = baris checkout yang harganya diubah manual / diberi diskon.
+type overrideLine struct {
+	detail        int // index di details
+	originalPrice int
+	overridePrice int // harga satuan setelah override, sebelum modifier
+	reason        string
+}
+
+// resolveApproval menentukan siapa yang meng-approve override di checkout:
+// PIN manager (sudah dicek di service), token approval, atau kasir shift itu sendiri kalau manager.
+func resolveApproval(ctx context.Context, q querier, req models.CheckoutRequest, cashierID int, cashierRole string) (int, error) {
+	if req.ApprovedBy != nil {
+		return *req.ApprovedBy, nil
+	}
+	if req.Approval != nil && req.Approval.Token != "" {
+		return consumeApprovalToken(ctx, q, req.Approval.Token)
+	}
+	if cashierRole == models.RoleManager {
+		return cashierID, nil
+	}
+	return 0, errors.New("override harga / diskon baris butuh approval manager")
+}
+
+func insertPriceOverrides(ctx context.Context, q querier, transactionID, cashierID, approvedBy int, details []models.TransactionDetail, lines []overrideLine) error {
+	for _, l := range lines {
+		d := details[l.detail]
+		_, err := q.Exec(ctx,
+			`INSERT INTO price_overrides (transaction_id, transaction_detail_id, product_id, quantity, cashier_id, approved_by,
+			 original_price, override_price, discount_amount, reason)
+			 VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)`,
+			transactionID, d.ID, d.ProductID, d.Quantity, cashierID, approvedBy,
+			l.originalPrice, l.overridePrice, d.DiscountAmount, l.reason,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
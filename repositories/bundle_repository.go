@@ -0,0 +1,210 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"kasir-api/models"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type BundleRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewBundleRepository(db *pgxpool.Pool) *BundleRepository {
+	return &BundleRepository{db: db}
+}
+
+func loadBundle(ctx context.Context, q querier, bundleID int) ([]models.BundleItem, error) {
+	rows, err := q.Query(ctx, `
+		SELECT b.product_id, COALESCE(p.name, ''), b.quantity, COALESCE(p.price, 0)
+		FROM bundle_items b
+		LEFT JOIN products p ON p.id = b.product_id
+		WHERE b.bundle_id = $1
+		ORDER BY b.product_id
+	`, bundleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]models.BundleItem, 0)
+	for rows.Next() {
+		var it models.BundleItem
+		if err := rows.Scan(&it.ProductID, &it.ProductName, &it.Quantity, &it.Price); err != nil {
+			return nil, err
+		}
+		out = append(out, it)
+	}
+	return out, rows.Err()
+}
+
+func (r *BundleRepository) Get(bundleID int) ([]models.BundleItem, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var exists bool
+	if err := r.db.QueryRow(ctx, `SELECT TRUE FROM products WHERE id=$1`, bundleID).Scan(&exists); err != nil {
+		return nil, errors.New("produk belum ada")
+	}
+	return loadBundle(ctx, r.db, bundleID)
+}
+
+// Set mengganti isi paket (replace). Isi kosong = produk kembali jadi produk biasa.
+// Paket tidak bertingkat dan tidak bisa sekaligus punya resep.
+func (r *BundleRepository) Set(bundleID int, items []models.BundleItem) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var isComponent, isIngredient, hasRecipe, hasVariants bool
+	err = tx.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM bundle_items WHERE product_id = p.id),
+			EXISTS (SELECT 1 FROM product_recipes WHERE ingredient_id = p.id),
+			EXISTS (SELECT 1 FROM product_recipes WHERE product_id = p.id),
+			EXISTS (SELECT 1 FROM products v WHERE v.parent_id = p.id)
+		FROM products p WHERE p.id = $1 FOR UPDATE OF p
+	`, bundleID).Scan(&isComponent, &isIngredient, &hasRecipe, &hasVariants)
+	if err != nil {
+		return errors.New("produk belum ada")
+	}
+	if len(items) > 0 {
+		switch {
+		case isComponent:
+			return errors.New("produk ini isi paket lain, tidak bisa jadi paket")
+		case isIngredient:
+			return errors.New("produk ini dipakai sebagai bahan, tidak bisa jadi paket")
+		case hasRecipe:
+			return errors.New("produk ini punya resep, hapus resepnya dulu")
+		case hasVariants:
+			return errors.New("paket dipasang di varian, bukan di produk induk")
+		}
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM bundle_items WHERE bundle_id=$1`, bundleID); err != nil {
+		return err
+	}
+
+	for _, it := range items {
+		var isBundle, isParent, trackSerials bool
+		err := tx.QueryRow(ctx, `
+			SELECT EXISTS (SELECT 1 FROM bundle_items WHERE bundle_id = p.id),
+				EXISTS (SELECT 1 FROM products v WHERE v.parent_id = p.id),
+				p.track_serials
+			FROM products p WHERE p.id = $1
+		`, it.ProductID).Scan(&isBundle, &isParent, &trackSerials)
+		if err != nil {
+			return fmt.Errorf("product id %d belum ada", it.ProductID)
+		}
+		if isBundle {
+			return fmt.Errorf("product id %d sudah paket (paket tidak bertingkat)", it.ProductID)
+		}
+		if isParent {
+			return fmt.Errorf("product id %d punya varian, pilih variannya", it.ProductID)
+		}
+		// Nomor seri di checkout diisi per baris produk, komponen paket tidak punya baris sendiri
+		if trackSerials {
+			return fmt.Errorf("product id %d dicatat per nomor seri, tidak bisa jadi isi paket", it.ProductID)
+		}
+
+		_, err = tx.Exec(ctx,
+			`INSERT INTO bundle_items (bundle_id, product_id, quantity) VALUES ($1,$2,$3)`,
+			bundleID, it.ProductID, it.Quantity,
+		)
+		if isUniqueViolation(err) {
+			return fmt.Errorf("product id %d dobel", it.ProductID)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// bundleLine = isi paket untuk 1 bundle; price = harga dasar komponen (bobot alokasi).
+type bundleLine struct {
+	productID int
+	quantity  int
+	price     int
+}
+
+// loadBundles mengambil isi banyak paket sekaligus (bundle_id -> komponen).
+func loadBundles(ctx context.Context, q querier, productIDs []int) (map[int][]bundleLine, error) {
+	rows, err := q.Query(ctx, `
+		SELECT b.bundle_id, b.product_id, b.quantity, p.price
+		FROM bundle_items b
+		JOIN products p ON p.id = b.product_id
+		WHERE b.bundle_id = ANY($1)
+		ORDER BY b.bundle_id, b.product_id
+	`, productIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make(map[int][]bundleLine)
+	for rows.Next() {
+		var bundleID int
+		var l bundleLine
+		if err := rows.Scan(&bundleID, &l.productID, &l.quantity, &l.price); err != nil {
+			return nil, err
+		}
+		out[bundleID] = append(out[bundleID], l)
+	}
+	return out, rows.Err()
+}
+
+// allocateBundle membagi subtotal baris paket ke komponennya, proporsional terhadap
+// harga dasar x qty komponen. Sisa pembulatan masuk ke komponen terakhir
+// supaya jumlah alokasi selalu sama persis dengan subtotal.
+func allocateBundle(subtotal int, bundle []bundleLine) []int {
+	weights := make([]int, len(bundle))
+	totalWeight := 0
+	for i, c := range bundle {
+		weights[i] = c.price * c.quantity
+		totalWeight += weights[i]
+	}
+	if totalWeight == 0 {
+		// semua komponen harga 0: bagi rata per qty
+		for i, c := range bundle {
+			weights[i] = c.quantity
+			totalWeight += c.quantity
+		}
+	}
+
+	out := make([]int, len(bundle))
+	allocated := 0
+	for i := range bundle {
+		if i == len(bundle)-1 {
+			out[i] = subtotal - allocated
+			break
+		}
+		out[i] = subtotal * weights[i] / totalWeight
+		allocated += out[i]
+	}
+	return out
+}
+
+func insertBundleAllocations(ctx context.Context, q querier, transactionID int, d models.TransactionDetail, bundle []bundleLine) error {
+	amounts := allocateBundle(d.Subtotal, bundle)
+	for i, c := range bundle {
+		_, err := q.Exec(ctx,
+			`INSERT INTO transaction_bundle_allocations (transaction_id, transaction_detail_id, product_id, quantity, amount)
+			 VALUES ($1,$2,$3,$4,$5)`,
+			transactionID, d.ID, c.productID, c.quantity*d.Quantity, amounts[i],
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
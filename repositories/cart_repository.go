@@ -0,0 +1,517 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"kasir-api/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// CartRepository menyimpan keranjang di server. Checkout memakai
+// TransactionRepository.createTransaction di dalam tx yang sama.
+type CartRepository struct {
+	db     *pgxpool.Pool
+	txRepo *TransactionRepository
+}
+
+func NewCartRepository(db *pgxpool.Pool, txRepo *TransactionRepository) *CartRepository {
+	return &CartRepository{db: db, txRepo: txRepo}
+}
+
+const cartColumns = `ca.id, ca.status, ca.terminal, ca.outlet_id, ca.customer_id, COALESCE(cu.name, ''), ca.voucher_code, ca.note,
+	ca.transaction_id, ca.created_at, ca.updated_at`
+
+const cartFrom = ` FROM carts ca LEFT JOIN customers cu ON cu.id = ca.customer_id`
+
+func scanCart(row pgx.Row) (*models.Cart, error) {
+	var c models.Cart
+	err := row.Scan(&c.ID, &c.Status, &c.Terminal, &c.OutletID, &c.CustomerID, &c.CustomerName, &c.VoucherCode, &c.Note,
+		&c.TransactionID, &c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	c.Items = make([]models.CartItem, 0)
+	return &c, nil
+}
+
+func (r *CartRepository) GetAll(status string) ([]models.Cart, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	query := `SELECT ` + cartColumns + cartFrom
+	args := []any{}
+	if status != "" {
+		query += ` WHERE ca.status = $1`
+		args = append(args, status)
+	}
+	query += ` ORDER BY ca.updated_at DESC, ca.id DESC LIMIT 200`
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]models.Cart, 0)
+	for rows.Next() {
+		c, err := scanCart(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *c)
+	}
+	return out, rows.Err()
+}
+
+func (r *CartRepository) Create(req models.CartRequest) (*models.Cart, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	outletID, _, err := resolveOutlet(ctx, r.db, req.OutletID)
+	if err != nil {
+		return nil, err
+	}
+	customerID, _, _, err := resolveCustomer(ctx, r.db, req.CustomerID, req.CustomerPhone)
+	if err != nil {
+		return nil, err
+	}
+	voucher, note := "", ""
+	if req.VoucherCode != nil {
+		voucher = *req.VoucherCode
+	}
+	if req.Note != nil {
+		note = *req.Note
+	}
+
+	var id int
+	err = r.db.QueryRow(ctx,
+		`INSERT INTO carts (terminal, outlet_id, customer_id, voucher_code, note) VALUES ($1,$2,$3,$4,$5) RETURNING id`,
+		req.Terminal, outletID, customerID, voucher, note,
+	).Scan(&id)
+	if err != nil {
+		return nil, err
+	}
+	return getCart(ctx, r.db, id)
+}
+
+func (r *CartRepository) GetByID(id int) (*models.Cart, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return getCart(ctx, r.db, id)
+}
+
+// getCart memuat cart + item dengan harga & stok outlet cart yang berlaku sekarang dan preview total.
+func getCart(ctx context.Context, q querier, id int) (*models.Cart, error) {
+	c, err := scanCart(q.QueryRow(ctx, `SELECT `+cartColumns+cartFrom+` WHERE ca.id = $1`, id))
+	if err != nil {
+		return nil, errors.New("cart belum ada")
+	}
+	var outletCode string
+	if err := q.QueryRow(ctx, `SELECT code FROM outlets WHERE id = $1`, c.OutletID).Scan(&outletCode); err != nil {
+		return nil, err
+	}
+
+	var priceList *int
+	if c.CustomerID != nil {
+		err := q.QueryRow(ctx, `SELECT price_list_id FROM customers WHERE id = $1`, *c.CustomerID).Scan(&priceList)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	rows, err := q.Query(ctx, `
+		SELECT ci.id, ci.product_id, p.name, ci.quantity, COALESCE(op.price, p.price), `+availableStock("$2::int")+`,
+			ci.serial_numbers, ci.modifiers, ci.price_override, ci.discount, ci.reason
+		FROM cart_items ci
+		JOIN products p ON p.id = ci.product_id
+		LEFT JOIN outlet_products op ON op.product_id = p.id AND op.outlet_id = $2
+		WHERE ci.cart_id = $1
+		ORDER BY ci.added_at, ci.id
+	`, id, c.OutletID)
+	if err != nil {
+		return nil, err
+	}
+	type line struct {
+		item      models.CartItem
+		base      int
+		stock     int
+		modifiers []int
+	}
+	var lines []line
+	qtyByProduct := make(map[int]int)
+	for rows.Next() {
+		var l line
+		err := rows.Scan(&l.item.ID, &l.item.ProductID, &l.item.ProductName, &l.item.Quantity, &l.base, &l.stock,
+			&l.item.SerialNumbers, &l.modifiers, &l.item.PriceOverride, &l.item.Discount, &l.item.Reason)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		lines = append(lines, l)
+		qtyByProduct[l.item.ProductID] += l.item.Quantity
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Harga & stok sama seperti checkout: quantity break dan stok dihitung dari total qty per produk
+	total := models.CartTotal{}
+	warned := make(map[int]bool)
+	for _, l := range lines {
+		qty := qtyByProduct[l.item.ProductID]
+		price, listID, err := resolvePrice(ctx, q, l.item.ProductID, qty, l.base, priceList, outletCode)
+		if err != nil {
+			return nil, err
+		}
+		if l.item.PriceOverride != nil && *l.item.PriceOverride != price {
+			price, listID = *l.item.PriceOverride, nil
+		}
+		modifiers, delta, err := resolveModifiers(ctx, q, l.item.ProductID, l.item.ProductName, l.modifiers)
+		if err != nil {
+			total.Warnings = append(total.Warnings, err.Error())
+		}
+		for _, m := range modifiers {
+			l.item.Modifiers = append(l.item.Modifiers, m.DetailModifier)
+		}
+		l.item.UnitPrice = price + delta
+		l.item.PriceListID = listID
+		l.item.Subtotal = l.item.UnitPrice * l.item.Quantity
+		if l.item.Discount > l.item.Subtotal {
+			total.Warnings = append(total.Warnings, fmt.Sprintf("discount melebihi subtotal %s", l.item.ProductName))
+		}
+		l.item.Subtotal -= l.item.Discount
+		total.Subtotal += l.item.Subtotal
+		if l.stock < qty && !warned[l.item.ProductID] {
+			warned[l.item.ProductID] = true
+			total.Warnings = append(total.Warnings,
+				fmt.Sprintf("stok tidak cukup untuk %s (stok=%d, qty=%d)", l.item.ProductName, l.stock, qty))
+		}
+		c.Items = append(c.Items, l.item)
+	}
+
+	if c.VoucherCode != "" {
+		discount, err := previewVoucher(ctx, q, c.VoucherCode, total.Subtotal)
+		if err != nil {
+			total.Warnings = append(total.Warnings, err.Error())
+		}
+		total.Discount = discount
+	}
+	total.Total = total.Subtotal - total.Discount
+	c.Preview = &total
+	return c, nil
+}
+
+// lockEditableCart mengunci cart dan memastikan statusnya masih open.
+func lockEditableCart(ctx context.Context, q querier, id int) error {
+	var status string
+	err := q.QueryRow(ctx, `SELECT status FROM carts WHERE id = $1 FOR UPDATE`, id).Scan(&status)
+	if err != nil {
+		return errors.New("cart belum ada")
+	}
+	switch status {
+	case models.CartOpen:
+		return nil
+	case models.CartParked:
+		return errors.New("cart sedang di-park, resume dulu")
+	default:
+		return fmt.Errorf("cart sudah %s", status)
+	}
+}
+
+// mutate menjalankan perubahan cart di dalam tx dengan cart ter-lock, lalu mengembalikan cart terbaru.
+func (r *CartRepository) mutate(id int, fn func(ctx context.Context, tx pgx.Tx) error) (*models.Cart, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockEditableCart(ctx, tx, id); err != nil {
+		return nil, err
+	}
+	if err := fn(ctx, tx); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE carts SET updated_at = now() WHERE id = $1`, id); err != nil {
+		return nil, err
+	}
+
+	c, err := getCart(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Update mengganti pelanggan / voucher / catatan. customer_id 0 = lepas pelanggan.
+func (r *CartRepository) Update(id int, req models.CartRequest) (*models.Cart, error) {
+	return r.mutate(id, func(ctx context.Context, tx pgx.Tx) error {
+		if req.CustomerID != nil && *req.CustomerID == 0 {
+			if _, err := tx.Exec(ctx, `UPDATE carts SET customer_id = NULL WHERE id = $1`, id); err != nil {
+				return err
+			}
+		} else if req.CustomerID != nil || req.CustomerPhone != "" {
+			customerID, _, _, err := resolveCustomer(ctx, tx, req.CustomerID, req.CustomerPhone)
+			if err != nil {
+				return err
+			}
+			if _, err := tx.Exec(ctx, `UPDATE carts SET customer_id = $1 WHERE id = $2`, customerID, id); err != nil {
+				return err
+			}
+		}
+		if req.VoucherCode != nil {
+			if _, err := tx.Exec(ctx, `UPDATE carts SET voucher_code = $1 WHERE id = $2`, *req.VoucherCode, id); err != nil {
+				return err
+			}
+		}
+		if req.Note != nil {
+			if _, err := tx.Exec(ctx, `UPDATE carts SET note = $1 WHERE id = $2`, *req.Note, id); err != nil {
+				return err
+			}
+		}
+		if req.Terminal != "" {
+			if _, err := tx.Exec(ctx, `UPDATE carts SET terminal = $1 WHERE id = $2`, req.Terminal, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// checkCartItem memastikan produk bisa dijual dan pilihan modifiernya valid, sama seperti di checkout.
+func checkCartItem(ctx context.Context, q querier, productID int, modifiers []int) error {
+	if err := checkSellable(ctx, q, productID); err != nil {
+		return err
+	}
+	var name string
+	if err := q.QueryRow(ctx, `SELECT name FROM products WHERE id = $1`, productID).Scan(&name); err != nil {
+		return fmt.Errorf("product id %d not found", productID)
+	}
+	_, _, err := resolveModifiers(ctx, q, productID, name, modifiers)
+	return err
+}
+
+// AddItem menambah qty ke baris produk dengan modifier yang sama (baris baru kalau belum ada);
+// serial yang di-scan ikut ditambahkan. Baris dengan override / diskon selalu jadi baris sendiri.
+func (r *CartRepository) AddItem(id int, item models.CheckoutItem) (*models.Cart, error) {
+	return r.mutate(id, func(ctx context.Context, tx pgx.Tx) error {
+		if err := checkCartItem(ctx, tx, item.ProductID, item.Modifiers); err != nil {
+			return err
+		}
+		if item.PriceOverride == nil && item.Discount == 0 {
+			ct, err := tx.Exec(ctx,
+				`UPDATE cart_items SET quantity = quantity + $1, serial_numbers = serial_numbers || $2
+				 WHERE id = (SELECT id FROM cart_items
+					WHERE cart_id = $3 AND product_id = $4 AND modifiers = $5 AND price_override IS NULL AND discount = 0
+					ORDER BY id LIMIT 1)`,
+				item.Quantity, serialNumbers(item.SerialNumbers), id, item.ProductID, modifierIDs(item.Modifiers),
+			)
+			if err != nil || ct.RowsAffected() > 0 {
+				return err
+			}
+		}
+		_, err := tx.Exec(ctx,
+			`INSERT INTO cart_items (cart_id, product_id, quantity, serial_numbers, modifiers, price_override, discount, reason)
+			 VALUES ($1,$2,$3,$4,$5,$6,$7,$8)`,
+			id, item.ProductID, item.Quantity, serialNumbers(item.SerialNumbers), modifierIDs(item.Modifiers),
+			item.PriceOverride, item.Discount, item.Reason,
+		)
+		if isForeignKeyViolation(err) {
+			return fmt.Errorf("product id %d not found", item.ProductID)
+		}
+		return err
+	})
+}
+
+// SetItem mengganti qty, serial, modifier dan override / diskon satu baris; qty 0 = hapus baris.
+func (r *CartRepository) SetItem(id, itemID int, item models.CheckoutItem) (*models.Cart, error) {
+	return r.mutate(id, func(ctx context.Context, tx pgx.Tx) error {
+		if item.Quantity == 0 {
+			return removeCartItem(ctx, tx, id, itemID)
+		}
+		var productID int
+		err := tx.QueryRow(ctx, `SELECT product_id FROM cart_items WHERE id = $1 AND cart_id = $2`, itemID, id).Scan(&productID)
+		if err != nil {
+			return fmt.Errorf("item id %d tidak ada di cart", itemID)
+		}
+		if err := checkCartItem(ctx, tx, productID, item.Modifiers); err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx,
+			`UPDATE cart_items SET quantity = $1, serial_numbers = $2, modifiers = $3, price_override = $4, discount = $5, reason = $6
+			 WHERE id = $7`,
+			item.Quantity, serialNumbers(item.SerialNumbers), modifierIDs(item.Modifiers),
+			item.PriceOverride, item.Discount, item.Reason, itemID,
+		)
+		return err
+	})
+}
+
+func (r *CartRepository) RemoveItem(id, itemID int) (*models.Cart, error) {
+	return r.mutate(id, func(ctx context.Context, tx pgx.Tx) error {
+		return removeCartItem(ctx, tx, id, itemID)
+	})
+}
+
+func removeCartItem(ctx context.Context, q querier, id, itemID int) error {
+	ct, err := q.Exec(ctx, `DELETE FROM cart_items WHERE cart_id = $1 AND id = $2`, id, itemID)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return fmt.Errorf("item id %d tidak ada di cart", itemID)
+	}
+	return nil
+}
+
+func modifierIDs(ids []int) []int {
+	if ids == nil {
+		return []int{}
+	}
+	return ids
+}
+
+// setStatus memindahkan status cart kalau status sekarang ada di from.
+func (r *CartRepository) setStatus(id int, from []string, to, terminal string) (*models.Cart, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var status string
+	err = tx.QueryRow(ctx, `SELECT status FROM carts WHERE id = $1 FOR UPDATE`, id).Scan(&status)
+	if err != nil {
+		return nil, errors.New("cart belum ada")
+	}
+	allowed := false
+	for _, s := range from {
+		if status == s {
+			allowed = true
+		}
+	}
+	if !allowed {
+		return nil, fmt.Errorf("cart berstatus %s tidak bisa diubah ke %s", status, to)
+	}
+
+	_, err = tx.Exec(ctx,
+		`UPDATE carts SET status = $1, terminal = COALESCE(NULLIF($2, ''), terminal), updated_at = now() WHERE id = $3`,
+		to, terminal, id,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := getCart(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (r *CartRepository) Park(id int) (*models.Cart, error) {
+	return r.setStatus(id, []string{models.CartOpen}, models.CartParked, "")
+}
+
+// Resume membuka lagi cart yang di-park, boleh dari terminal lain.
+func (r *CartRepository) Resume(id int, terminal string) (*models.Cart, error) {
+	return r.setStatus(id, []string{models.CartParked}, models.CartOpen, terminal)
+}
+
+func (r *CartRepository) Cancel(id int) (*models.Cart, error) {
+	return r.setStatus(id, []string{models.CartOpen, models.CartParked}, models.CartCancelled, "")
+}
+
+// Checkout mengubah cart jadi transaksi. Validasi stok, harga, voucher dan tender
+// sama persis dengan checkout langsung karena memakai createTransaction.
+func (r *CartRepository) Checkout(id int, req models.CartCheckoutRequest) (*models.Transaction, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockEditableCart(ctx, tx, id); err != nil {
+		return nil, err
+	}
+
+	checkout := models.CheckoutRequest{ShiftID: req.ShiftID, Approval: req.Approval, ApprovedBy: req.ApprovedBy, Payments: req.Payments}
+	var cartOutlet int
+	err = tx.QueryRow(ctx,
+		`SELECT customer_id, voucher_code, outlet_id FROM carts WHERE id = $1`, id,
+	).Scan(&checkout.CustomerID, &checkout.VoucherCode, &cartOutlet)
+	if err != nil {
+		return nil, err
+	}
+
+	// Harga & stok cart dihitung di outletnya, jadi harus dibayar di shift outlet yang sama
+	var shiftOutlet int
+	if err := tx.QueryRow(ctx, `SELECT outlet_id FROM shifts WHERE id = $1`, req.ShiftID).Scan(&shiftOutlet); err != nil {
+		return nil, fmt.Errorf("shift id %d not found", req.ShiftID)
+	}
+	if shiftOutlet != cartOutlet {
+		return nil, errors.New("cart milik outlet lain, checkout di shift outlet tersebut")
+	}
+
+	rows, err := tx.Query(ctx,
+		`SELECT product_id, quantity, serial_numbers, modifiers, price_override, discount, reason
+		 FROM cart_items WHERE cart_id = $1 ORDER BY added_at, id`, id)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var it models.CheckoutItem
+		err := rows.Scan(&it.ProductID, &it.Quantity, &it.SerialNumbers, &it.Modifiers, &it.PriceOverride, &it.Discount, &it.Reason)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		checkout.Items = append(checkout.Items, it)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(checkout.Items) == 0 {
+		return nil, errors.New("cart masih kosong")
+	}
+
+	t, err := r.txRepo.createTransaction(ctx, tx, checkout)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(ctx,
+		`UPDATE carts SET status = 'checked_out', transaction_id = $1, updated_at = now() WHERE id = $2`,
+		t.ID, id,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
@@ -0,0 +1,106 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"kasir-api/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type CashierRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewCashierRepository(db *pgxpool.Pool) *CashierRepository {
+	return &CashierRepository{db: db}
+}
+
+func (r *CashierRepository) GetAll() ([]models.Cashier, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := r.db.Query(ctx, `SELECT id, name, role, active, outlet_id, pin_hash <> '' FROM cashiers ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]models.Cashier, 0)
+	for rows.Next() {
+		var c models.Cashier
+		if err := rows.Scan(&c.ID, &c.Name, &c.Role, &c.Active, &c.OutletID, &c.HasPIN); err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+func (r *CashierRepository) Create(c *models.Cashier) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := r.db.QueryRow(ctx,
+		`INSERT INTO cashiers (name, role, active, outlet_id, pin_hash) VALUES ($1,$2,$3,$4,$5) RETURNING id, pin_hash <> ''`,
+		c.Name, c.Role, c.Active, c.OutletID, c.PINHash,
+	).Scan(&c.ID, &c.HasPIN)
+	if isForeignKeyViolation(err) {
+		return errors.New("outlet belum ada")
+	}
+	return err
+}
+
+func (r *CashierRepository) GetByID(id int) (*models.Cashier, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var c models.Cashier
+	err := r.db.QueryRow(ctx,
+		`SELECT id, name, role, active, outlet_id, pin_hash <> '' FROM cashiers WHERE id=$1`,
+		id,
+	).Scan(&c.ID, &c.Name, &c.Role, &c.Active, &c.OutletID, &c.HasPIN)
+
+	if err != nil {
+		return nil, errors.New("kasir belum ada")
+	}
+	return &c, nil
+}
+
+func (r *CashierRepository) Update(c *models.Cashier) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// PIN lama tetap dipakai kalau tidak ada PIN baru; PIN baru membuka kunci PIN yang salah berulang
+	err := r.db.QueryRow(ctx,
+		`UPDATE cashiers SET name=$1, role=$2, active=$3, outlet_id=$4, pin_hash=COALESCE(NULLIF($5, ''), pin_hash),
+			pin_failed_attempts = CASE WHEN $5 = '' THEN pin_failed_attempts ELSE 0 END,
+			pin_locked_until = CASE WHEN $5 = '' THEN pin_locked_until END
+		 WHERE id=$6
+		 RETURNING pin_hash <> ''`,
+		c.Name, c.Role, c.Active, c.OutletID, c.PINHash, c.ID,
+	).Scan(&c.HasPIN)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return errors.New("kasir belum ada")
+	}
+	if isForeignKeyViolation(err) {
+		return errors.New("outlet belum ada")
+	}
+	return err
+}
+
+func (r *CashierRepository) Delete(id int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	ct, err := r.db.Exec(ctx, `DELETE FROM cashiers WHERE id=$1`, id)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return errors.New("kasir belum ada")
+	}
+	return nil
+}
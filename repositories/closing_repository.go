@@ -0,0 +1,283 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"kasir-api/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ClosingRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewClosingRepository(db *pgxpool.Pool) *ClosingRepository {
+	return &ClosingRepository{db: db}
+}
+
+// CloseDay membekukan rekap satu hari bisnis jadi Z-report dengan nomor urut.
+// Hari bisnis mengikuti zona waktu & jam tutup buku masing-masing outlet;
+// businessDate kosong = hari bisnis berjalan di outlet default.
+func (r *ClosingRepository) CloseDay(businessDate string, closedBy *int, taxRate float64) (*models.ZReport, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if businessDate == "" {
+		today, err := currentBusinessDate(ctx, tx, nil)
+		if err != nil {
+			return nil, err
+		}
+		businessDate = today.Format("2006-01-02")
+	}
+
+	// Z berlaku untuk semua outlet (closedZNumber tidak per outlet), jadi hari itu
+	// harus sudah dimulai di setiap outlet aktif; outlet yang zona waktunya masih
+	// di hari sebelumnya akan terkunci kalau Z dibuat lebih dulu.
+	var started bool
+	err = tx.QueryRow(ctx,
+		`SELECT COALESCE(bool_and(outlet_business_date(now(), id) >= $1::date), FALSE) FROM outlets WHERE active`,
+		businessDate,
+	).Scan(&started)
+	if err != nil {
+		return nil, err
+	}
+	if !started {
+		return nil, errors.New("tidak bisa menutup hari yang belum dimulai di semua outlet aktif")
+	}
+
+	// ✅ Serialisasi penomoran Z, lalu tahan insert transaksi baru
+	// sampai snapshot selesai (checkout yang sedang jalan ditunggu dulu).
+	if _, err := tx.Exec(ctx, `LOCK TABLE z_reports IN EXCLUSIVE MODE`); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(ctx, `LOCK TABLE transactions IN SHARE MODE`); err != nil {
+		return nil, err
+	}
+
+	var existing int
+	err = tx.QueryRow(ctx,
+		`SELECT z_number FROM z_reports WHERE business_date = $1::date`,
+		businessDate,
+	).Scan(&existing)
+	if err == nil {
+		return nil, fmt.Errorf("hari %s sudah ditutup (Z #%d)", businessDate, existing)
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+
+	var openShifts int
+	err = tx.QueryRow(ctx,
+		`SELECT COUNT(*) FROM shifts WHERE status = 'open' AND outlet_business_date(opened_at, outlet_id) <= $1::date`,
+		businessDate,
+	).Scan(&openShifts)
+	if err != nil {
+		return nil, err
+	}
+	if openShifts > 0 {
+		return nil, fmt.Errorf("masih ada %d shift terbuka, tutup dulu sebelum Z-report", openShifts)
+	}
+
+	rep, err := dayReport(ctx, tx, businessDate, nil, taxRate)
+	if err != nil {
+		return nil, err
+	}
+	snapshot, err := json.Marshal(rep)
+	if err != nil {
+		return nil, err
+	}
+
+	z := models.ZReport{
+		BusinessDate: businessDate,
+		ClosedBy:     closedBy,
+		Report:       *rep,
+	}
+	err = tx.QueryRow(ctx, `
+		INSERT INTO z_reports (z_number, business_date, period_start, period_end,
+			total_sales, total_transaksi, snapshot, closed_by)
+		SELECT COALESCE(MAX(z_number), 0) + 1, $1::date, $2, $3, $4, $5, $6, $7
+		FROM z_reports
+		RETURNING z_number, closed_at
+	`, businessDate, rep.PeriodStart, rep.PeriodEnd, rep.TotalSales, rep.TotalTransaksi, snapshot, closedBy,
+	).Scan(&z.ZNumber, &z.ClosedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	// Snapshot per outlet ikut dibekukan, dibaca GetByNumber dengan outlet_id
+	for _, o := range rep.PerOutlet {
+		outletID := o.OutletID
+		orep, err := dayReport(ctx, tx, businessDate, &outletID, taxRate)
+		if err != nil {
+			return nil, err
+		}
+		orep.GeneratedAt = rep.GeneratedAt
+		osnap, err := json.Marshal(orep)
+		if err != nil {
+			return nil, err
+		}
+		_, err = tx.Exec(ctx,
+			`INSERT INTO z_report_outlets (z_number, outlet_id, snapshot) VALUES ($1, $2, $3)`,
+			z.ZNumber, outletID, osnap,
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return &z, nil
+}
+
+func scanZReport(row pgx.Row) (*models.ZReport, error) {
+	var z models.ZReport
+	var date time.Time
+	var snapshot []byte
+	if err := row.Scan(&z.ZNumber, &date, &z.ClosedAt, &z.ClosedBy, &snapshot); err != nil {
+		return nil, err
+	}
+	z.BusinessDate = date.Format("2006-01-02")
+	if err := json.Unmarshal(snapshot, &z.Report); err != nil {
+		return nil, err
+	}
+	return &z, nil
+}
+
+func (r *ClosingRepository) GetAll() ([]models.ZReport, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := r.db.Query(ctx,
+		`SELECT z_number, business_date, closed_at, closed_by, snapshot FROM z_reports ORDER BY z_number DESC`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]models.ZReport, 0)
+	for rows.Next() {
+		z, err := scanZReport(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *z)
+	}
+	return out, nil
+}
+
+// GetByNumber mengembalikan snapshot Z. Dengan outletID, yang dikembalikan snapshot outlet itu
+// yang dibekukan saat penutupan; Z lama (sebelum ada snapshot per outlet) hanya punya rekap
+// per_outlet, jadi rinciannya kosong. Outlet tanpa aktivitas di hari itu = rekap nol.
+func (r *ClosingRepository) GetByNumber(number int, outletID *int) (*models.ZReport, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	z, err := scanZReport(r.db.QueryRow(ctx,
+		`SELECT z_number, business_date, closed_at, closed_by, snapshot FROM z_reports WHERE z_number=$1`,
+		number,
+	))
+	if err != nil {
+		return nil, errors.New("z-report belum ada")
+	}
+	if outletID == nil {
+		return z, nil
+	}
+
+	var snapshot []byte
+	err = r.db.QueryRow(ctx,
+		`SELECT snapshot FROM z_report_outlets WHERE z_number = $1 AND outlet_id = $2`,
+		number, *outletID,
+	).Scan(&snapshot)
+	if err == nil {
+		var rep models.DayReport
+		if err := json.Unmarshal(snapshot, &rep); err != nil {
+			return nil, err
+		}
+		z.Report = rep
+		return z, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+
+	z.Report = outletFromSnapshot(z.Report, *outletID)
+	return z, nil
+}
+
+// outletFromSnapshot = rekap satu outlet dari per_outlet snapshot Z keseluruhan.
+func outletFromSnapshot(all models.DayReport, outletID int) models.DayReport {
+	rep := models.DayReport{
+		BusinessDate: all.BusinessDate,
+		OutletID:     &outletID,
+		PeriodStart:  all.PeriodStart,
+		PeriodEnd:    all.PeriodEnd,
+		GeneratedAt:  all.GeneratedAt,
+		TaxRate:      all.TaxRate,
+		Payments:     make([]models.PaymentTotal, 0),
+		Refunds:      make([]models.PaymentTotal, 0),
+		PerKasir:     make([]models.CashierTotal, 0),
+		PerOutlet:    make([]models.OutletTotal, 0),
+		PerProduk:    make([]models.ProductTotal, 0),
+		PerModifier:  make([]models.ModifierTotal, 0),
+	}
+	for _, o := range all.PerOutlet {
+		if o.OutletID != outletID {
+			continue
+		}
+		rep.TotalSales = o.TotalSales
+		rep.TotalTransaksi = o.TotalTransaksi
+		rep.TotalRefund = o.TotalRefund
+		rep.NetSales = o.NetSales
+		rep.PerOutlet = append(rep.PerOutlet, o)
+	}
+	rep.PPN = includedTax(rep.NetSales, rep.TaxRate)
+	return rep
+}
+
+// closedZNumber mengembalikan nomor Z kalau hari bisnis businessDate sudah ditutup.
+// Z mencakup semua outlet, jadi tidak ada filter outlet di sini.
+func closedZNumber(ctx context.Context, q querier, businessDate time.Time) (int, bool, error) {
+	var z int
+	err := q.QueryRow(ctx,
+		`SELECT z_number FROM z_reports WHERE business_date = $1::date`,
+		businessDate.Format("2006-01-02"),
+	).Scan(&z)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return z, true, nil
+}
+
+// currentBusinessDate = hari bisnis berjalan di outlet (zona waktu & jam tutup buku outlet);
+// outletID nil = outlet default. now() = awal tx, sama dengan created_at baris yang ditulis tx itu.
+func currentBusinessDate(ctx context.Context, q querier, outletID *int) (time.Time, error) {
+	var d *time.Time
+	err := q.QueryRow(ctx,
+		`SELECT outlet_business_date(now(), COALESCE($1::int, (SELECT id FROM outlets WHERE is_default)))`,
+		outletID,
+	).Scan(&d)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if d == nil {
+		return time.Time{}, errors.New("outlet belum ada")
+	}
+	return *d, nil
+}
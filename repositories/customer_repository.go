@@ -0,0 +1,209 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"kasir-api/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type CustomerRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewCustomerRepository(db *pgxpool.Pool) *CustomerRepository {
+	return &CustomerRepository{db: db}
+}
+
+const customerColumns = `id, name, phone, email, birthday, notes, price_list_id, credit_limit, created_at`
+
+func scanCustomer(row pgx.Row) (*models.Customer, error) {
+	var c models.Customer
+	var bday pgtype.Date
+	if err := row.Scan(&c.ID, &c.Name, &c.Phone, &c.Email, &bday, &c.Notes, &c.PriceListID, &c.CreditLimit, &c.CreatedAt); err != nil {
+		return nil, err
+	}
+	if bday.Valid {
+		v := bday.Time.Format("2006-01-02")
+		c.Birthday = &v
+	}
+	return &c, nil
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+func isForeignKeyViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23503"
+}
+
+func (r *CustomerRepository) GetAll(search string) ([]models.Customer, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	query := `SELECT ` + customerColumns + ` FROM customers`
+	args := []any{}
+
+	if search != "" {
+		query += ` WHERE name ILIKE $1 OR phone LIKE $1`
+		args = append(args, "%"+search+"%")
+	}
+
+	query += ` ORDER BY name, id`
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]models.Customer, 0)
+	for rows.Next() {
+		c, err := scanCustomer(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *c)
+	}
+	return out, nil
+}
+
+func (r *CustomerRepository) Create(c *models.Customer) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := r.db.QueryRow(ctx,
+		`INSERT INTO customers (name, phone, email, birthday, notes, price_list_id, credit_limit)
+		 VALUES ($1,$2,$3,$4::date,$5,$6,$7)
+		 RETURNING id, created_at`,
+		c.Name, c.Phone, c.Email, c.Birthday, c.Notes, c.PriceListID, c.CreditLimit,
+	).Scan(&c.ID, &c.CreatedAt)
+	if isUniqueViolation(err) {
+		return errors.New("no HP sudah terdaftar")
+	}
+	if isForeignKeyViolation(err) {
+		return errors.New("price list belum ada")
+	}
+	return err
+}
+
+func (r *CustomerRepository) GetByID(id int) (*models.Customer, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	c, err := scanCustomer(r.db.QueryRow(ctx,
+		`SELECT `+customerColumns+` FROM customers WHERE id=$1`, id))
+	if err != nil {
+		return nil, errors.New("pelanggan belum ada")
+	}
+	return c, nil
+}
+
+func (r *CustomerRepository) GetByPhone(phone string) (*models.Customer, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	c, err := scanCustomer(r.db.QueryRow(ctx,
+		`SELECT `+customerColumns+` FROM customers WHERE phone=$1`, phone))
+	if err != nil {
+		return nil, errors.New("pelanggan belum ada")
+	}
+	return c, nil
+}
+
+func (r *CustomerRepository) Update(c *models.Customer) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := r.db.QueryRow(ctx,
+		`UPDATE customers
+		 SET name=$1, phone=$2, email=$3, birthday=$4::date, notes=$5, price_list_id=$6, credit_limit=$7
+		 WHERE id=$8
+		 RETURNING created_at`,
+		c.Name, c.Phone, c.Email, c.Birthday, c.Notes, c.PriceListID, c.CreditLimit, c.ID,
+	).Scan(&c.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return errors.New("pelanggan belum ada")
+	}
+	if isUniqueViolation(err) {
+		return errors.New("no HP sudah terdaftar")
+	}
+	if isForeignKeyViolation(err) {
+		return errors.New("price list belum ada")
+	}
+	return err
+}
+
+func (r *CustomerRepository) Delete(id int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	ct, err := r.db.Exec(ctx, `DELETE FROM customers WHERE id=$1`, id)
+	if isForeignKeyViolation(err) {
+		return errors.New("pelanggan sudah punya transaksi, tidak bisa dihapus")
+	}
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return errors.New("pelanggan belum ada")
+	}
+	return nil
+}
+
+// Stats = statistik seumur hidup pelanggan dari transactions + transaction_details.
+// Refund selalu membatalkan seluruh transaksi, jadi transaksi yang sudah di-refund tidak dihitung.
+func (r *CustomerRepository) Stats(id int, topN int) (*models.CustomerStats, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	st := models.CustomerStats{
+		CustomerID:    id,
+		FavoriteItems: make([]models.FavoriteProduct, 0),
+	}
+	err := r.db.QueryRow(ctx, `
+		SELECT COUNT(*), COALESCE(SUM(t.total_amount), 0), MIN(t.created_at), MAX(t.created_at)
+		FROM transactions t
+		WHERE t.customer_id = $1
+		  AND NOT EXISTS (SELECT 1 FROM refunds r WHERE r.transaction_id = t.id)
+	`, id).Scan(&st.Visits, &st.TotalSpend, &st.FirstVisit, &st.LastVisit)
+	if err != nil {
+		return nil, err
+	}
+	if st.Visits > 0 {
+		st.AvgBasket = st.TotalSpend / st.Visits
+	}
+
+	rows, err := r.db.Query(ctx, `
+		SELECT td.product_id, COALESCE(p.name, ''), SUM(td.quantity) AS qty, SUM(td.subtotal)
+		FROM transaction_details td
+		JOIN transactions t ON t.id = td.transaction_id
+		LEFT JOIN products p ON p.id = td.product_id
+		WHERE t.customer_id = $1
+		  AND NOT EXISTS (SELECT 1 FROM refunds r WHERE r.transaction_id = t.id)
+		GROUP BY td.product_id, p.name
+		ORDER BY qty DESC, td.product_id
+		LIMIT $2
+	`, id, topN)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var f models.FavoriteProduct
+		if err := rows.Scan(&f.ProductID, &f.Nama, &f.QtyTerjual, &f.TotalSpend); err != nil {
+			return nil, err
+		}
+		st.FavoriteItems = append(st.FavoriteItems, f)
+	}
+	return &st, rows.Err()
+}
@@ -0,0 +1,275 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"kasir-api/models"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type GiftCardRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewGiftCardRepository(db *pgxpool.Pool) *GiftCardRepository {
+	return &GiftCardRepository{db: db}
+}
+
+const giftCardColumns = `id, code, balance, customer_id, active, expires_at, created_at`
+
+func scanGiftCard(row pgx.Row) (*models.GiftCard, error) {
+	var g models.GiftCard
+	if err := row.Scan(&g.ID, &g.Code, &g.Balance, &g.CustomerID, &g.Active, &g.ExpiresAt, &g.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+// MaskGiftCardCode dipakai di struk / transaksi supaya kode lengkap tidak tersebar.
+func MaskGiftCardCode(code string) string {
+	if len(code) <= 4 {
+		return code
+	}
+	return "****" + code[len(code)-4:]
+}
+
+// lockOpenShift dipakai operasi yang menerima uang di laci (issue / top-up).
+func lockOpenShift(ctx context.Context, q querier, shiftID int) error {
+	var status string
+	err := q.QueryRow(ctx, `SELECT status FROM shifts WHERE id = $1 FOR SHARE`, shiftID).Scan(&status)
+	if err != nil {
+		return fmt.Errorf("shift id %d not found", shiftID)
+	}
+	if status != models.ShiftOpen {
+		return errors.New("shift sudah ditutup, buka shift baru dulu")
+	}
+	return nil
+}
+
+func (r *GiftCardRepository) Issue(req models.IssueGiftCardRequest) (*models.GiftCard, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockOpenShift(ctx, tx, req.ShiftID); err != nil {
+		return nil, err
+	}
+
+	g, err := scanGiftCard(tx.QueryRow(ctx,
+		`INSERT INTO gift_cards (code, balance, customer_id, expires_at) VALUES ($1,$2,$3,$4)
+		 RETURNING `+giftCardColumns,
+		req.Code, req.Amount, req.CustomerID, req.ExpiresAt,
+	))
+	if isUniqueViolation(err) {
+		return nil, errors.New("kode gift card sudah dipakai")
+	}
+	if isForeignKeyViolation(err) {
+		return nil, errors.New("pelanggan belum ada")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(ctx,
+		`INSERT INTO gift_card_entries (gift_card_id, shift_id, type, method, amount) VALUES ($1,$2,'issue',$3,$4)`,
+		g.ID, req.ShiftID, req.Method, req.Amount,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+func (r *GiftCardRepository) TopUp(code string, req models.TopUpGiftCardRequest) (*models.GiftCard, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockOpenShift(ctx, tx, req.ShiftID); err != nil {
+		return nil, err
+	}
+
+	g, err := scanGiftCard(tx.QueryRow(ctx,
+		`UPDATE gift_cards SET balance = balance + $1 WHERE code = $2 RETURNING `+giftCardColumns,
+		req.Amount, code,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, errors.New("gift card belum ada")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !g.Active {
+		return nil, errors.New("gift card tidak aktif")
+	}
+
+	_, err = tx.Exec(ctx,
+		`INSERT INTO gift_card_entries (gift_card_id, shift_id, type, method, amount) VALUES ($1,$2,'topup',$3,$4)`,
+		g.ID, req.ShiftID, req.Method, req.Amount,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// GetByCode = cek saldo + riwayat mutasi.
+func (r *GiftCardRepository) GetByCode(code string) (*models.GiftCard, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	g, err := scanGiftCard(r.db.QueryRow(ctx, `SELECT `+giftCardColumns+` FROM gift_cards WHERE code=$1`, code))
+	if err != nil {
+		return nil, errors.New("gift card belum ada")
+	}
+
+	rows, err := r.db.Query(ctx, `
+		SELECT id, gift_card_id, transaction_id, shift_id, type, method, amount, created_at
+		FROM gift_card_entries
+		WHERE gift_card_id = $1
+		ORDER BY id DESC
+		LIMIT 100
+	`, g.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	g.Entries = make([]models.GiftCardEntry, 0)
+	for rows.Next() {
+		var e models.GiftCardEntry
+		if err := rows.Scan(&e.ID, &e.GiftCardID, &e.TransactionID, &e.ShiftID, &e.Type, &e.Method, &e.Amount, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		g.Entries = append(g.Entries, e)
+	}
+	return g, rows.Err()
+}
+
+// redeemGiftCards memotong saldo gift card di dalam transaksi checkout.
+// Kartu di-lock FOR UPDATE urut kode supaya dua checkout tidak saling deadlock,
+// lalu reference tender diganti kode yang disamarkan sebelum disimpan.
+func redeemGiftCards(ctx context.Context, q querier, transactionID, shiftID int, payments []models.TransactionPayment) error {
+	amounts := make(map[string]int)
+	for _, p := range payments {
+		if p.Method == models.PaymentGiftCard {
+			amounts[p.Reference] += p.Amount
+		}
+	}
+	if len(amounts) == 0 {
+		return nil
+	}
+
+	codes := make([]string, 0, len(amounts))
+	for code := range amounts {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	for _, code := range codes {
+		var id, balance int
+		var active bool
+		var expiresAt *time.Time
+		err := q.QueryRow(ctx,
+			`SELECT id, balance, active, expires_at FROM gift_cards WHERE code = $1 FOR UPDATE`,
+			code,
+		).Scan(&id, &balance, &active, &expiresAt)
+		if err != nil {
+			return fmt.Errorf("gift card %s belum ada", MaskGiftCardCode(code))
+		}
+		if !active {
+			return fmt.Errorf("gift card %s tidak aktif", MaskGiftCardCode(code))
+		}
+		if expiresAt != nil && expiresAt.Before(time.Now()) {
+			return fmt.Errorf("gift card %s sudah kedaluwarsa", MaskGiftCardCode(code))
+		}
+		if balance < amounts[code] {
+			return fmt.Errorf("saldo gift card %s tidak cukup (saldo=%d, bayar=%d)", MaskGiftCardCode(code), balance, amounts[code])
+		}
+
+		if _, err := q.Exec(ctx, `UPDATE gift_cards SET balance = balance - $1 WHERE id = $2`, amounts[code], id); err != nil {
+			return err
+		}
+		_, err = q.Exec(ctx,
+			`INSERT INTO gift_card_entries (gift_card_id, transaction_id, shift_id, type, amount) VALUES ($1,$2,$3,'redeem',$4)`,
+			id, transactionID, shiftID, -amounts[code],
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	for i := range payments {
+		if payments[i].Method == models.PaymentGiftCard {
+			payments[i].Reference = MaskGiftCardCode(payments[i].Reference)
+		}
+	}
+	return nil
+}
+
+// restoreGiftCards mengembalikan saldo gift card yang dipakai di transaksi yang di-refund.
+// Urutan lock sama dengan redeemGiftCards (urut kode).
+func restoreGiftCards(ctx context.Context, q querier, transactionID, shiftID int) error {
+	rows, err := q.Query(ctx, `
+		SELECT e.gift_card_id, -SUM(e.amount)
+		FROM gift_card_entries e
+		JOIN gift_cards g ON g.id = e.gift_card_id
+		WHERE e.transaction_id = $1 AND e.type = 'redeem'
+		GROUP BY e.gift_card_id, g.code
+		ORDER BY g.code
+	`, transactionID)
+	if err != nil {
+		return err
+	}
+	type used struct{ id, amount int }
+	var cards []used
+	for rows.Next() {
+		var u used
+		if err := rows.Scan(&u.id, &u.amount); err != nil {
+			rows.Close()
+			return err
+		}
+		cards = append(cards, u)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, c := range cards {
+		if _, err := q.Exec(ctx, `UPDATE gift_cards SET balance = balance + $1 WHERE id = $2`, c.amount, c.id); err != nil {
+			return err
+		}
+		_, err := q.Exec(ctx,
+			`INSERT INTO gift_card_entries (gift_card_id, transaction_id, shift_id, type, amount) VALUES ($1,$2,$3,'refund',$4)`,
+			c.id, transactionID, shiftID, c.amount,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
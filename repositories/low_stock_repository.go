@@ -0,0 +1,130 @@
+package repositories
+
+import (
+	"context"
+	"kasir-api/models"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type LowStockRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewLowStockRepository(db *pgxpool.Pool) *LowStockRepository {
+	return &LowStockRepository{db: db}
+}
+
+// stockSalesSource = stok yang dipotong penjualan sejak $1 (outlet_id, product_id, qty),
+// sama seperti refundStockSource tapi untuk semua transaksi yang tidak di-refund.
+const stockSalesSource = `(
+		SELECT t.outlet_id, td.product_id, td.quantity AS qty
+		FROM transaction_details td
+		JOIN transactions t ON t.id = td.transaction_id
+		WHERE t.created_at >= $1
+		  AND NOT EXISTS (SELECT 1 FROM refunds r WHERE r.transaction_id = t.id)
+		  AND NOT EXISTS (SELECT 1 FROM transaction_ingredient_usage u WHERE u.transaction_detail_id = td.id)
+		UNION ALL
+		SELECT t.outlet_id, u.ingredient_id, u.quantity
+		FROM transaction_ingredient_usage u
+		JOIN transactions t ON t.id = u.transaction_id
+		WHERE t.created_at >= $1
+		  AND NOT EXISTS (SELECT 1 FROM refunds r WHERE r.transaction_id = t.id)
+		UNION ALL
+		SELECT t.outlet_id, m.stock_product_id, m.stock_qty
+		FROM transaction_detail_modifiers m
+		JOIN transaction_details td ON td.id = m.transaction_detail_id
+		JOIN transactions t ON t.id = td.transaction_id
+		WHERE t.created_at >= $1 AND m.stock_product_id IS NOT NULL
+		  AND NOT EXISTS (SELECT 1 FROM refunds r WHERE r.transaction_id = t.id)
+	) s`
+
+// GetLow = produk ber-reorder point yang stoknya <= reorder point di outlet aktif
+// (outletID nil = semua outlet). Produk induk varian, komposit dan paket tidak punya stok sendiri.
+func (r *LowStockRepository) GetLow(outletID *int) ([]models.LowStockItem, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	rows, err := r.db.Query(ctx, `
+		SELECT o.id, o.code, p.id, p.name, p.unit, COALESCE(op.stock, 0), p.reorder_point, p.lead_time_days,
+			COALESCE(rs.avg_daily_sales, 0)::float8, GREATEST(COALESCE(rs.target_stock, 0) - COALESCE(op.stock, 0), 0),
+			rs.computed_at
+		FROM outlets o
+		CROSS JOIN products p
+		LEFT JOIN outlet_products op ON op.outlet_id = o.id AND op.product_id = p.id
+		LEFT JOIN stock_reorder_suggestions rs ON rs.outlet_id = o.id AND rs.product_id = p.id
+		WHERE o.active AND ($1::int IS NULL OR o.id = $1)
+		  AND p.reorder_point IS NOT NULL AND COALESCE(op.stock, 0) <= p.reorder_point
+		  AND NOT EXISTS (SELECT 1 FROM products v WHERE v.parent_id = p.id)
+		  AND NOT EXISTS (SELECT 1 FROM product_recipes pr WHERE pr.product_id = p.id)
+		  AND NOT EXISTS (SELECT 1 FROM bundle_items b WHERE b.bundle_id = p.id)
+		ORDER BY o.id, COALESCE(op.stock, 0) - p.reorder_point, p.name
+	`, outletID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]models.LowStockItem, 0)
+	for rows.Next() {
+		var it models.LowStockItem
+		err := rows.Scan(&it.OutletID, &it.OutletCode, &it.ProductID, &it.ProductName, &it.Unit, &it.Stock,
+			&it.ReorderPoint, &it.LeadTimeDays, &it.AvgDailySales, &it.SuggestedQty, &it.ComputedAt)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, it)
+	}
+	return out, rows.Err()
+}
+
+// RefreshSuggestions menghitung ulang kecepatan jual per outlet & produk ber-reorder point
+// dari penjualan salesDays hari terakhir. Target stok = reorder point + kebutuhan selama
+// lead time + coverDays. Mengembalikan jumlah baris saran.
+func (r *LowStockRepository) RefreshSuggestions(salesDays, coverDays int) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		DELETE FROM stock_reorder_suggestions rs
+		USING products p, outlets o
+		WHERE p.id = rs.product_id AND o.id = rs.outlet_id AND (p.reorder_point IS NULL OR NOT o.active)
+	`)
+	if err != nil {
+		return 0, err
+	}
+
+	since := time.Now().AddDate(0, 0, -salesDays)
+	ct, err := tx.Exec(ctx, `
+		WITH sold AS (
+			SELECT s.outlet_id, s.product_id, SUM(s.qty)::numeric / $2 AS per_day
+			FROM `+stockSalesSource+`
+			GROUP BY 1, 2
+		)
+		INSERT INTO stock_reorder_suggestions (outlet_id, product_id, avg_daily_sales, target_stock, computed_at)
+		SELECT o.id, p.id, COALESCE(sold.per_day, 0),
+			p.reorder_point + CEIL(COALESCE(sold.per_day, 0) * (p.lead_time_days + $3))::int, now()
+		FROM outlets o
+		CROSS JOIN products p
+		LEFT JOIN sold ON sold.outlet_id = o.id AND sold.product_id = p.id
+		WHERE o.active AND p.reorder_point IS NOT NULL
+		ON CONFLICT (outlet_id, product_id) DO UPDATE
+		SET avg_daily_sales = EXCLUDED.avg_daily_sales, target_stock = EXCLUDED.target_stock,
+			computed_at = EXCLUDED.computed_at
+	`, since, salesDays, coverDays)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return int(ct.RowsAffected()), nil
+}
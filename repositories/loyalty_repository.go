@@ -0,0 +1,435 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"kasir-api/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type LoyaltyRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewLoyaltyRepository(db *pgxpool.Pool) *LoyaltyRepository {
+	return &LoyaltyRepository{db: db}
+}
+
+func loadLoyaltySettings(ctx context.Context, q querier) (models.LoyaltySettings, error) {
+	var s models.LoyaltySettings
+	err := q.QueryRow(ctx,
+		`SELECT enabled, amount_per_point, point_value, expiry_days FROM loyalty_settings WHERE id`,
+	).Scan(&s.Enabled, &s.AmountPerPoint, &s.PointValue, &s.ExpiryDays)
+	return s, err
+}
+
+func (r *LoyaltyRepository) GetSettings() (models.LoyaltySettings, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return loadLoyaltySettings(ctx, r.db)
+}
+
+func (r *LoyaltyRepository) UpdateSettings(s models.LoyaltySettings) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.db.Exec(ctx,
+		`INSERT INTO loyalty_settings (id, enabled, amount_per_point, point_value, expiry_days)
+		 VALUES (TRUE, $1, $2, $3, $4)
+		 ON CONFLICT (id) DO UPDATE
+		 SET enabled=$1, amount_per_point=$2, point_value=$3, expiry_days=$4`,
+		s.Enabled, s.AmountPerPoint, s.PointValue, s.ExpiryDays,
+	)
+	return err
+}
+
+const loyaltyRuleColumns = `id, name, category_id, multiplier::float8, starts_at, ends_at, active`
+
+func scanLoyaltyRule(row pgx.Row) (*models.LoyaltyRule, error) {
+	var rule models.LoyaltyRule
+	var cat pgtype.Int8
+	if err := row.Scan(&rule.ID, &rule.Name, &cat, &rule.Multiplier, &rule.StartsAt, &rule.EndsAt, &rule.Active); err != nil {
+		return nil, err
+	}
+	if cat.Valid {
+		v := int(cat.Int64)
+		rule.CategoryID = &v
+	}
+	return &rule, nil
+}
+
+func (r *LoyaltyRepository) GetRules() ([]models.LoyaltyRule, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := r.db.Query(ctx, `SELECT `+loyaltyRuleColumns+` FROM loyalty_rules ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]models.LoyaltyRule, 0)
+	for rows.Next() {
+		rule, err := scanLoyaltyRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *rule)
+	}
+	return out, nil
+}
+
+func (r *LoyaltyRepository) CreateRule(rule *models.LoyaltyRule) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return r.db.QueryRow(ctx,
+		`INSERT INTO loyalty_rules (name, category_id, multiplier, starts_at, ends_at, active)
+		 VALUES ($1,$2,$3,$4,$5,$6) RETURNING id`,
+		rule.Name, rule.CategoryID, rule.Multiplier, rule.StartsAt, rule.EndsAt, rule.Active,
+	).Scan(&rule.ID)
+}
+
+func (r *LoyaltyRepository) UpdateRule(rule *models.LoyaltyRule) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	ct, err := r.db.Exec(ctx,
+		`UPDATE loyalty_rules SET name=$1, category_id=$2, multiplier=$3, starts_at=$4, ends_at=$5, active=$6
+		 WHERE id=$7`,
+		rule.Name, rule.CategoryID, rule.Multiplier, rule.StartsAt, rule.EndsAt, rule.Active, rule.ID,
+	)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return errors.New("aturan poin belum ada")
+	}
+	return nil
+}
+
+func (r *LoyaltyRepository) DeleteRule(id int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	ct, err := r.db.Exec(ctx, `DELETE FROM loyalty_rules WHERE id=$1`, id)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return errors.New("aturan poin belum ada")
+	}
+	return nil
+}
+
+// Balance mengembalikan saldo + ledger terbaru. Poin yang lewat masa berlaku
+// dicatat kedaluwarsa dulu supaya saldo yang tampil akurat.
+func (r *LoyaltyRepository) Balance(customerID int, limit int) (*models.PointsBalance, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockCustomer(ctx, tx, customerID); err != nil {
+		return nil, err
+	}
+	if err := expirePoints(ctx, tx, customerID); err != nil {
+		return nil, err
+	}
+
+	settings, err := loadLoyaltySettings(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	b := models.PointsBalance{CustomerID: customerID, Ledger: make([]models.PointsEntry, 0)}
+	if b.Balance, err = pointsBalance(ctx, tx, customerID); err != nil {
+		return nil, err
+	}
+	b.Value = b.Balance * settings.PointValue
+
+	rows, err := tx.Query(ctx,
+		`SELECT id, customer_id, transaction_id, type, points, remaining, expires_at, created_at
+		 FROM loyalty_ledger WHERE customer_id=$1 ORDER BY id DESC LIMIT $2`,
+		customerID, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var e models.PointsEntry
+		if err := rows.Scan(&e.ID, &e.CustomerID, &e.TransactionID, &e.Type, &e.Points, &e.Remaining, &e.ExpiresAt, &e.CreatedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		b.Ledger = append(b.Ledger, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// lockCustomer men-serialisasi semua mutasi poin satu pelanggan.
+func lockCustomer(ctx context.Context, q querier, customerID int) error {
+	var id int
+	err := q.QueryRow(ctx, `SELECT id FROM customers WHERE id=$1 FOR UPDATE`, customerID).Scan(&id)
+	if err != nil {
+		return errors.New("pelanggan belum ada")
+	}
+	return nil
+}
+
+func pointsBalance(ctx context.Context, q querier, customerID int) (int, error) {
+	var balance int
+	err := q.QueryRow(ctx,
+		`SELECT COALESCE(SUM(points), 0) FROM loyalty_ledger WHERE customer_id=$1`,
+		customerID,
+	).Scan(&balance)
+	return balance, err
+}
+
+// expirePoints menghanguskan sisa poin yang sudah lewat expires_at.
+func expirePoints(ctx context.Context, q querier, customerID int) error {
+	var expired int
+	err := q.QueryRow(ctx,
+		`SELECT COALESCE(SUM(remaining), 0) FROM loyalty_ledger
+		 WHERE customer_id=$1 AND remaining > 0 AND expires_at <= now()`,
+		customerID,
+	).Scan(&expired)
+	if err != nil || expired == 0 {
+		return err
+	}
+
+	_, err = q.Exec(ctx,
+		`UPDATE loyalty_ledger SET remaining = 0
+		 WHERE customer_id=$1 AND remaining > 0 AND expires_at <= now()`,
+		customerID,
+	)
+	if err != nil {
+		return err
+	}
+	_, err = q.Exec(ctx,
+		`INSERT INTO loyalty_ledger (customer_id, type, points) VALUES ($1, 'expire', $2)`,
+		customerID, -expired,
+	)
+	return err
+}
+
+// consumePoints mengurangi sisa poin FIFO (yang paling cepat kedaluwarsa dulu).
+// Mengembalikan jumlah poin yang benar-benar terpakai (bisa < n kalau saldo kurang).
+func consumePoints(ctx context.Context, q querier, customerID, n int) (int, error) {
+	rows, err := q.Query(ctx,
+		`SELECT id, remaining FROM loyalty_ledger
+		 WHERE customer_id=$1 AND remaining > 0
+		 ORDER BY expires_at NULLS LAST, id`,
+		customerID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	type lot struct{ id, remaining int }
+	lots := make([]lot, 0)
+	for rows.Next() {
+		var l lot
+		if err := rows.Scan(&l.id, &l.remaining); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		lots = append(lots, l)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	used := 0
+	for _, l := range lots {
+		if used == n {
+			break
+		}
+		take := min(l.remaining, n-used)
+		if _, err := q.Exec(ctx, `UPDATE loyalty_ledger SET remaining = remaining - $1 WHERE id=$2`, take, l.id); err != nil {
+			return used, err
+		}
+		used += take
+	}
+	return used, nil
+}
+
+func pointsExpiry(settings models.LoyaltySettings) *time.Time {
+	if settings.ExpiryDays <= 0 {
+		return nil
+	}
+	t := time.Now().AddDate(0, 0, settings.ExpiryDays)
+	return &t
+}
+
+// redeemPoints memakai poin sebagai tender. Customer harus sudah di-lock.
+func redeemPoints(ctx context.Context, q querier, customerID, transactionID, points int) error {
+	if err := expirePoints(ctx, q, customerID); err != nil {
+		return err
+	}
+	balance, err := pointsBalance(ctx, q, customerID)
+	if err != nil {
+		return err
+	}
+	if balance < points {
+		return errors.New("saldo poin tidak cukup")
+	}
+	if _, err := consumePoints(ctx, q, customerID, points); err != nil {
+		return err
+	}
+	_, err = q.Exec(ctx,
+		`INSERT INTO loyalty_ledger (customer_id, transaction_id, type, points) VALUES ($1, $2, 'redeem', $3)`,
+		customerID, transactionID, -points,
+	)
+	return err
+}
+
+type earnLine struct {
+	categoryID *int
+	subtotal   int
+}
+
+// activeMultiplier = multiplier terbesar dari aturan yang berlaku untuk kategori itu
+// (aturan tidak ditumpuk). Tanpa aturan = 1x.
+func activeMultiplier(rules []models.LoyaltyRule, categoryID *int) float64 {
+	m := 1.0
+	for _, rule := range rules {
+		if rule.CategoryID != nil && (categoryID == nil || *rule.CategoryID != *categoryID) {
+			continue
+		}
+		m = max(m, rule.Multiplier)
+	}
+	return m
+}
+
+// earnPoints menghitung & mencatat poin transaksi. eligible = bagian belanja
+// yang tidak dibayar pakai poin. Customer harus sudah di-lock.
+func earnPoints(ctx context.Context, q querier, settings models.LoyaltySettings, customerID, transactionID int, lines []earnLine, total, eligible int) (int, error) {
+	if total <= 0 || eligible <= 0 {
+		return 0, nil
+	}
+
+	rows, err := q.Query(ctx,
+		`SELECT `+loyaltyRuleColumns+` FROM loyalty_rules
+		 WHERE active AND (starts_at IS NULL OR starts_at <= now()) AND (ends_at IS NULL OR ends_at > now())`,
+	)
+	if err != nil {
+		return 0, err
+	}
+	rules := make([]models.LoyaltyRule, 0)
+	for rows.Next() {
+		rule, err := scanLoyaltyRule(rows)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		rules = append(rules, *rule)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	weighted := 0.0
+	for _, l := range lines {
+		weighted += float64(l.subtotal) * activeMultiplier(rules, l.categoryID)
+	}
+	weighted = weighted * float64(eligible) / float64(total)
+
+	points := int(weighted) / settings.AmountPerPoint
+	if points <= 0 {
+		return 0, nil
+	}
+
+	_, err = q.Exec(ctx,
+		`INSERT INTO loyalty_ledger (customer_id, transaction_id, type, points, remaining, expires_at)
+		 VALUES ($1, $2, 'earn', $3, $3, $4)`,
+		customerID, transactionID, points, pointsExpiry(settings),
+	)
+	return points, err
+}
+
+// reverseLoyalty membatalkan efek poin transaksi yang di-refund:
+// poin yang didapat ditarik lagi (sebatas saldo yang ada), poin yang dipakai dikembalikan.
+func reverseLoyalty(ctx context.Context, q querier, customerID, transactionID int) error {
+	if err := lockCustomer(ctx, q, customerID); err != nil {
+		return err
+	}
+
+	var earned, earnedRemaining, redeemed int
+	err := q.QueryRow(ctx,
+		`SELECT
+			COALESCE(SUM(points) FILTER (WHERE type = 'earn'), 0),
+			COALESCE(SUM(remaining) FILTER (WHERE type = 'earn'), 0),
+			COALESCE(-SUM(points) FILTER (WHERE type = 'redeem'), 0)
+		 FROM loyalty_ledger WHERE transaction_id=$1 AND customer_id=$2`,
+		transactionID, customerID,
+	).Scan(&earned, &earnedRemaining, &redeemed)
+	if err != nil {
+		return err
+	}
+
+	if earned > 0 {
+		// ambil dari sisa poin transaksi ini dulu, kekurangannya dari saldo lain
+		_, err := q.Exec(ctx,
+			`UPDATE loyalty_ledger SET remaining = 0 WHERE transaction_id=$1 AND customer_id=$2 AND type='earn'`,
+			transactionID, customerID,
+		)
+		if err != nil {
+			return err
+		}
+		taken := earnedRemaining
+		if taken < earned {
+			if err := expirePoints(ctx, q, customerID); err != nil {
+				return err
+			}
+			more, err := consumePoints(ctx, q, customerID, earned-taken)
+			if err != nil {
+				return err
+			}
+			taken += more
+		}
+		if taken > 0 {
+			_, err = q.Exec(ctx,
+				`INSERT INTO loyalty_ledger (customer_id, transaction_id, type, points) VALUES ($1, $2, 'reverse_earn', $3)`,
+				customerID, transactionID, -taken,
+			)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if redeemed > 0 {
+		settings, err := loadLoyaltySettings(ctx, q)
+		if err != nil {
+			return err
+		}
+		_, err = q.Exec(ctx,
+			`INSERT INTO loyalty_ledger (customer_id, transaction_id, type, points, remaining, expires_at)
+			 VALUES ($1, $2, 'restore_redeem', $3, $3, $4)`,
+			customerID, transactionID, redeemed, pointsExpiry(settings),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,385 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"kasir-api/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ModifierRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewModifierRepository(db *pgxpool.Pool) *ModifierRepository {
+	return &ModifierRepository{db: db}
+}
+
+const modifierGroupColumns = `g.id, g.name, g.required, g.min_select, g.max_select, g.active`
+
+func scanModifierGroup(row pgx.Row) (*models.ModifierGroup, error) {
+	var g models.ModifierGroup
+	if err := row.Scan(&g.ID, &g.Name, &g.Required, &g.MinSelect, &g.MaxSelect, &g.Active); err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+// loadModifiers mengisi modifier untuk banyak group sekaligus.
+func loadModifiers(ctx context.Context, q querier, groups []*models.ModifierGroup, activeOnly bool) error {
+	if len(groups) == 0 {
+		return nil
+	}
+	ids := make([]int, 0, len(groups))
+	byID := make(map[int]*models.ModifierGroup, len(groups))
+	for _, g := range groups {
+		g.Modifiers = make([]models.Modifier, 0)
+		ids = append(ids, g.ID)
+		byID[g.ID] = g
+	}
+
+	rows, err := q.Query(ctx, `
+		SELECT id, group_id, name, price_delta, product_id, stock_qty, active
+		FROM modifiers
+		WHERE group_id = ANY($1) AND (active OR NOT $2)
+		ORDER BY group_id, position, id
+	`, ids, activeOnly)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var m models.Modifier
+		if err := rows.Scan(&m.ID, &m.GroupID, &m.Name, &m.PriceDelta, &m.ProductID, &m.StockQty, &m.Active); err != nil {
+			return err
+		}
+		byID[m.GroupID].Modifiers = append(byID[m.GroupID].Modifiers, m)
+	}
+	return rows.Err()
+}
+
+func (r *ModifierRepository) GetAll() ([]models.ModifierGroup, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := r.db.Query(ctx, `SELECT `+modifierGroupColumns+` FROM modifier_groups g ORDER BY g.id`)
+	if err != nil {
+		return nil, err
+	}
+	groups := make([]*models.ModifierGroup, 0)
+	for rows.Next() {
+		g, err := scanModifierGroup(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		groups = append(groups, g)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := loadModifiers(ctx, r.db, groups, false); err != nil {
+		return nil, err
+	}
+	out := make([]models.ModifierGroup, 0, len(groups))
+	for _, g := range groups {
+		out = append(out, *g)
+	}
+	return out, nil
+}
+
+func (r *ModifierRepository) Create(g *models.ModifierGroup) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return r.db.QueryRow(ctx,
+		`INSERT INTO modifier_groups (name, required, min_select, max_select, active) VALUES ($1,$2,$3,$4,$5)
+		 RETURNING id`,
+		g.Name, g.Required, g.MinSelect, g.MaxSelect, g.Active,
+	).Scan(&g.ID)
+}
+
+func (r *ModifierRepository) GetByID(id int) (*models.ModifierGroup, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	g, err := scanModifierGroup(r.db.QueryRow(ctx, `SELECT `+modifierGroupColumns+` FROM modifier_groups g WHERE g.id=$1`, id))
+	if err != nil {
+		return nil, errors.New("modifier group belum ada")
+	}
+	if err := loadModifiers(ctx, r.db, []*models.ModifierGroup{g}, false); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+func (r *ModifierRepository) Update(g *models.ModifierGroup) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	ct, err := r.db.Exec(ctx,
+		`UPDATE modifier_groups SET name=$1, required=$2, min_select=$3, max_select=$4, active=$5 WHERE id=$6`,
+		g.Name, g.Required, g.MinSelect, g.MaxSelect, g.Active, g.ID,
+	)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return errors.New("modifier group belum ada")
+	}
+	return nil
+}
+
+func (r *ModifierRepository) Delete(id int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	ct, err := r.db.Exec(ctx, `DELETE FROM modifier_groups WHERE id=$1`, id)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return errors.New("modifier group belum ada")
+	}
+	return nil
+}
+
+// SetModifiers menyimpan daftar modifier group: id terisi = update, kosong = baru,
+// yang tidak ada di daftar dinonaktifkan (bukan dihapus, karena dipakai histori).
+func (r *ModifierRepository) SetModifiers(groupID int, mods []models.Modifier) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var exists bool
+	err = tx.QueryRow(ctx, `SELECT TRUE FROM modifier_groups WHERE id=$1 FOR UPDATE`, groupID).Scan(&exists)
+	if err != nil {
+		return errors.New("modifier group belum ada")
+	}
+
+	keep := make([]int, 0, len(mods))
+	for i, m := range mods {
+		if m.ID > 0 {
+			ct, err := tx.Exec(ctx,
+				`UPDATE modifiers SET name=$1, price_delta=$2, product_id=$3, stock_qty=$4, position=$5, active=$6
+				 WHERE id=$7 AND group_id=$8`,
+				m.Name, m.PriceDelta, m.ProductID, m.StockQty, i, m.Active, m.ID, groupID,
+			)
+			if isForeignKeyViolation(err) {
+				return errors.New("product belum ada")
+			}
+			if err != nil {
+				return err
+			}
+			if ct.RowsAffected() == 0 {
+				return fmt.Errorf("modifier id %d bukan milik group ini", m.ID)
+			}
+			keep = append(keep, m.ID)
+			continue
+		}
+
+		var id int
+		err := tx.QueryRow(ctx,
+			`INSERT INTO modifiers (group_id, name, price_delta, product_id, stock_qty, position, active)
+			 VALUES ($1,$2,$3,$4,$5,$6,$7) RETURNING id`,
+			groupID, m.Name, m.PriceDelta, m.ProductID, m.StockQty, i, m.Active,
+		).Scan(&id)
+		if isForeignKeyViolation(err) {
+			return errors.New("product belum ada")
+		}
+		if err != nil {
+			return err
+		}
+		keep = append(keep, id)
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE modifiers SET active = FALSE WHERE group_id = $1 AND NOT (id = ANY($2))`, groupID, keep)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// SetProducts mengganti daftar produk yang memakai modifier group ini.
+func (r *ModifierRepository) SetProducts(groupID int, productIDs []int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var exists bool
+	err = tx.QueryRow(ctx, `SELECT TRUE FROM modifier_groups WHERE id=$1 FOR UPDATE`, groupID).Scan(&exists)
+	if err != nil {
+		return errors.New("modifier group belum ada")
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM product_modifier_groups WHERE group_id=$1`, groupID); err != nil {
+		return err
+	}
+	for _, pid := range productIDs {
+		_, err := tx.Exec(ctx,
+			`INSERT INTO product_modifier_groups (product_id, group_id) VALUES ($1,$2) ON CONFLICT DO NOTHING`,
+			pid, groupID,
+		)
+		if isForeignKeyViolation(err) {
+			return fmt.Errorf("product id %d belum ada", pid)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// productModifierGroups = group aktif (beserta modifier aktif) yang dipasang di produk.
+func productModifierGroups(ctx context.Context, q querier, productID int) ([]*models.ModifierGroup, error) {
+	rows, err := q.Query(ctx, `
+		SELECT `+modifierGroupColumns+`
+		FROM modifier_groups g
+		JOIN product_modifier_groups pg ON pg.group_id = g.id
+		WHERE pg.product_id = $1 AND g.active
+		ORDER BY g.id
+	`, productID)
+	if err != nil {
+		return nil, err
+	}
+	groups := make([]*models.ModifierGroup, 0)
+	for rows.Next() {
+		g, err := scanModifierGroup(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		groups = append(groups, g)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return groups, loadModifiers(ctx, q, groups, true)
+}
+
+// selectedModifier = modifier yang dipilih di satu baris checkout.
+type selectedModifier struct {
+	models.DetailModifier
+	stockProductID *int
+	stockQty       int // per item
+}
+
+// resolveModifiers memvalidasi pilihan modifier terhadap group yang dipasang di produk
+// (required, min/max pilihan) dan mengembalikan snapshot + total tambahan harga per item.
+func resolveModifiers(ctx context.Context, q querier, productID int, productName string, ids []int) ([]selectedModifier, int, error) {
+	groups, err := productModifierGroups(ctx, q, productID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	type option struct {
+		group *models.ModifierGroup
+		mod   models.Modifier
+	}
+	options := make(map[int]option)
+	for _, g := range groups {
+		for _, m := range g.Modifiers {
+			options[m.ID] = option{group: g, mod: m}
+		}
+	}
+
+	picked := make(map[int]int, len(groups))
+	selected := make([]selectedModifier, 0, len(ids))
+	delta := 0
+	for _, id := range ids {
+		opt, ok := options[id]
+		if !ok {
+			return nil, 0, fmt.Errorf("modifier id %d tidak tersedia untuk %s", id, productName)
+		}
+		picked[opt.group.ID]++
+		delta += opt.mod.PriceDelta
+
+		modID := opt.mod.ID
+		selected = append(selected, selectedModifier{
+			DetailModifier: models.DetailModifier{
+				ModifierID: &modID,
+				GroupName:  opt.group.Name,
+				Name:       opt.mod.Name,
+				PriceDelta: opt.mod.PriceDelta,
+			},
+			stockProductID: opt.mod.ProductID,
+			stockQty:       opt.mod.StockQty,
+		})
+	}
+
+	for _, g := range groups {
+		n := picked[g.ID]
+		minSelect := g.MinSelect
+		if g.Required && minSelect < 1 {
+			minSelect = 1
+		}
+		if n < minSelect {
+			return nil, 0, fmt.Errorf("%s: pilih minimal %d %s", productName, minSelect, g.Name)
+		}
+		if g.MaxSelect > 0 && n > g.MaxSelect {
+			return nil, 0, fmt.Errorf("%s: maksimal %d pilihan %s", productName, g.MaxSelect, g.Name)
+		}
+	}
+	return selected, delta, nil
+}
+
+// modifierStockProducts = produk yang stoknya ikut dipotong oleh modifier terpilih.
+func modifierStockProducts(ctx context.Context, q querier, modifierIDs []int) ([]int, error) {
+	if len(modifierIDs) == 0 {
+		return nil, nil
+	}
+	rows, err := q.Query(ctx,
+		`SELECT DISTINCT product_id FROM modifiers WHERE id = ANY($1) AND product_id IS NOT NULL`,
+		modifierIDs,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func insertDetailModifiers(ctx context.Context, q querier, detailID, qty int, mods []selectedModifier) error {
+	for _, m := range mods {
+		stockQty := 0
+		if m.stockProductID != nil {
+			stockQty = m.stockQty * qty
+		}
+		_, err := q.Exec(ctx,
+			`INSERT INTO transaction_detail_modifiers
+			 (transaction_detail_id, modifier_id, group_name, name, price_delta, quantity, stock_product_id, stock_qty)
+			 VALUES ($1,$2,$3,$4,$5,$6,$7,$8)`,
+			detailID, m.ModifierID, m.GroupName, m.Name, m.PriceDelta, qty, m.stockProductID, stockQty,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,330 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"kasir-api/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type OutletRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewOutletRepository(db *pgxpool.Pool) *OutletRepository {
+	return &OutletRepository{db: db}
+}
+
+const outletColumns = `id, code, name, address, timezone, day_cutoff_hour, active, is_default, created_at`
+
+func scanOutlet(row pgx.Row) (*models.Outlet, error) {
+	var o models.Outlet
+	if err := row.Scan(&o.ID, &o.Code, &o.Name, &o.Address, &o.Timezone, &o.CutoffHour, &o.Active, &o.IsDefault, &o.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+func (r *OutletRepository) GetAll() ([]models.Outlet, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := r.db.Query(ctx, `SELECT `+outletColumns+` FROM outlets ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]models.Outlet, 0)
+	for rows.Next() {
+		o, err := scanOutlet(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *o)
+	}
+	return out, rows.Err()
+}
+
+func (r *OutletRepository) GetByID(id int) (*models.Outlet, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	o, err := scanOutlet(r.db.QueryRow(ctx, `SELECT `+outletColumns+` FROM outlets WHERE id=$1`, id))
+	if err != nil {
+		return nil, errors.New("outlet belum ada")
+	}
+	return o, nil
+}
+
+func (r *OutletRepository) Create(o *models.Outlet) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if o.IsDefault {
+		if _, err := tx.Exec(ctx, `UPDATE outlets SET is_default = FALSE WHERE is_default`); err != nil {
+			return err
+		}
+	}
+	err = tx.QueryRow(ctx,
+		`INSERT INTO outlets (code, name, address, timezone, day_cutoff_hour, active, is_default)
+		 VALUES ($1,$2,$3,$4,$5,$6,$7)
+		 RETURNING id, created_at`,
+		o.Code, o.Name, o.Address, o.Timezone, o.CutoffHour, o.Active, o.IsDefault,
+	).Scan(&o.ID, &o.CreatedAt)
+	if isUniqueViolation(err) {
+		return errors.New("kode outlet sudah dipakai")
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// Update ikut mengganti outlet_code di price list outlet kalau kodenya berubah.
+// Outlet default tidak bisa dilepas begitu saja: jadikan outlet lain default.
+func (r *OutletRepository) Update(o *models.Outlet) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var oldCode string
+	var wasDefault bool
+	err = tx.QueryRow(ctx, `SELECT code, is_default FROM outlets WHERE id=$1 FOR UPDATE`, o.ID).Scan(&oldCode, &wasDefault)
+	if err != nil {
+		return errors.New("outlet belum ada")
+	}
+	if wasDefault && !o.IsDefault {
+		return errors.New("jadikan outlet lain default dulu")
+	}
+	if wasDefault && !o.Active {
+		return errors.New("outlet default tidak bisa dinonaktifkan")
+	}
+	if o.IsDefault && !wasDefault {
+		if _, err := tx.Exec(ctx, `UPDATE outlets SET is_default = FALSE WHERE is_default`); err != nil {
+			return err
+		}
+	}
+
+	err = tx.QueryRow(ctx,
+		`UPDATE outlets SET code=$1, name=$2, address=$3, timezone=$4, day_cutoff_hour=$5, active=$6, is_default=$7
+		 WHERE id=$8
+		 RETURNING created_at`,
+		o.Code, o.Name, o.Address, o.Timezone, o.CutoffHour, o.Active, o.IsDefault, o.ID,
+	).Scan(&o.CreatedAt)
+	if isUniqueViolation(err) {
+		return errors.New("kode outlet sudah dipakai")
+	}
+	if err != nil {
+		return err
+	}
+	if o.Code != oldCode {
+		_, err = tx.Exec(ctx, `UPDATE price_lists SET outlet_code = $1 WHERE outlet_code = $2`, o.Code, oldCode)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *OutletRepository) Delete(id int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	ct, err := r.db.Exec(ctx, `DELETE FROM outlets WHERE id=$1 AND NOT is_default`, id)
+	if isForeignKeyViolation(err) {
+		return errors.New("outlet sudah punya shift / transaksi, nonaktifkan saja")
+	}
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return errors.New("outlet belum ada atau masih default")
+	}
+	return nil
+}
+
+// GetStock = stok & harga semua produk jual (bukan induk varian) di satu outlet.
+func (r *OutletRepository) GetStock(outletID int) ([]models.OutletStock, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var code string
+	if err := r.db.QueryRow(ctx, `SELECT code FROM outlets WHERE id=$1`, outletID).Scan(&code); err != nil {
+		return nil, errors.New("outlet belum ada")
+	}
+
+	rows, err := r.db.Query(ctx, `
+		SELECT p.id, p.name, `+availableStock("$1::int")+`, op.price,
+			(SELECT COALESCE(SUM(ti.quantity), 0)
+			 FROM stock_transfer_items ti JOIN stock_transfers st ON st.id = ti.transfer_id
+			 WHERE st.status = 'sent' AND st.to_outlet_id = $1 AND ti.product_id = p.id)
+		FROM products p
+		LEFT JOIN outlet_products op ON op.product_id = p.id AND op.outlet_id = $1
+		WHERE NOT EXISTS (SELECT 1 FROM products v WHERE v.parent_id = p.id)
+		ORDER BY p.id
+	`, outletID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]models.OutletStock, 0)
+	for rows.Next() {
+		s := models.OutletStock{OutletID: outletID, OutletCode: code}
+		if err := rows.Scan(&s.ProductID, &s.ProductName, &s.Stock, &s.Price, &s.InTransit); err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+// SetStock mengisi stok (angka absolut) dan harga khusus produk di satu outlet.
+func (r *OutletRepository) SetStock(outletID int, items []models.OutletStock) ([]models.OutletStock, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := tx.QueryRow(ctx, `SELECT id FROM outlets WHERE id=$1`, outletID).Scan(&outletID); err != nil {
+		return nil, errors.New("outlet belum ada")
+	}
+	for _, it := range items {
+		if err := checkSellable(ctx, tx, it.ProductID); err != nil {
+			return nil, err
+		}
+		if err := setOutletStock(ctx, tx, outletID, it.ProductID, it.Stock, it.Note); err != nil {
+			return nil, err
+		}
+		_, err := tx.Exec(ctx,
+			`UPDATE outlet_products SET price = $1 WHERE outlet_id = $2 AND product_id = $3`,
+			it.Price, outletID, it.ProductID,
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return r.GetStock(outletID)
+}
+
+// setOutletStock men-set stok absolut produk di outlet dan mencatat selisihnya sebagai mutasi adjustment.
+func setOutletStock(ctx context.Context, q querier, outletID, productID, stock int, note string) error {
+	var old int
+	err := q.QueryRow(ctx,
+		`SELECT stock FROM outlet_products WHERE outlet_id = $1 AND product_id = $2 FOR UPDATE`,
+		outletID, productID,
+	).Scan(&old)
+	exists := err == nil
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+	if exists && stock == old {
+		return nil
+	}
+
+	// Stok produk ber-lot / ber-serial = jumlah lot / unitnya, jadi hanya berubah lewat lot / serial
+	if stock != old {
+		var trackLots, trackSerials bool
+		err = q.QueryRow(ctx, `SELECT track_lots, track_serials FROM products WHERE id = $1`, productID).
+			Scan(&trackLots, &trackSerials)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("product id %d not found", productID)
+		}
+		if err != nil {
+			return err
+		}
+		if trackLots {
+			return errors.New("stok produk ber-lot diubah lewat goods receipt atau koreksi lot")
+		}
+		if trackSerials {
+			return errors.New("stok produk ber-serial diubah lewat goods receipt")
+		}
+	}
+
+	_, err = q.Exec(ctx,
+		`INSERT INTO outlet_products (outlet_id, product_id, stock) VALUES ($1,$2,$3)
+		 ON CONFLICT (outlet_id, product_id) DO UPDATE SET stock = EXCLUDED.stock`,
+		outletID, productID, stock,
+	)
+	if isForeignKeyViolation(err) {
+		return fmt.Errorf("product id %d not found", productID)
+	}
+	if err != nil {
+		return err
+	}
+	if stock == old {
+		return nil
+	}
+	return addStockMovement(ctx, q, outletID, productID, stock-old, models.MovementAdjustment, nil, note)
+}
+
+// productOutlets = stok & harga satu produk di tiap outlet yang punya barisnya.
+func productOutlets(ctx context.Context, q querier, productID int) ([]models.OutletStock, error) {
+	rows, err := q.Query(ctx, `
+		SELECT o.id, o.code, op.stock, op.price
+		FROM outlet_products op
+		JOIN outlets o ON o.id = op.outlet_id
+		WHERE op.product_id = $1
+		ORDER BY o.id
+	`, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []models.OutletStock
+	for rows.Next() {
+		s := models.OutletStock{ProductID: productID}
+		if err := rows.Scan(&s.OutletID, &s.OutletCode, &s.Stock, &s.Price); err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+// resolveOutlet mengembalikan outlet aktif (id, kode); id nil = outlet default.
+func resolveOutlet(ctx context.Context, q querier, id *int) (int, string, error) {
+	var outletID int
+	var code string
+	var active bool
+	var err error
+	if id == nil {
+		err = q.QueryRow(ctx, `SELECT id, code, active FROM outlets WHERE is_default`).Scan(&outletID, &code, &active)
+		if err != nil {
+			return 0, "", errors.New("belum ada outlet default")
+		}
+	} else {
+		err = q.QueryRow(ctx, `SELECT id, code, active FROM outlets WHERE id = $1`, *id).Scan(&outletID, &code, &active)
+		if err != nil {
+			return 0, "", fmt.Errorf("outlet id %d not found", *id)
+		}
+	}
+	if !active {
+		return 0, "", fmt.Errorf("outlet %s tidak aktif", code)
+	}
+	return outletID, code, nil
+}
@@ -0,0 +1,194 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"kasir-api/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type PriceListRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPriceListRepository(db *pgxpool.Pool) *PriceListRepository {
+	return &PriceListRepository{db: db}
+}
+
+const priceListColumns = `id, name, kind, outlet_code, active, created_at`
+
+func scanPriceList(row pgx.Row) (*models.PriceList, error) {
+	var pl models.PriceList
+	if err := row.Scan(&pl.ID, &pl.Name, &pl.Kind, &pl.OutletCode, &pl.Active, &pl.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &pl, nil
+}
+
+func (r *PriceListRepository) GetAll() ([]models.PriceList, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := r.db.Query(ctx, `SELECT `+priceListColumns+` FROM price_lists ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]models.PriceList, 0)
+	for rows.Next() {
+		pl, err := scanPriceList(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *pl)
+	}
+	return out, nil
+}
+
+func (r *PriceListRepository) Create(pl *models.PriceList) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := r.db.QueryRow(ctx,
+		`INSERT INTO price_lists (name, kind, outlet_code, active) VALUES ($1,$2,$3,$4)
+		 RETURNING id, created_at`,
+		pl.Name, pl.Kind, pl.OutletCode, pl.Active,
+	).Scan(&pl.ID, &pl.CreatedAt)
+	if isUniqueViolation(err) {
+		return errors.New("price list retail / outlet tersebut sudah ada")
+	}
+	return err
+}
+
+func (r *PriceListRepository) GetByID(id int) (*models.PriceList, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	pl, err := scanPriceList(r.db.QueryRow(ctx,
+		`SELECT `+priceListColumns+` FROM price_lists WHERE id=$1`, id))
+	if err != nil {
+		return nil, errors.New("price list belum ada")
+	}
+
+	rows, err := r.db.Query(ctx, `
+		SELECT pli.product_id, COALESCE(p.name, ''), pli.min_qty, pli.price
+		FROM price_list_items pli
+		LEFT JOIN products p ON p.id = pli.product_id
+		WHERE pli.price_list_id = $1
+		ORDER BY pli.product_id, pli.min_qty
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pl.Items = make([]models.PriceListItem, 0)
+	for rows.Next() {
+		var it models.PriceListItem
+		if err := rows.Scan(&it.ProductID, &it.ProductName, &it.MinQty, &it.Price); err != nil {
+			return nil, err
+		}
+		pl.Items = append(pl.Items, it)
+	}
+	return pl, rows.Err()
+}
+
+func (r *PriceListRepository) Update(pl *models.PriceList) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := r.db.QueryRow(ctx,
+		`UPDATE price_lists SET name=$1, kind=$2, outlet_code=$3, active=$4 WHERE id=$5
+		 RETURNING created_at`,
+		pl.Name, pl.Kind, pl.OutletCode, pl.Active, pl.ID,
+	).Scan(&pl.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return errors.New("price list belum ada")
+	}
+	if isUniqueViolation(err) {
+		return errors.New("price list retail / outlet tersebut sudah ada")
+	}
+	return err
+}
+
+func (r *PriceListRepository) Delete(id int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	ct, err := r.db.Exec(ctx, `DELETE FROM price_lists WHERE id=$1`, id)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return errors.New("price list belum ada")
+	}
+	return nil
+}
+
+// SetItems mengganti seluruh harga di price list (replace, bukan merge).
+func (r *PriceListRepository) SetItems(id int, items []models.PriceListItem) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var exists bool
+	err = tx.QueryRow(ctx, `SELECT TRUE FROM price_lists WHERE id=$1 FOR UPDATE`, id).Scan(&exists)
+	if err != nil {
+		return errors.New("price list belum ada")
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM price_list_items WHERE price_list_id=$1`, id); err != nil {
+		return err
+	}
+
+	for _, it := range items {
+		_, err := tx.Exec(ctx,
+			`INSERT INTO price_list_items (price_list_id, product_id, min_qty, price) VALUES ($1,$2,$3,$4)`,
+			id, it.ProductID, it.MinQty, it.Price,
+		)
+		if isForeignKeyViolation(err) {
+			return errors.New("product belum ada")
+		}
+		if isUniqueViolation(err) {
+			return errors.New("min_qty dobel untuk produk yang sama")
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// resolvePrice memilih harga satuan untuk qty tertentu. Urutan prioritas:
+// price list pelanggan (member/grosir) -> price list outlet -> retail -> products.price.
+// Di tiap price list dipakai quantity break terbesar yang min_qty <= qty;
+// kalau produk tidak ada di price list itu, turun ke prioritas berikutnya.
+func resolvePrice(ctx context.Context, q querier, productID, qty, basePrice int, customerList *int, outletCode string) (int, *int, error) {
+	var price, listID int
+	err := q.QueryRow(ctx, `
+		SELECT pli.price, pl.id
+		FROM price_list_items pli
+		JOIN price_lists pl ON pl.id = pli.price_list_id
+		WHERE pli.product_id = $1 AND pli.min_qty <= $2 AND pl.active
+		  AND (pl.id = $3 OR (pl.kind = 'outlet' AND pl.outlet_code = $4) OR pl.kind = 'retail')
+		ORDER BY CASE WHEN pl.id = $3 THEN 0 WHEN pl.kind = 'outlet' THEN 1 ELSE 2 END, pli.min_qty DESC
+		LIMIT 1
+	`, productID, qty, customerList, outletCode).Scan(&price, &listID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return basePrice, nil, nil
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+	return price, &listID, nil
+}
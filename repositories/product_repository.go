@@ -3,9 +3,11 @@ package repositories
 import (
 	"context"
 	"errors"
+	"fmt"
 	"kasir-api/models"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -18,19 +20,50 @@ func NewProductRepository(db *pgxpool.Pool) *ProductRepository {
 	return &ProductRepository{db: db}
 }
 
-func (r *ProductRepository) GetAll(nameFilter string) ([]models.Product, error) {
+// productColumns membaca stok di outlet (ekspresi SQL, NULL = total semua outlet).
+// Stok produk induk = jumlah stok varian-variannya,
+// stok produk komposit = porsi yang bisa dibuat dari stok bahan.
+func productColumns(outlet string) string {
+	return `p.id, p.name, p.price,
+	COALESCE((SELECT SUM(outlet_stock(v.id, ` + outlet + `)) FROM products v WHERE v.parent_id = p.id), ` + availableStock(outlet) + `),
+	p.category_id, p.unit, p.parent_id, COALESCE(p.sku, ''), COALESCE(p.barcode, ''), p.attributes, p.options,
+	p.reorder_point, p.lead_time_days, p.track_lots, p.track_serials`
+}
+
+func scanProduct(row pgx.Row) (*models.Product, error) {
+	var p models.Product
+	var cat pgtype.Int8
+	err := row.Scan(&p.ID, &p.Name, &p.Price, &p.Stock, &cat, &p.Unit, &p.ParentID, &p.SKU, &p.Barcode, &p.Attributes, &p.Options,
+		&p.ReorderPoint, &p.LeadTimeDays, &p.TrackLots, &p.TrackSerials)
+	if err != nil {
+		return nil, err
+	}
+	if cat.Valid {
+		v := int(cat.Int64)
+		p.CategoryID = &v
+	}
+	return &p, nil
+}
+
+// GetAll mencari produk berdasarkan nama dan/atau SKU/barcode (persis, untuk scanner).
+// outletID nil = stok total semua outlet.
+func (r *ProductRepository) GetAll(nameFilter, code string, outletID *int) ([]models.Product, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	query := `SELECT id, name, price, stock, category_id FROM products`
-	args := []any{}
+	query := `SELECT ` + productColumns("$1::int") + ` FROM products p WHERE TRUE`
+	args := []any{outletID}
 
 	if nameFilter != "" {
-		query += ` WHERE name ILIKE $1`
 		args = append(args, "%"+nameFilter+"%")
+		query += fmt.Sprintf(` AND p.name ILIKE $%d`, len(args))
+	}
+	if code != "" {
+		args = append(args, code)
+		query += fmt.Sprintf(` AND (p.sku = $%d OR p.barcode = $%d)`, len(args), len(args))
 	}
 
-	query += ` ORDER BY id`
+	query += ` ORDER BY p.id`
 
 	rows, err := r.db.Query(ctx, query, args...)
 	if err != nil {
@@ -40,20 +73,16 @@ func (r *ProductRepository) GetAll(nameFilter string) ([]models.Product, error)
 
 	out := make([]models.Product, 0)
 	for rows.Next() {
-		var p models.Product
-		var cat pgtype.Int8
-		if err := rows.Scan(&p.ID, &p.Name, &p.Price, &p.Stock, &cat); err != nil {
+		p, err := scanProduct(rows)
+		if err != nil {
 			return nil, err
 		}
-		if cat.Valid {
-			v := int(cat.Int64)
-			p.CategoryID = &v
-		}
-		out = append(out, p)
+		out = append(out, *p)
 	}
 	return out, nil
 }
 
+// Create mengisi stok awal di outlet default.
 func (r *ProductRepository) Create(p *models.Product) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -63,36 +92,102 @@ func (r *ProductRepository) Create(p *models.Product) error {
 		cat = pgtype.Int8{Int64: int64(*p.CategoryID), Valid: true}
 	}
 
-	return r.db.QueryRow(ctx,
-		`INSERT INTO products (name, price, stock, category_id) VALUES ($1,$2,$3,$4) RETURNING id`,
-		p.Name, p.Price, p.Stock, cat,
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx,
+		`INSERT INTO products (name, price, category_id, unit, parent_id, sku, barcode, attributes, options,
+		 reorder_point, lead_time_days, track_lots, track_serials)
+		 VALUES ($1,$2,$3,$4,$5,NULLIF($6, ''),NULLIF($7, ''),$8,$9,$10,$11,$12,$13) RETURNING id`,
+		p.Name, p.Price, cat, p.Unit, p.ParentID, p.SKU, p.Barcode, p.Attributes, p.Options,
+		p.ReorderPoint, p.LeadTimeDays, p.TrackLots, p.TrackSerials,
 	).Scan(&p.ID)
+	if err != nil {
+		return productWriteError(err)
+	}
+
+	// Produk induk (punya options) stoknya dari varian, tidak punya stok sendiri
+	if len(p.Options) == 0 {
+		outletID, _, err := resolveOutlet(ctx, tx, nil)
+		if err != nil {
+			return err
+		}
+		if err := setOutletStock(ctx, tx, outletID, p.ID, p.Stock, "stok awal"); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
 }
 
-func (r *ProductRepository) GetByID(id int) (*models.Product, error) {
+func (r *ProductRepository) GetByID(id int, outletID *int) (*models.Product, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	var p models.Product
-	var cat pgtype.Int8
+	p, err := scanProduct(r.db.QueryRow(ctx,
+		`SELECT `+productColumns("$2::int")+` FROM products p WHERE p.id=$1`, id, outletID))
+	if err != nil {
+		return nil, errors.New("produk belum ada")
+	}
 
-	err := r.db.QueryRow(ctx,
-		`SELECT id, name, price, stock, category_id FROM products WHERE id=$1`,
-		id,
-	).Scan(&p.ID, &p.Name, &p.Price, &p.Stock, &cat)
+	variants, err := r.variants(ctx, id, outletID)
+	if err != nil {
+		return nil, err
+	}
+	p.Variants = variants
 
+	groups, err := productModifierGroups(ctx, r.db, id)
 	if err != nil {
-		return nil, errors.New("produk belum ada")
+		return nil, err
+	}
+	for _, g := range groups {
+		p.ModifierGroups = append(p.ModifierGroups, *g)
 	}
 
-	if cat.Valid {
-		v := int(cat.Int64)
-		p.CategoryID = &v
+	if p.Recipe, err = loadRecipe(ctx, r.db, id); err != nil {
+		return nil, err
 	}
-	return &p, nil
+	if p.Bundle, err = loadBundle(ctx, r.db, id); err != nil {
+		return nil, err
+	}
+	if p.Outlets, err = productOutlets(ctx, r.db, id); err != nil {
+		return nil, err
+	}
+	return p, nil
 }
 
-func (r *ProductRepository) Update(p *models.Product) error {
+// GetVariants = daftar varian satu produk induk.
+func (r *ProductRepository) GetVariants(parentID int, outletID *int) ([]models.Product, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return r.variants(ctx, parentID, outletID)
+}
+
+func (r *ProductRepository) variants(ctx context.Context, parentID int, outletID *int) ([]models.Product, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT `+productColumns("$2::int")+` FROM products p WHERE p.parent_id=$1 ORDER BY p.id`, parentID, outletID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]models.Product, 0)
+	for rows.Next() {
+		p, err := scanProduct(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *p)
+	}
+	return out, rows.Err()
+}
+
+// Update tidak mengubah parent_id; varian tetap milik induknya.
+// Stok yang dikirim ditulis ke outletID (nil = outlet default); harga khusus outlet tidak disentuh.
+func (r *ProductRepository) Update(p *models.Product, outletID *int) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
@@ -101,17 +196,72 @@ func (r *ProductRepository) Update(p *models.Product) error {
 		cat = pgtype.Int8{Int64: int64(*p.CategoryID), Valid: true}
 	}
 
-	ct, err := r.db.Exec(ctx,
-		`UPDATE products SET name=$1, price=$2, stock=$3, category_id=$4 WHERE id=$5`,
-		p.Name, p.Price, p.Stock, cat, p.ID,
-	)
+	tx, err := r.db.Begin(ctx)
 	if err != nil {
 		return err
 	}
-	if ct.RowsAffected() == 0 {
+	defer tx.Rollback(ctx)
+
+	if p.TrackSerials {
+		var isComponent bool
+		err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM bundle_items WHERE product_id = $1)`, p.ID).Scan(&isComponent)
+		if err != nil {
+			return err
+		}
+		if isComponent {
+			return errors.New("produk ini isi paket, tidak bisa dicatat per nomor seri")
+		}
+	}
+
+	err = tx.QueryRow(ctx,
+		`UPDATE products SET name=$1, price=$2, category_id=$3, unit=$4, sku=NULLIF($5, ''), barcode=NULLIF($6, ''),
+		 attributes=$7, options=$8, reorder_point=$9, lead_time_days=$10, track_lots=$11, track_serials=$12
+		 WHERE id=$13
+		 RETURNING parent_id`,
+		p.Name, p.Price, cat, p.Unit, p.SKU, p.Barcode, p.Attributes, p.Options, p.ReorderPoint, p.LeadTimeDays,
+		p.TrackLots, p.TrackSerials, p.ID,
+	).Scan(&p.ParentID)
+	if errors.Is(err, pgx.ErrNoRows) {
 		return errors.New("produk belum ada")
 	}
-	return nil
+	if err != nil {
+		return productWriteError(err)
+	}
+
+	// Stok induk varian, paket dan produk resep adalah hasil hitungan (lihat availableStock);
+	// angka dari GET yang dikirim balik lewat PUT tidak boleh jadi stok sendiri
+	derived, err := hasDerivedStock(ctx, tx, p.ID)
+	if err != nil {
+		return err
+	}
+	if !derived {
+		target, _, err := resolveOutlet(ctx, tx, outletID)
+		if err != nil {
+			return err
+		}
+		if err := setOutletStock(ctx, tx, target, p.ID, p.Stock, "edit produk"); err != nil {
+			return err
+		}
+	}
+	// Baru diaktifkan track_lots: stok yang sudah ada masuk lot TANPA-LOT
+	if err := syncLotRemainder(ctx, tx, nil, p.ID); err != nil {
+		return err
+	}
+	if err := checkSerialStock(ctx, tx, p.ID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// hasDerivedStock = produk punya varian, komponen paket atau resep, jadi stoknya dihitung dari produk lain.
+func hasDerivedStock(ctx context.Context, q querier, productID int) (bool, error) {
+	var derived bool
+	err := q.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM products WHERE parent_id = $1)
+			OR EXISTS (SELECT 1 FROM bundle_items WHERE bundle_id = $1)
+			OR EXISTS (SELECT 1 FROM product_recipes WHERE product_id = $1)
+	`, productID).Scan(&derived)
+	return derived, err
 }
 
 func (r *ProductRepository) Delete(id int) error {
@@ -127,3 +277,55 @@ func (r *ProductRepository) Delete(id int) error {
 	}
 	return nil
 }
+
+func productWriteError(err error) error {
+	if isUniqueViolation(err) {
+		return errors.New("sku / barcode / kombinasi atribut varian sudah dipakai")
+	}
+	if isForeignKeyViolation(err) {
+		return errors.New("produk induk belum ada")
+	}
+	return err
+}
+
+// checkSerialStock: stok produk track_serials di tiap outlet harus sama dengan unit
+// in_stock-nya. Serial tidak bisa dikarang dari stok lama, jadi produk yang masih
+// punya stok tanpa serial belum bisa diaktifkan track_serials.
+func checkSerialStock(ctx context.Context, q querier, productID int) error {
+	var code string
+	var stock, units int
+	err := q.QueryRow(ctx, `
+		SELECT o.code, op.stock, COUNT(s.id)
+		FROM outlet_products op
+		JOIN products p ON p.id = op.product_id AND p.track_serials
+		JOIN outlets o ON o.id = op.outlet_id
+		LEFT JOIN product_serials s ON s.product_id = op.product_id AND s.outlet_id = op.outlet_id AND s.status = $2
+		WHERE op.product_id = $1
+		GROUP BY o.code, op.stock
+		HAVING op.stock <> COUNT(s.id)
+		LIMIT 1
+	`, productID, models.SerialInStock).Scan(&code, &stock, &units)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("stok di outlet %s (%d) belum tercatat per serial (%d unit); nol-kan stoknya lalu terima ulang lewat goods receipt", code, stock, units)
+}
+
+// checkSellable menolak produk induk yang punya varian: yang dijual harus variannya.
+func checkSellable(ctx context.Context, q querier, productID int) error {
+	var name string
+	err := q.QueryRow(ctx,
+		`SELECT p.name FROM products p WHERE p.id = $1 AND EXISTS (SELECT 1 FROM products v WHERE v.parent_id = p.id)`,
+		productID,
+	).Scan(&name)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("pilih varian untuk %s", name)
+}
@@ -0,0 +1,116 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const DefaultReceiptFormat = "INV/{outlet}/{date}/{seq:4}"
+
+// ReceiptNumbering = format nomor struk. Token yang dikenal:
+// {outlet} (kode outlet shift), {date} (YYYYMMDD), {seq} / {seq:N} (nomor urut, N digit).
+type ReceiptNumbering struct {
+	Format string
+}
+
+// receiptPart = potongan format: teks apa adanya, atau token di dalam {...}.
+type receiptPart struct {
+	text    string
+	token   string
+	isToken bool
+}
+
+// receiptParts memecah format jadi teks & token; '{' tanpa pasangan '}' dianggap teks biasa.
+func receiptParts(f string) []receiptPart {
+	parts := make([]receiptPart, 0)
+	for {
+		open := strings.IndexByte(f, '{')
+		if open < 0 {
+			break
+		}
+		end := strings.IndexByte(f[open:], '}')
+		if end < 0 {
+			break
+		}
+		end += open
+
+		if open > 0 {
+			parts = append(parts, receiptPart{text: f[:open]})
+		}
+		parts = append(parts, receiptPart{text: f[open : end+1], token: f[open+1 : end], isToken: true})
+		f = f[end+1:]
+	}
+	if f != "" {
+		parts = append(parts, receiptPart{text: f})
+	}
+	return parts
+}
+
+// seqWidth = lebar N dari {seq} / {seq:N}; ok false kalau token bukan seq atau N tidak valid.
+func seqWidth(token string) (int, bool) {
+	if token == "seq" {
+		return 4, true
+	}
+	if !strings.HasPrefix(token, "seq:") {
+		return 0, false
+	}
+	w, err := strconv.Atoi(strings.TrimPrefix(token, "seq:"))
+	return w, err == nil && w > 0
+}
+
+// Validate memastikan format menghasilkan nomor unik: index unik receipt_no berlaku global,
+// jadi {outlet}, {date} dan {seq} / {seq:N} wajib ada semua.
+func (n ReceiptNumbering) Validate() error {
+	var outlet, date, seq bool
+	for _, p := range receiptParts(n.Format) {
+		if !p.isToken {
+			continue
+		}
+		switch {
+		case p.token == "outlet":
+			outlet = true
+		case p.token == "date":
+			date = true
+		case p.token == "seq" || strings.HasPrefix(p.token, "seq:"):
+			if _, ok := seqWidth(p.token); !ok {
+				return fmt.Errorf("RECEIPT_FORMAT: {%s} tidak valid, pakai {seq:N} dengan N > 0", p.token)
+			}
+			seq = true
+		}
+	}
+	if !outlet || !date || !seq {
+		return errors.New("RECEIPT_FORMAT wajib memuat {outlet}, {date} dan {seq} / {seq:N}")
+	}
+	return nil
+}
+
+func (n ReceiptNumbering) format(outletCode string, businessDate time.Time, seq int) string {
+	f := n.Format
+	if f == "" {
+		f = DefaultReceiptFormat
+	}
+
+	var b strings.Builder
+	for _, p := range receiptParts(f) {
+		if !p.isToken {
+			b.WriteString(p.text)
+			continue
+		}
+		switch p.token {
+		case "outlet":
+			b.WriteString(outletCode)
+		case "date":
+			b.WriteString(businessDate.Format("20060102"))
+		default:
+			if width, ok := seqWidth(p.token); ok {
+				fmt.Fprintf(&b, "%0*d", width, seq)
+			} else {
+				b.WriteString(p.text)
+			}
+		}
+	}
+	return b.String()
+}
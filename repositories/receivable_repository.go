@@ -0,0 +1,318 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"kasir-api/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ReceivableRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewReceivableRepository(db *pgxpool.Pool) *ReceivableRepository {
+	return &ReceivableRepository{db: db}
+}
+
+const receivableEntryColumns = `re.id, re.customer_id, re.transaction_id, COALESCE(t.receipt_no, ''), re.shift_id,
+	re.type, re.method, re.amount, re.remaining, re.notes, re.created_at`
+
+const receivableEntryFrom = ` FROM receivable_entries re
+	LEFT JOIN transactions t ON t.id = re.transaction_id`
+
+func scanReceivableEntry(row pgx.Row) (*models.ReceivableEntry, error) {
+	var e models.ReceivableEntry
+	err := row.Scan(&e.ID, &e.CustomerID, &e.TransactionID, &e.ReceiptNo, &e.ShiftID,
+		&e.Type, &e.Method, &e.Amount, &e.Remaining, &e.Notes, &e.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// Aging dihitung dari sisa (remaining) tiap charge berdasarkan umur transaksinya.
+const customerReceivableQuery = `
+	SELECT c.id, c.name, c.phone, c.credit_limit,
+		COALESCE((SELECT SUM(amount) FROM receivable_entries WHERE customer_id = c.id), 0),
+		COALESCE(SUM(re.remaining) FILTER (WHERE re.created_at >  $1::timestamptz - interval '31 days'), 0),
+		COALESCE(SUM(re.remaining) FILTER (WHERE re.created_at <= $1::timestamptz - interval '31 days'
+			AND re.created_at > $1::timestamptz - interval '61 days'), 0),
+		COALESCE(SUM(re.remaining) FILTER (WHERE re.created_at <= $1::timestamptz - interval '61 days'
+			AND re.created_at > $1::timestamptz - interval '91 days'), 0),
+		COALESCE(SUM(re.remaining) FILTER (WHERE re.created_at <= $1::timestamptz - interval '91 days'), 0)
+	FROM customers c
+	LEFT JOIN receivable_entries re ON re.customer_id = c.id AND re.type = 'charge' AND re.remaining > 0`
+
+func scanCustomerReceivable(row pgx.Row) (*models.CustomerReceivable, error) {
+	var cr models.CustomerReceivable
+	err := row.Scan(&cr.CustomerID, &cr.CustomerName, &cr.Phone, &cr.CreditLimit, &cr.Balance,
+		&cr.Aging.Current, &cr.Aging.Days60, &cr.Aging.Days90, &cr.Aging.Over90)
+	if err != nil {
+		return nil, err
+	}
+	cr.Available = max(cr.CreditLimit-cr.Balance, 0)
+	return &cr, nil
+}
+
+// GetAll = laporan aging piutang: semua pelanggan yang saldonya tidak nol.
+func (r *ReceivableRepository) GetAll() ([]models.CustomerReceivable, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	rows, err := r.db.Query(ctx, customerReceivableQuery+`
+		WHERE EXISTS (SELECT 1 FROM receivable_entries x WHERE x.customer_id = c.id)
+		GROUP BY c.id
+		HAVING COALESCE((SELECT SUM(amount) FROM receivable_entries WHERE customer_id = c.id), 0) <> 0
+		ORDER BY c.name, c.id
+	`, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]models.CustomerReceivable, 0)
+	for rows.Next() {
+		cr, err := scanCustomerReceivable(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *cr)
+	}
+	return out, rows.Err()
+}
+
+func customerReceivable(ctx context.Context, q querier, customerID int, asOf time.Time) (*models.CustomerReceivable, error) {
+	cr, err := scanCustomerReceivable(q.QueryRow(ctx, customerReceivableQuery+`
+		WHERE c.id = $2
+		GROUP BY c.id
+	`, asOf, customerID))
+	if err != nil {
+		return nil, errors.New("pelanggan belum ada")
+	}
+	return cr, nil
+}
+
+func (r *ReceivableRepository) GetByCustomer(customerID int) (*models.CustomerReceivable, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return customerReceivable(ctx, r.db, customerID, time.Now())
+}
+
+// Statement = mutasi piutang pelanggan dalam periode [from, to) + saldo awal/akhir.
+func (r *ReceivableRepository) Statement(customerID int, from, to time.Time) (*models.Statement, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cr, err := customerReceivable(ctx, r.db, customerID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	st := models.Statement{
+		CustomerReceivable: *cr,
+		From:               from,
+		To:                 to,
+		Lines:              make([]models.StatementLine, 0),
+	}
+
+	err = r.db.QueryRow(ctx,
+		`SELECT COALESCE(SUM(amount), 0) FROM receivable_entries WHERE customer_id = $1 AND created_at < $2`,
+		customerID, from,
+	).Scan(&st.OpeningBalance)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(ctx, `SELECT `+receivableEntryColumns+receivableEntryFrom+`
+		WHERE re.customer_id = $1 AND re.created_at >= $2 AND re.created_at < $3
+		ORDER BY re.created_at, re.id
+	`, customerID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	balance := st.OpeningBalance
+	for rows.Next() {
+		e, err := scanReceivableEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		balance += e.Amount
+		st.Lines = append(st.Lines, models.StatementLine{ReceivableEntry: *e, Balance: balance})
+	}
+	st.ClosingBalance = balance
+	return &st, rows.Err()
+}
+
+func receivableBalance(ctx context.Context, q querier, customerID int) (int, error) {
+	var balance int
+	err := q.QueryRow(ctx,
+		`SELECT COALESCE(SUM(amount), 0) FROM receivable_entries WHERE customer_id = $1`,
+		customerID,
+	).Scan(&balance)
+	return balance, err
+}
+
+// applyCredit mengurangi sisa charge paling lama dulu (FIFO). Kalau preferTxID diisi,
+// charge transaksi itu dilunasi duluan. Sisa yang tidak terpakai dikembalikan
+// (jadi saldo minus / titipan pelanggan).
+func applyCredit(ctx context.Context, q querier, customerID, amount int, preferTxID *int) (int, error) {
+	rows, err := q.Query(ctx, `
+		SELECT id, remaining FROM receivable_entries
+		WHERE customer_id = $1 AND type = 'charge' AND remaining > 0
+		ORDER BY (transaction_id IS NOT DISTINCT FROM $2) DESC, created_at, id
+		FOR UPDATE
+	`, customerID, preferTxID)
+	if err != nil {
+		return 0, err
+	}
+	type openCharge struct{ id, remaining int }
+	var charges []openCharge
+	for rows.Next() {
+		var c openCharge
+		if err := rows.Scan(&c.id, &c.remaining); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		charges = append(charges, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, c := range charges {
+		if amount == 0 {
+			break
+		}
+		take := min(c.remaining, amount)
+		if _, err := q.Exec(ctx,
+			`UPDATE receivable_entries SET remaining = remaining - $1 WHERE id = $2`, take, c.id,
+		); err != nil {
+			return 0, err
+		}
+		amount -= take
+	}
+	return amount, nil
+}
+
+// chargeAccount mencatat belanja kasbon. Customer harus sudah di-lock oleh pemanggil.
+func chargeAccount(ctx context.Context, q querier, customerID, transactionID, shiftID, amount int) error {
+	var limit int
+	if err := q.QueryRow(ctx, `SELECT credit_limit FROM customers WHERE id = $1`, customerID).Scan(&limit); err != nil {
+		return errors.New("pelanggan belum ada")
+	}
+	balance, err := receivableBalance(ctx, q, customerID)
+	if err != nil {
+		return err
+	}
+	if balance+amount > limit {
+		return fmt.Errorf("limit kasbon tidak cukup (limit=%d, utang=%d, kasbon=%d)", limit, balance, amount)
+	}
+
+	_, err = q.Exec(ctx,
+		`INSERT INTO receivable_entries (customer_id, transaction_id, shift_id, type, method, amount, remaining)
+		 VALUES ($1, $2, $3, 'charge', $4, $5, $5)`,
+		customerID, transactionID, shiftID, models.PaymentAccount, amount,
+	)
+	if err != nil {
+		return err
+	}
+
+	// Titipan (saldo minus) langsung dipakai untuk charge baru
+	if balance < 0 {
+		_, err = applyCredit(ctx, q, customerID, min(-balance, amount), &transactionID)
+	}
+	return err
+}
+
+// reverseReceivable dipanggil saat transaksi kasbon di-refund:
+// utang transaksi itu dihapus dulu, kelebihannya jadi titipan pelanggan.
+func reverseReceivable(ctx context.Context, q querier, customerID, transactionID, shiftID, amount int) error {
+	if err := lockCustomer(ctx, q, customerID); err != nil {
+		return err
+	}
+	_, err := q.Exec(ctx,
+		`INSERT INTO receivable_entries (customer_id, transaction_id, shift_id, type, amount)
+		 VALUES ($1, $2, $3, 'refund', $4)`,
+		customerID, transactionID, shiftID, -amount,
+	)
+	if err != nil {
+		return err
+	}
+	_, err = applyCredit(ctx, q, customerID, amount, &transactionID)
+	return err
+}
+
+// RecordPayment mencatat cicilan kasbon. Uang masuk ke laci shift yang menerima,
+// jadi shift harus terbuka (sama seperti checkout).
+func (r *ReceivableRepository) RecordPayment(customerID int, req models.ReceivablePaymentRequest) (*models.ReceivableEntry, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var shiftStatus string
+	var businessDate time.Time
+	err = tx.QueryRow(ctx,
+		`SELECT status, outlet_business_date(now(), outlet_id) FROM shifts WHERE id = $1 FOR SHARE`,
+		req.ShiftID,
+	).Scan(&shiftStatus, &businessDate)
+	if err != nil {
+		return nil, fmt.Errorf("shift id %d not found", req.ShiftID)
+	}
+	if shiftStatus != models.ShiftOpen {
+		return nil, errors.New("shift sudah ditutup, buka shift baru dulu")
+	}
+	if z, closed, err := closedZNumber(ctx, tx, businessDate); err != nil {
+		return nil, err
+	} else if closed {
+		return nil, fmt.Errorf("hari bisnis sudah ditutup (Z #%d)", z)
+	}
+
+	if err := lockCustomer(ctx, tx, customerID); err != nil {
+		return nil, err
+	}
+	balance, err := receivableBalance(ctx, tx, customerID)
+	if err != nil {
+		return nil, err
+	}
+	if req.Amount > balance {
+		return nil, fmt.Errorf("pembayaran melebihi sisa utang (sisa=%d)", balance)
+	}
+
+	var id int
+	err = tx.QueryRow(ctx,
+		`INSERT INTO receivable_entries (customer_id, shift_id, type, method, amount, notes)
+		 VALUES ($1, $2, 'payment', $3, $4, $5)
+		 RETURNING id`,
+		customerID, req.ShiftID, req.Method, -req.Amount, req.Notes,
+	).Scan(&id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := applyCredit(ctx, tx, customerID, req.Amount, nil); err != nil {
+		return nil, err
+	}
+
+	e, err := scanReceivableEntry(tx.QueryRow(ctx,
+		`SELECT `+receivableEntryColumns+receivableEntryFrom+` WHERE re.id = $1`, id))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
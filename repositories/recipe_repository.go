@@ -0,0 +1,153 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"kasir-api/models"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type RecipeRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewRecipeRepository(db *pgxpool.Pool) *RecipeRepository {
+	return &RecipeRepository{db: db}
+}
+
+func loadRecipe(ctx context.Context, q querier, productID int) ([]models.RecipeItem, error) {
+	rows, err := q.Query(ctx, `
+		SELECT r.ingredient_id, COALESCE(p.name, ''), COALESCE(p.unit, ''), r.quantity
+		FROM product_recipes r
+		LEFT JOIN products p ON p.id = r.ingredient_id
+		WHERE r.product_id = $1
+		ORDER BY r.ingredient_id
+	`, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]models.RecipeItem, 0)
+	for rows.Next() {
+		var it models.RecipeItem
+		if err := rows.Scan(&it.IngredientID, &it.IngredientName, &it.Unit, &it.Quantity); err != nil {
+			return nil, err
+		}
+		out = append(out, it)
+	}
+	return out, rows.Err()
+}
+
+func (r *RecipeRepository) Get(productID int) ([]models.RecipeItem, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var exists bool
+	if err := r.db.QueryRow(ctx, `SELECT TRUE FROM products WHERE id=$1`, productID).Scan(&exists); err != nil {
+		return nil, errors.New("produk belum ada")
+	}
+	return loadRecipe(ctx, r.db, productID)
+}
+
+// Set mengganti resep produk (replace). Resep kosong = produk kembali memakai stok sendiri.
+// Resep tidak bertingkat: bahan tidak boleh punya resep, dan produk komposit tidak boleh jadi bahan.
+func (r *RecipeRepository) Set(productID int, items []models.RecipeItem) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var usedAsIngredient, hasVariants, isBundle bool
+	err = tx.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM product_recipes WHERE ingredient_id = p.id),
+			EXISTS (SELECT 1 FROM products v WHERE v.parent_id = p.id),
+			EXISTS (SELECT 1 FROM bundle_items WHERE bundle_id = p.id)
+		FROM products p WHERE p.id = $1 FOR UPDATE OF p
+	`, productID).Scan(&usedAsIngredient, &hasVariants, &isBundle)
+	if err != nil {
+		return errors.New("produk belum ada")
+	}
+	if len(items) > 0 && usedAsIngredient {
+		return errors.New("produk ini dipakai sebagai bahan, tidak bisa punya resep")
+	}
+	if len(items) > 0 && hasVariants {
+		return errors.New("resep dipasang di varian, bukan di produk induk")
+	}
+	if len(items) > 0 && isBundle {
+		return errors.New("produk ini paket, stoknya dari isi paket")
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM product_recipes WHERE product_id=$1`, productID); err != nil {
+		return err
+	}
+
+	for _, it := range items {
+		var composite, bundle bool
+		err := tx.QueryRow(ctx, `
+			SELECT EXISTS (SELECT 1 FROM product_recipes WHERE product_id = p.id),
+				EXISTS (SELECT 1 FROM bundle_items WHERE bundle_id = p.id)
+			FROM products p WHERE p.id = $1
+		`, it.IngredientID).Scan(&composite, &bundle)
+		if err != nil {
+			return fmt.Errorf("bahan id %d belum ada", it.IngredientID)
+		}
+		if composite {
+			return fmt.Errorf("bahan id %d punya resep sendiri (resep tidak bertingkat)", it.IngredientID)
+		}
+		if bundle {
+			return fmt.Errorf("bahan id %d adalah paket", it.IngredientID)
+		}
+
+		_, err = tx.Exec(ctx,
+			`INSERT INTO product_recipes (product_id, ingredient_id, quantity) VALUES ($1,$2,$3)`,
+			productID, it.IngredientID, it.Quantity,
+		)
+		if isUniqueViolation(err) {
+			return fmt.Errorf("bahan id %d dobel", it.IngredientID)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// recipeLine = kebutuhan satu bahan untuk 1 produk komposit.
+type recipeLine struct {
+	ingredientID int
+	quantity     int
+}
+
+// loadRecipes mengambil resep banyak produk sekaligus (product_id -> bahan).
+func loadRecipes(ctx context.Context, q querier, productIDs []int) (map[int][]recipeLine, error) {
+	rows, err := q.Query(ctx, `
+		SELECT product_id, ingredient_id, quantity
+		FROM product_recipes
+		WHERE product_id = ANY($1)
+		ORDER BY product_id, ingredient_id
+	`, productIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make(map[int][]recipeLine)
+	for rows.Next() {
+		var productID int
+		var l recipeLine
+		if err := rows.Scan(&productID, &l.ingredientID, &l.quantity); err != nil {
+			return nil, err
+		}
+		out[productID] = append(out[productID], l)
+	}
+	return out, rows.Err()
+}
@@ -2,6 +2,10 @@ package repositories
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"kasir-api/models"
+	"math"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -21,25 +25,85 @@ type BestSeller struct {
 }
 
 type TodayReport struct {
-	TotalRevenue   int        `json:"total_revenue"`
-	TotalTransaksi int        `json:"total_transaksi"`
-	ProdukTerlaris BestSeller `json:"produk_terlaris"`
+	OutletID       *int                 `json:"outlet_id,omitempty"`
+	TotalRevenue   int                  `json:"total_revenue"`
+	TotalTransaksi int                  `json:"total_transaksi"`
+	ProdukTerlaris BestSeller           `json:"produk_terlaris"`
+	PerOutlet      []models.OutletTotal `json:"per_outlet,omitempty"` // group_by=outlet
 }
 
-func (r *ReportRepository) GetReportByDateRange(start, end time.Time) (TodayReport, error) {
+// Semua query laporan memakai $1/$2 = hari bisnis [from, to) (YYYY-MM-DD, sesuai zona waktu &
+// jam tutup buku outlet masing-masing) dan $3 = outlet (NULL = semua outlet).
+const outletFilter = `($3::int IS NULL OR t.outlet_id = $3)`
+
+// productSalesSource = penjualan per produk (transaction_id, product_id, quantity, amount, outlet_id, business_date)
+// untuk transaksi di hari bisnis [$1, $2). Baris paket diganti komponennya, dengan pendapatan yang sudah
+// dialokasikan proporsional.
+var productSalesSource = productSales("TRUE")
+
+// productSales = productSalesSource dengan syarat tambahan cond atas transaksi t.
+func productSales(cond string) string {
+	return `(
+		SELECT td.transaction_id, td.product_id, td.quantity, td.subtotal AS amount, t.outlet_id, t.business_date
+		FROM transaction_details td
+		JOIN transactions t ON t.id = td.transaction_id
+		WHERE t.business_date >= $1::date AND t.business_date < $2::date AND ` + outletFilter + ` AND ` + cond + `
+		  AND NOT EXISTS (SELECT 1 FROM transaction_bundle_allocations a WHERE a.transaction_detail_id = td.id)
+		UNION ALL
+		SELECT a.transaction_id, a.product_id, a.quantity, a.amount, t.outlet_id, t.business_date
+		FROM transaction_bundle_allocations a
+		JOIN transactions t ON t.id = a.transaction_id
+		WHERE t.business_date >= $1::date AND t.business_date < $2::date AND ` + outletFilter + ` AND ` + cond + `
+	) s`
+}
+
+// unsummarized = transaksi t yang hari bisnisnya belum masuk ringkasan harian outletnya.
+const unsummarized = `NOT EXISTS (SELECT 1 FROM sales_summary_state ss WHERE ss.outlet_id = t.outlet_id AND t.business_date < ss.summarized_until)`
+
+// dailySalesSource = (outlet_id, business_date, transactions, total_amount, items) per outlet per hari bisnis
+// [$1, $2): dari daily_sales untuk hari yang sudah diringkas, selebihnya dihitung dari transaksi.
+const dailySalesSource = `(
+		SELECT d.outlet_id, d.business_date, d.transactions, d.total_amount, d.items
+		FROM daily_sales d
+		JOIN sales_summary_state ss ON ss.outlet_id = d.outlet_id AND d.business_date < ss.summarized_until
+		WHERE d.business_date >= $1::date AND d.business_date < $2::date AND ($3::int IS NULL OR d.outlet_id = $3)
+		UNION ALL
+		SELECT t.outlet_id, t.business_date, COUNT(*), SUM(t.total_amount), SUM(i.items)::bigint
+		FROM transactions t
+		CROSS JOIN LATERAL (SELECT COALESCE(SUM(quantity), 0) AS items FROM transaction_details WHERE transaction_id = t.id) i
+		WHERE t.business_date >= $1::date AND t.business_date < $2::date AND ` + outletFilter + ` AND ` + unsummarized + `
+		GROUP BY t.outlet_id, t.business_date
+	) ds`
+
+// productDaySource = (product_id, quantity, amount) seperti productSalesSource tanpa transaction_id,
+// dari daily_product_sales untuk hari yang sudah diringkas.
+var productDaySource = `(
+		SELECT d.product_id, d.quantity, d.amount
+		FROM daily_product_sales d
+		JOIN sales_summary_state ss ON ss.outlet_id = d.outlet_id AND d.business_date < ss.summarized_until
+		WHERE d.business_date >= $1::date AND d.business_date < $2::date AND ($3::int IS NULL OR d.outlet_id = $3)
+		UNION ALL
+		SELECT s.product_id, s.quantity, s.amount
+		FROM ` + productSales(unsummarized) + `
+		WHERE s.product_id IS NOT NULL
+	) s`
+
+// GetReportByDateRange: outletID nil = semua outlet, groupByOutlet menambah rincian per outlet.
+func (r *ReportRepository) GetReportByDateRange(start, end time.Time, outletID *int, groupByOutlet bool) (TodayReport, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	var rep TodayReport
+	from, to := start.Format("2006-01-02"), end.Format("2006-01-02")
+
+	rep := TodayReport{OutletID: outletID}
 
 	// total_revenue + total_transaksi
 	err := r.db.QueryRow(ctx, `
 		SELECT
-			COALESCE(SUM(total_amount), 0) AS total_revenue,
-			COUNT(*) AS total_transaksi
-		FROM transactions
-		WHERE created_at >= $1 AND created_at < $2
-	`, start, end).Scan(&rep.TotalRevenue, &rep.TotalTransaksi)
+			COALESCE(SUM(ds.total_amount), 0)::bigint AS total_revenue,
+			COALESCE(SUM(ds.transactions), 0)::bigint AS total_transaksi
+		FROM `+dailySalesSource+`
+	`, from, to, outletID).Scan(&rep.TotalRevenue, &rep.TotalTransaksi)
 	if err != nil {
 		return rep, err
 	}
@@ -48,15 +112,14 @@ func (r *ReportRepository) GetReportByDateRange(start, end time.Time) (TodayRepo
 	var nama string
 	var qty int
 	err = r.db.QueryRow(ctx, `
-		SELECT p.name, COALESCE(SUM(td.quantity),0) AS qty
-		FROM transaction_details td
-		JOIN transactions t ON t.id = td.transaction_id
-		JOIN products p ON p.id = td.product_id
-		WHERE t.created_at >= $1 AND t.created_at < $2
-		GROUP BY p.name
+		SELECT COALESCE(pp.name, p.name), COALESCE(SUM(s.quantity),0) AS qty
+		FROM `+productDaySource+`
+		JOIN products p ON p.id = s.product_id
+		LEFT JOIN products pp ON pp.id = p.parent_id -- varian dihitung ke produk induk
+		GROUP BY COALESCE(pp.id, p.id), COALESCE(pp.name, p.name)
 		ORDER BY qty DESC
 		LIMIT 1
-	`, start, end).Scan(&nama, &qty)
+	`, from, to, outletID).Scan(&nama, &qty)
 
 	// Kalau belum ada transaksi hari itu, query ini bisa “no rows”
 	if err == nil {
@@ -65,5 +128,610 @@ func (r *ReportRepository) GetReportByDateRange(start, end time.Time) (TodayRepo
 		rep.ProdukTerlaris = BestSeller{Nama: "", QtyTerjual: 0}
 	}
 
+	if groupByOutlet {
+		if rep.PerOutlet, err = outletTotals(ctx, r.db, from, to, outletID); err != nil {
+			return rep, err
+		}
+	}
 	return rep, nil
 }
+
+// Today = hari bisnis berjalan di outlet (nil = outlet default).
+func (r *ReportRepository) Today(outletID *int) (time.Time, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return currentBusinessDate(ctx, r.db, outletID)
+}
+
+// DayBounds = [awal hari bisnis start, awal hari bisnis end) di outlet (nil = outlet default),
+// untuk memfilter tabel yang hanya punya created_at.
+func (r *ReportRepository) DayBounds(start, end time.Time, outletID *int) (time.Time, time.Time, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var from, to *time.Time
+	err := r.db.QueryRow(ctx, `
+		SELECT outlet_day_start($1::date, o.id), outlet_day_start($2::date, o.id)
+		FROM (SELECT COALESCE($3::int, (SELECT id FROM outlets WHERE is_default)) AS id) o
+	`, start.Format("2006-01-02"), end.Format("2006-01-02"), outletID).Scan(&from, &to)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if from == nil || to == nil {
+		return time.Time{}, time.Time{}, errors.New("outlet belum ada")
+	}
+	return *from, *to, nil
+}
+
+func (r *ReportRepository) GetDayReport(businessDate string, outletID *int, taxRate float64) (*models.DayReport, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return dayReport(ctx, r.db, businessDate, outletID, taxRate)
+}
+
+// dayReport dipakai X-report (pool) dan Z-report (di dalam tx penutupan).
+// Refund dihitung ke outlet transaksi aslinya (tempat stoknya dikembalikan).
+// PeriodStart/PeriodEnd = awal hari bisnis paling awal s/d akhir paling akhir di antara outlet yang dicakup.
+// Sengaja dari data mentah, bukan daily_sales: cuma satu hari (index business_date), X-report selalu hari
+// berjalan yang belum diringkas, dan rincian kasir/tender/refund/diskon tidak ada di ringkasan, jadi semua
+// angka Z diambil dari sumber yang sama di dalam tx penutupan.
+func dayReport(ctx context.Context, q querier, businessDate string, outletID *int, taxRate float64) (*models.DayReport, error) {
+	day, err := time.Parse("2006-01-02", businessDate)
+	if err != nil {
+		return nil, err
+	}
+	from, to := businessDate, day.AddDate(0, 0, 1).Format("2006-01-02")
+
+	rep := models.DayReport{
+		BusinessDate: businessDate,
+		OutletID:     outletID,
+		GeneratedAt:  time.Now(),
+		TaxRate:      taxRate,
+		Payments:     make([]models.PaymentTotal, 0),
+		Refunds:      make([]models.PaymentTotal, 0),
+		PerKasir:     make([]models.CashierTotal, 0),
+		PerOutlet:    make([]models.OutletTotal, 0),
+		PerProduk:    make([]models.ProductTotal, 0),
+		PerModifier:  make([]models.ModifierTotal, 0),
+	}
+
+	err = q.QueryRow(ctx, `
+		SELECT MIN(outlet_day_start($1::date, o.id)), MAX(outlet_day_start($2::date, o.id))
+		FROM outlets o
+		WHERE $3::int IS NULL OR o.id = $3
+	`, from, to, outletID).Scan(&rep.PeriodStart, &rep.PeriodEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	err = q.QueryRow(ctx, `
+		SELECT COALESCE(SUM(t.total_amount), 0), COUNT(*)
+		FROM transactions t
+		WHERE t.business_date >= $1::date AND t.business_date < $2::date AND `+outletFilter+`
+	`, from, to, outletID).Scan(&rep.TotalSales, &rep.TotalTransaksi)
+	if err != nil {
+		return nil, err
+	}
+
+	err = q.QueryRow(ctx, `
+		SELECT COALESCE(SUM(td.quantity), 0)
+		FROM transaction_details td
+		JOIN transactions t ON t.id = td.transaction_id
+		WHERE t.business_date >= $1::date AND t.business_date < $2::date AND `+outletFilter+`
+	`, from, to, outletID).Scan(&rep.ItemTerjual)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := q.Query(ctx, `
+		SELECT tp.method, COALESCE(SUM(tp.amount), 0)
+		FROM transaction_payments tp
+		JOIN transactions t ON t.id = tp.transaction_id
+		WHERE t.business_date >= $1::date AND t.business_date < $2::date AND `+outletFilter+`
+		GROUP BY tp.method
+		ORDER BY tp.method
+	`, from, to, outletID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var p models.PaymentTotal
+		if err := rows.Scan(&p.Method, &p.Amount); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		rep.Payments = append(rep.Payments, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Refund dihitung di hari refund dilakukan, bukan hari transaksi aslinya
+	err = q.QueryRow(ctx, `
+		SELECT COALESCE(SUM(r.total_amount), 0), COUNT(*)
+		FROM refunds r
+		JOIN transactions t ON t.id = r.transaction_id
+		WHERE r.business_date >= $1::date AND r.business_date < $2::date AND `+outletFilter+`
+	`, from, to, outletID).Scan(&rep.TotalRefund, &rep.TotalRefundTrx)
+	if err != nil {
+		return nil, err
+	}
+	rep.NetSales = rep.TotalSales - rep.TotalRefund
+	rep.PPN = includedTax(rep.NetSales, taxRate)
+
+	// Diskon baris yang lewat override tercatat di transaction_details juga, jadi
+	// diskon_override hanya selisih harga satuan normal vs harga override.
+	err = q.QueryRow(ctx, `
+		SELECT
+			COALESCE((SELECT SUM(t.discount_amount) FROM transactions t
+				WHERE t.business_date >= $1::date AND t.business_date < $2::date AND `+outletFilter+`), 0),
+			COALESCE((SELECT SUM(td.discount_amount) FROM transaction_details td
+				JOIN transactions t ON t.id = td.transaction_id
+				WHERE t.business_date >= $1::date AND t.business_date < $2::date AND `+outletFilter+`), 0),
+			COALESCE((SELECT SUM((po.original_price - po.override_price) * po.quantity) FROM price_overrides po
+				JOIN transactions t ON t.id = po.transaction_id
+				WHERE t.business_date >= $1::date AND t.business_date < $2::date AND `+outletFilter+`), 0)
+	`, from, to, outletID).Scan(&rep.DiskonVoucher, &rep.DiskonBaris, &rep.DiskonOverride)
+	if err != nil {
+		return nil, err
+	}
+	rep.TotalDiskon = rep.DiskonVoucher + rep.DiskonBaris + rep.DiskonOverride
+
+	// Void = cart yang dibatalkan; status cancelled final, jadi angkanya tetap setelah Z
+	err = q.QueryRow(ctx, `
+		SELECT COUNT(DISTINCT ca.id), COALESCE(SUM(ci.quantity), 0)
+		FROM carts ca
+		LEFT JOIN cart_items ci ON ci.cart_id = ca.id
+		WHERE ca.status = 'cancelled'
+		  AND outlet_business_date(ca.updated_at, ca.outlet_id) >= $1::date
+		  AND outlet_business_date(ca.updated_at, ca.outlet_id) < $2::date
+		  AND ($3::int IS NULL OR ca.outlet_id = $3)
+	`, from, to, outletID).Scan(&rep.TotalVoid, &rep.ItemVoid)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err = q.Query(ctx, `
+		SELECT rp.method, COALESCE(SUM(rp.amount), 0)
+		FROM refund_payments rp
+		JOIN refunds r ON r.id = rp.refund_id
+		JOIN transactions t ON t.id = r.transaction_id
+		WHERE r.business_date >= $1::date AND r.business_date < $2::date AND `+outletFilter+`
+		GROUP BY rp.method
+		ORDER BY rp.method
+	`, from, to, outletID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var p models.PaymentTotal
+		if err := rows.Scan(&p.Method, &p.Amount); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		rep.Refunds = append(rep.Refunds, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = q.Query(ctx, `
+		SELECT t.cashier_id, COALESCE(c.name, ''), COUNT(*), COALESCE(SUM(t.total_amount), 0)
+		FROM transactions t
+		LEFT JOIN cashiers c ON c.id = t.cashier_id
+		WHERE t.business_date >= $1::date AND t.business_date < $2::date AND `+outletFilter+`
+		GROUP BY t.cashier_id, c.name
+		ORDER BY t.cashier_id NULLS LAST
+	`, from, to, outletID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var c models.CashierTotal
+		if err := rows.Scan(&c.CashierID, &c.Nama, &c.TotalTransaksi, &c.TotalSales); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		rep.PerKasir = append(rep.PerKasir, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if rep.PerOutlet, err = outletTotals(ctx, q, from, to, outletID); err != nil {
+		return nil, err
+	}
+	if rep.PerProduk, err = productTotals(ctx, q, from, to, outletID); err != nil {
+		return nil, err
+	}
+
+	rows, err = q.Query(ctx, `
+		SELECT m.modifier_id, m.name, SUM(m.quantity), SUM(m.price_delta * m.quantity)
+		FROM transaction_detail_modifiers m
+		JOIN transaction_details td ON td.id = m.transaction_detail_id
+		JOIN transactions t ON t.id = td.transaction_id
+		WHERE t.business_date >= $1::date AND t.business_date < $2::date AND `+outletFilter+`
+		GROUP BY m.modifier_id, m.name
+		ORDER BY 3 DESC, m.name
+	`, from, to, outletID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var m models.ModifierTotal
+		if err := rows.Scan(&m.ModifierID, &m.Nama, &m.Qty, &m.Sales); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		rep.PerModifier = append(rep.PerModifier, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &rep, nil
+}
+
+// includedTax = PPN yang sudah termasuk di amount (harga sudah termasuk pajak).
+func includedTax(amount int, rate float64) int {
+	if rate <= 0 {
+		return 0
+	}
+	return int(math.Round(float64(amount) * rate / (100 + rate)))
+}
+
+// outletTotals = penjualan & refund per outlet; outlet tanpa aktivitas di periode tidak ikut.
+func outletTotals(ctx context.Context, q querier, from, to string, outletID *int) ([]models.OutletTotal, error) {
+	rows, err := q.Query(ctx, `
+		WITH sales AS (
+			SELECT t.outlet_id, COUNT(*) AS trx, SUM(t.total_amount) AS amount
+			FROM transactions t
+			WHERE t.business_date >= $1::date AND t.business_date < $2::date AND `+outletFilter+`
+			GROUP BY t.outlet_id
+		), refunded AS (
+			SELECT t.outlet_id, SUM(r.total_amount) AS amount
+			FROM refunds r
+			JOIN transactions t ON t.id = r.transaction_id
+			WHERE r.business_date >= $1::date AND r.business_date < $2::date AND `+outletFilter+`
+			GROUP BY t.outlet_id
+		)
+		SELECT o.id, o.code, o.name, COALESCE(s.trx, 0), COALESCE(s.amount, 0), COALESCE(rf.amount, 0)
+		FROM outlets o
+		LEFT JOIN sales s ON s.outlet_id = o.id
+		LEFT JOIN refunded rf ON rf.outlet_id = o.id
+		WHERE s.outlet_id IS NOT NULL OR rf.outlet_id IS NOT NULL
+		ORDER BY o.id
+	`, from, to, outletID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]models.OutletTotal, 0)
+	for rows.Next() {
+		var o models.OutletTotal
+		if err := rows.Scan(&o.OutletID, &o.Code, &o.Nama, &o.TotalTransaksi, &o.TotalSales, &o.TotalRefund); err != nil {
+			return nil, err
+		}
+		o.NetSales = o.TotalSales - o.TotalRefund
+		out = append(out, o)
+	}
+	return out, rows.Err()
+}
+
+// productTotals = penjualan per produk; baris varian digabung ke produk induknya,
+// paket dipecah ke komponennya. Hanya dipakai dayReport, jadi ikut membaca data mentah
+// (produk yang sudah dihapus tetap muncul, beda dengan daily_product_sales).
+func productTotals(ctx context.Context, q querier, from, to string, outletID *int) ([]models.ProductTotal, error) {
+	rows, err := q.Query(ctx, `
+		SELECT COALESCE(p.parent_id, s.product_id), COALESCE(pp.name, p.name, ''), p.parent_id IS NOT NULL,
+			s.product_id, COALESCE(p.name, ''), SUM(s.quantity), SUM(s.amount)
+		FROM `+productSalesSource+`
+		LEFT JOIN products p ON p.id = s.product_id
+		LEFT JOIN products pp ON pp.id = p.parent_id
+		GROUP BY 1, 2, 3, 4, 5
+		ORDER BY 1, 4
+	`, from, to, outletID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]models.ProductTotal, 0)
+	index := make(map[int]int)
+	for rows.Next() {
+		var parentID int
+		var parentName string
+		var isVariant bool
+		var line models.ProductTotal
+		if err := rows.Scan(&parentID, &parentName, &isVariant, &line.ProductID, &line.Nama, &line.Qty, &line.Sales); err != nil {
+			return nil, err
+		}
+
+		i, ok := index[parentID]
+		if !ok {
+			out = append(out, models.ProductTotal{ProductID: parentID, Nama: parentName})
+			i = len(out) - 1
+			index[parentID] = i
+		}
+		out[i].Qty += line.Qty
+		out[i].Sales += line.Sales
+		if isVariant {
+			out[i].Variants = append(out[i].Variants, line)
+		}
+	}
+	return out, rows.Err()
+}
+
+// IngredientUsage = pemakaian bahan resep: terpakai dari penjualan periode ini,
+// dikurangi yang kembali lewat refund periode ini. groupByOutlet memecah per outlet
+// dengan stok outlet tersebut; selain itu stok = outlet yang difilter / semua outlet.
+func (r *ReportRepository) IngredientUsage(start, end time.Time, outletID *int, groupByOutlet bool) ([]models.IngredientUsage, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	from, to := start.Format("2006-01-02"), end.Format("2006-01-02")
+
+	rows, err := r.db.Query(ctx, `
+		WITH used AS (
+			SELECT CASE WHEN $4::boolean THEN t.outlet_id END AS outlet_id, u.ingredient_id, SUM(u.quantity) AS qty
+			FROM transaction_ingredient_usage u
+			JOIN transactions t ON t.id = u.transaction_id
+			WHERE t.business_date >= $1::date AND t.business_date < $2::date AND `+outletFilter+` AND u.source = 'recipe'
+			GROUP BY 1, 2
+		), returned AS (
+			SELECT CASE WHEN $4::boolean THEN t.outlet_id END AS outlet_id, u.ingredient_id, SUM(u.quantity) AS qty
+			FROM transaction_ingredient_usage u
+			JOIN refunds rf ON rf.transaction_id = u.transaction_id
+			JOIN transactions t ON t.id = u.transaction_id
+			WHERE rf.business_date >= $1::date AND rf.business_date < $2::date AND `+outletFilter+` AND u.source = 'recipe'
+			GROUP BY 1, 2
+		), net_usage AS (
+			SELECT COALESCE(us.outlet_id, rt.outlet_id) AS outlet_id, COALESCE(us.ingredient_id, rt.ingredient_id) AS ingredient_id,
+				COALESCE(us.qty, 0) AS used, COALESCE(rt.qty, 0) AS returned
+			FROM used us
+			FULL JOIN returned rt ON rt.ingredient_id = us.ingredient_id AND rt.outlet_id IS NOT DISTINCT FROM us.outlet_id
+		)
+		SELECT x.outlet_id, COALESCE(o.code, ''), p.id, p.name, p.unit, x.used, x.returned,
+			outlet_stock(p.id, COALESCE(x.outlet_id, $3))
+		FROM net_usage x
+		JOIN products p ON p.id = x.ingredient_id
+		LEFT JOIN outlets o ON o.id = x.outlet_id
+		ORDER BY x.outlet_id NULLS FIRST, x.used - x.returned DESC, p.name
+	`, from, to, outletID, groupByOutlet)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]models.IngredientUsage, 0)
+	for rows.Next() {
+		var u models.IngredientUsage
+		if err := rows.Scan(&u.OutletID, &u.OutletCode, &u.IngredientID, &u.Nama, &u.Unit, &u.Used, &u.Returned, &u.Stock); err != nil {
+			return nil, err
+		}
+		u.Net = u.Used - u.Returned
+		out = append(out, u)
+	}
+	return out, rows.Err()
+}
+
+// ExpiringLots = lot berisi yang kedaluwarsa dalam `days` hari bisnis ke depan
+// (termasuk yang sudah lewat), dihitung dari hari bisnis outlet masing-masing.
+func (r *ReportRepository) ExpiringLots(days int, outletID *int) ([]models.StockLot, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return queryLots(ctx, r.db, `SELECT `+lotColumns+lotFrom+`
+		WHERE l.quantity > 0 AND l.expiry_date IS NOT NULL
+			AND l.expiry_date <= outlet_business_date(now(), l.outlet_id) + $1::int
+			AND ($2::int IS NULL OR l.outlet_id = $2)
+		ORDER BY l.expiry_date, l.outlet_id, p.name, l.id
+	`, days, outletID)
+}
+
+// salesBuckets: baris penjualan per bucket (bucket, amount, trx, items) & deret bucket kosong untuk
+// periode [$1, $2). Hari / minggu / bulan dijumlah dari ringkasan harian; jam butuh jam transaksi, jadi
+// selalu dari data mentah, memakai jam lokal outlet. Deret jam dimulai dari jam tutup buku outlet yang
+// difilter (semua outlet: yang paling awal), bucket di luar deret tetap ikut dari data transaksinya.
+var salesBuckets = map[string]struct{ sales, series string }{
+	models.BucketHour: {
+		sales: `SELECT date_trunc('hour', t.created_at AT TIME ZONE o.timezone) AS bucket, t.total_amount AS amount, 1 AS trx,
+				(SELECT COALESCE(SUM(td.quantity), 0) FROM transaction_details td WHERE td.transaction_id = t.id) AS items
+			FROM transactions t
+			JOIN outlets o ON o.id = t.outlet_id
+			WHERE t.business_date >= $1::date AND t.business_date < $2::date AND ` + outletFilter,
+		series: `SELECT generate_series($1::date + c.h * interval '1 hour', $2::date + (c.h - 1) * interval '1 hour', interval '1 hour')
+			FROM (SELECT COALESCE((SELECT day_cutoff_hour FROM outlets WHERE id = $3), (SELECT MIN(day_cutoff_hour) FROM outlets), 0) AS h) c`,
+	},
+	models.BucketDay: {
+		sales:  `SELECT ds.business_date::timestamp AS bucket, ds.total_amount AS amount, ds.transactions AS trx, ds.items FROM ` + dailySalesSource,
+		series: `SELECT generate_series($1::date::timestamp, $2::date - interval '1 day', interval '1 day')`,
+	},
+	models.BucketWeek: {
+		sales:  `SELECT date_trunc('week', ds.business_date::timestamp) AS bucket, ds.total_amount AS amount, ds.transactions AS trx, ds.items FROM ` + dailySalesSource,
+		series: `SELECT generate_series(date_trunc('week', $1::date::timestamp), $2::date - interval '1 day', interval '1 week')`,
+	},
+	models.BucketMonth: {
+		sales:  `SELECT date_trunc('month', ds.business_date::timestamp) AS bucket, ds.total_amount AS amount, ds.transactions AS trx, ds.items FROM ` + dailySalesSource,
+		series: `SELECT generate_series(date_trunc('month', $1::date::timestamp), $2::date - interval '1 day', interval '1 month')`,
+	},
+}
+
+// SalesSeries = penjualan hari bisnis [start, end) per bucket waktu, bucket tanpa transaksi tetap muncul (0).
+// Angkanya sama dengan /api/report: total transaksi sebelum refund.
+func (r *ReportRepository) SalesSeries(granularity string, start, end time.Time, outletID *int) ([]models.SalesPoint, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	b, ok := salesBuckets[granularity]
+	if !ok {
+		return nil, fmt.Errorf("granularity %q tidak dikenal", granularity)
+	}
+	from, to := start.Format("2006-01-02"), end.Format("2006-01-02")
+
+	rows, err := r.db.Query(ctx, `
+		WITH sales AS (
+			`+b.sales+`
+		), buckets AS (
+			`+b.series+`
+			UNION
+			SELECT bucket FROM sales
+		)
+		SELECT b.bucket, COALESCE(SUM(s.amount), 0)::bigint, COALESCE(SUM(s.trx), 0)::bigint, COALESCE(SUM(s.items), 0)::bigint
+		FROM buckets b
+		LEFT JOIN sales s ON s.bucket = b.bucket
+		GROUP BY b.bucket
+		ORDER BY b.bucket
+	`, from, to, outletID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	layout := "2006-01-02"
+	if granularity == models.BucketHour {
+		layout = "2006-01-02T15:04"
+	}
+	out := make([]models.SalesPoint, 0)
+	for rows.Next() {
+		var bucket time.Time
+		var p models.SalesPoint
+		if err := rows.Scan(&bucket, &p.TotalSales, &p.TotalTransaksi, &p.ItemTerjual); err != nil {
+			return nil, err
+		}
+		p.Bucket = bucket.Format(layout)
+		if p.TotalTransaksi > 0 {
+			p.AvgBasket = p.TotalSales / p.TotalTransaksi
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+// rankOrder = urutan ranking produk per kriteria; bottom membalik arahnya.
+var rankOrder = map[string][2]string{
+	models.RankByQty:     {`c.qty DESC, c.sales DESC`, `c.qty, c.sales`},
+	models.RankByRevenue: {`c.sales DESC, c.qty DESC`, `c.sales, c.qty`},
+}
+
+// ProductRanking = N produk terlaris / paling tidak laku. Varian digabung ke induknya dan paket
+// dipecah ke komponennya, seperti produk_terlaris. Untuk bottom, produk jual yang tidak terjual
+// sama sekali (bukan paket / bahan resep, dan punya stok di outlet yang difilter) ikut dengan qty 0.
+func (r *ReportRepository) ProductRanking(start, end time.Time, outletID *int, sortBy string, bottom bool, limit int) ([]models.RankedProduct, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	order, ok := rankOrder[sortBy]
+	if !ok {
+		return nil, fmt.Errorf("sort_by %q tidak dikenal", sortBy)
+	}
+	orderBy := order[0]
+	if bottom {
+		orderBy = order[1]
+	}
+	from, to := start.Format("2006-01-02"), end.Format("2006-01-02")
+
+	rows, err := r.db.Query(ctx, `
+		WITH sold AS (
+			SELECT COALESCE(p.parent_id, p.id) AS product_id, SUM(s.quantity)::bigint AS qty, SUM(s.amount)::bigint AS sales
+			FROM `+productDaySource+`
+			JOIN products p ON p.id = s.product_id
+			GROUP BY 1
+		), candidates AS (
+			SELECT product_id, qty, sales FROM sold
+			UNION ALL
+			SELECT p.id, 0, 0
+			FROM products p
+			WHERE $4 AND p.parent_id IS NULL
+				AND NOT EXISTS (SELECT 1 FROM sold WHERE sold.product_id = p.id)
+				AND NOT EXISTS (SELECT 1 FROM bundle_items b WHERE b.bundle_id = p.id)
+				AND NOT EXISTS (SELECT 1 FROM product_recipes r WHERE r.ingredient_id = p.id)
+				AND ($3::int IS NULL OR EXISTS (
+					SELECT 1 FROM outlet_products op JOIN products x ON x.id = op.product_id
+					WHERE op.outlet_id = $3 AND COALESCE(x.parent_id, x.id) = p.id))
+		)
+		SELECT c.product_id, p.name, c.qty, c.sales
+		FROM candidates c
+		JOIN products p ON p.id = c.product_id
+		ORDER BY `+orderBy+`, p.name
+		LIMIT $5
+	`, from, to, outletID, bottom, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]models.RankedProduct, 0, limit)
+	for rows.Next() {
+		p := models.RankedProduct{Rank: len(out) + 1}
+		if err := rows.Scan(&p.ProductID, &p.Nama, &p.Qty, &p.Sales); err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+// salesBreakdownQueries: (id, nama, transaksi, item, sales) per kategori / kasir / metode bayar.
+// Kategori memakai subtotal baris (sebelum diskon voucher), kasir total transaksi, metode bayar
+// nominal tender (cash sudah dikurangi kembalian). Tetap dari data mentah: ringkasan harian tidak
+// menyimpan kasir / tender, dan jumlah transaksi per kategori butuh transaction_id.
+var salesBreakdownQueries = map[string]string{
+	models.BreakdownCategory: `
+		SELECT c.id, COALESCE(c.name, 'Tanpa kategori'), COUNT(DISTINCT s.transaction_id), SUM(s.quantity), SUM(s.amount)
+		FROM ` + productSalesSource + `
+		JOIN products p ON p.id = s.product_id
+		LEFT JOIN products pp ON pp.id = p.parent_id
+		LEFT JOIN categories c ON c.id = COALESCE(p.category_id, pp.category_id)
+		GROUP BY c.id, c.name
+		ORDER BY 5 DESC, 2`,
+	models.BreakdownCashier: `
+		SELECT t.cashier_id, COALESCE(c.name, 'Tanpa kasir'), COUNT(*),
+			COALESCE(SUM((SELECT SUM(td.quantity) FROM transaction_details td WHERE td.transaction_id = t.id)), 0)::bigint,
+			SUM(t.total_amount)
+		FROM transactions t
+		LEFT JOIN cashiers c ON c.id = t.cashier_id
+		WHERE t.business_date >= $1::date AND t.business_date < $2::date AND ` + outletFilter + `
+		GROUP BY t.cashier_id, c.name
+		ORDER BY 5 DESC, 2`,
+	models.BreakdownPayment: `
+		SELECT NULL::int, tp.method, COUNT(DISTINCT tp.transaction_id), 0, SUM(tp.amount)
+		FROM transaction_payments tp
+		JOIN transactions t ON t.id = tp.transaction_id
+		WHERE t.business_date >= $1::date AND t.business_date < $2::date AND ` + outletFilter + `
+		GROUP BY tp.method
+		ORDER BY 5 DESC, 2`,
+}
+
+// SalesBreakdown = penjualan hari bisnis [start, end) per kategori, kasir atau metode bayar.
+func (r *ReportRepository) SalesBreakdown(by string, start, end time.Time, outletID *int) ([]models.BreakdownLine, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	query, ok := salesBreakdownQueries[by]
+	if !ok {
+		return nil, fmt.Errorf("breakdown %q tidak dikenal", by)
+	}
+	rows, err := r.db.Query(ctx, query, start.Format("2006-01-02"), end.Format("2006-01-02"), outletID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]models.BreakdownLine, 0)
+	for rows.Next() {
+		var l models.BreakdownLine
+		if err := rows.Scan(&l.ID, &l.Nama, &l.TotalTransaksi, &l.ItemTerjual, &l.TotalSales); err != nil {
+			return nil, err
+		}
+		out = append(out, l)
+	}
+	return out, rows.Err()
+}
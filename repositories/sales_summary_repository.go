@@ -0,0 +1,177 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// SalesSummaryRepository memelihara daily_sales & daily_product_sales. Per outlet, semua hari
+// bisnis sebelum sales_summary_state.summarized_until sudah diringkas; hari berjalan tidak pernah
+// diringkas karena masih bisa bertambah transaksi.
+type SalesSummaryRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewSalesSummaryRepository(db *pgxpool.Pool) *SalesSummaryRepository {
+	return &SalesSummaryRepository{db: db}
+}
+
+// Refresh meringkas hari bisnis yang sudah lewat di semua outlet, mengembalikan jumlah
+// hari-outlet yang ditulis.
+func (r *SalesSummaryRepository) Refresh() (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	return r.eachOutlet(ctx, nil, false)
+}
+
+// Rebuild menghapus lalu menghitung ulang ringkasan mulai hari bisnis from (nil = semua data).
+func (r *SalesSummaryRepository) Rebuild(from *time.Time) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	return r.eachOutlet(ctx, from, true)
+}
+
+func (r *SalesSummaryRepository) eachOutlet(ctx context.Context, from *time.Time, reset bool) (int, error) {
+	rows, err := r.db.Query(ctx, `SELECT id FROM outlets ORDER BY id`)
+	if err != nil {
+		return 0, err
+	}
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for _, id := range ids {
+		n, err := r.summarizeOutlet(ctx, id, from, reset)
+		if err != nil {
+			return total, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// summarizeOutlet: satu tx per outlet, baris state di-lock supaya job & rebuild tidak bertabrakan.
+func (r *SalesSummaryRepository) summarizeOutlet(ctx context.Context, outletID int, from *time.Time, reset bool) (int, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `INSERT INTO sales_summary_state (outlet_id) VALUES ($1) ON CONFLICT (outlet_id) DO NOTHING`, outletID)
+	if err != nil {
+		return 0, err
+	}
+	var until *time.Time
+	var today time.Time
+	err = tx.QueryRow(ctx,
+		`SELECT summarized_until, outlet_business_date(now(), outlet_id) FROM sales_summary_state WHERE outlet_id = $1 FOR UPDATE`,
+		outletID,
+	).Scan(&until, &today)
+	if err != nil {
+		return 0, err
+	}
+
+	// Lanjut dari hari terakhir yang diringkas, diringkas ulang karena checkout yang mulai sebelum
+	// jam tutup buku bisa commit sesudahnya. Belum pernah / rebuild penuh: dari transaksi pertama outlet.
+	var start time.Time
+	if until != nil && !(reset && from == nil) {
+		start = until.AddDate(0, 0, -1)
+	} else {
+		var first *time.Time
+		if err := tx.QueryRow(ctx, `SELECT MIN(business_date) FROM transactions WHERE outlet_id = $1`, outletID).Scan(&first); err != nil {
+			return 0, err
+		}
+		start = today
+		if first != nil && first.Before(today) {
+			start = *first
+		}
+	}
+	if reset {
+		if from != nil && from.Before(start) {
+			start = *from
+		}
+		if from == nil {
+			if err := deleteSummaries(ctx, tx, outletID, nil, nil); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	days := 0
+	if start.Before(today) {
+		if err := summarizeDays(ctx, tx, outletID, start, today); err != nil {
+			return 0, err
+		}
+		days = int(today.Sub(start).Hours()/24 + 0.5)
+	}
+	_, err = tx.Exec(ctx,
+		`UPDATE sales_summary_state SET summarized_until = $2, updated_at = now() WHERE outlet_id = $1`,
+		outletID, today,
+	)
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return days, nil
+}
+
+// deleteSummaries menghapus ringkasan outlet di hari bisnis [from, to); nil = tanpa batas.
+func deleteSummaries(ctx context.Context, q querier, outletID int, from, to *time.Time) error {
+	for _, table := range []string{"daily_sales", "daily_product_sales"} {
+		_, err := q.Exec(ctx,
+			`DELETE FROM `+table+` WHERE outlet_id = $1
+			 AND ($2::date IS NULL OR business_date >= $2) AND ($3::date IS NULL OR business_date < $3)`,
+			outletID, from, to,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// summarizeDays menulis ulang ringkasan outlet untuk hari bisnis [start, end) dari data transaksi.
+func summarizeDays(ctx context.Context, q querier, outletID int, start, end time.Time) error {
+	if err := deleteSummaries(ctx, q, outletID, &start, &end); err != nil {
+		return err
+	}
+	from, to := start.Format("2006-01-02"), end.Format("2006-01-02")
+
+	_, err := q.Exec(ctx, `
+		INSERT INTO daily_sales (business_date, outlet_id, transactions, total_amount, items)
+		SELECT t.business_date, t.outlet_id, COUNT(*), SUM(t.total_amount), SUM(i.items)
+		FROM transactions t
+		CROSS JOIN LATERAL (SELECT COALESCE(SUM(quantity), 0) AS items FROM transaction_details WHERE transaction_id = t.id) i
+		WHERE t.business_date >= $1::date AND t.business_date < $2::date AND t.outlet_id = $3
+		GROUP BY t.business_date, t.outlet_id
+	`, from, to, outletID)
+	if err != nil {
+		return err
+	}
+	_, err = q.Exec(ctx, `
+		INSERT INTO daily_product_sales (business_date, outlet_id, product_id, quantity, amount)
+		SELECT s.business_date, s.outlet_id, s.product_id, SUM(s.quantity), SUM(s.amount)
+		FROM `+productSalesSource+`
+		WHERE s.product_id IS NOT NULL AND EXISTS (SELECT 1 FROM products p WHERE p.id = s.product_id)
+		GROUP BY s.business_date, s.outlet_id, s.product_id
+	`, from, to, outletID)
+	return err
+}
@@ -0,0 +1,108 @@
+package repositories
+
+import (
+	"context"
+	"kasir-api/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type SerialRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewSerialRepository(db *pgxpool.Pool) *SerialRepository {
+	return &SerialRepository{db: db}
+}
+
+const serialColumns = `s.id, s.product_id, p.name, s.serial_number, s.status, s.outlet_id, o.code, s.received_at`
+
+const serialFrom = ` FROM product_serials s JOIN products p ON p.id = s.product_id JOIN outlets o ON o.id = s.outlet_id`
+
+func scanSerial(row pgx.Row) (*models.ProductSerial, error) {
+	var s models.ProductSerial
+	err := row.Scan(&s.ID, &s.ProductID, &s.ProductName, &s.SerialNumber, &s.Status, &s.OutletID, &s.OutletCode, &s.ReceivedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func querySerials(ctx context.Context, q querier, query string, args ...any) ([]models.ProductSerial, error) {
+	rows, err := q.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]models.ProductSerial, 0)
+	for rows.Next() {
+		s, err := scanSerial(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *s)
+	}
+	return out, rows.Err()
+}
+
+// GetAll = daftar unit ber-serial (maks. 500), mis. stok unit satu produk di satu outlet.
+func (r *SerialRepository) GetAll(f models.SerialFilter) ([]models.ProductSerial, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return querySerials(ctx, r.db, `SELECT `+serialColumns+serialFrom+`
+		WHERE ($1::int IS NULL OR s.outlet_id = $1) AND ($2 = 0 OR s.product_id = $2) AND ($3 = '' OR s.status = $3)
+		ORDER BY s.product_id, s.serial_number
+		LIMIT 500
+	`, f.OutletID, f.ProductID, f.Status)
+}
+
+// Lookup mencari serial number (persis, bisa cocok di lebih dari satu produk) lengkap dengan riwayatnya.
+func (r *SerialRepository) Lookup(serialNumber string) ([]models.ProductSerial, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	out, err := querySerials(ctx, r.db, `SELECT `+serialColumns+serialFrom+`
+		WHERE s.serial_number = $1
+		ORDER BY s.id
+	`, serialNumber)
+	if err != nil || len(out) == 0 {
+		return out, err
+	}
+
+	ids := make([]int, 0, len(out))
+	byID := make(map[int]*models.ProductSerial, len(out))
+	for i := range out {
+		ids = append(ids, out[i].ID)
+		byID[out[i].ID] = &out[i]
+	}
+
+	rows, err := r.db.Query(ctx, `
+		SELECT e.serial_id, e.kind, e.outlet_id, o.code, e.transaction_id, COALESCE(t.receipt_no, ''),
+			t.customer_id, COALESCE(cu.name, ''), e.goods_receipt_id, e.created_at
+		FROM serial_events e
+		JOIN outlets o ON o.id = e.outlet_id
+		LEFT JOIN transactions t ON t.id = e.transaction_id
+		LEFT JOIN customers cu ON cu.id = t.customer_id
+		WHERE e.serial_id = ANY($1)
+		ORDER BY e.id
+	`, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var serialID int
+		var e models.SerialEvent
+		err := rows.Scan(&serialID, &e.Kind, &e.OutletID, &e.OutletCode, &e.TransactionID, &e.ReceiptNo,
+			&e.CustomerID, &e.CustomerName, &e.GoodsReceiptID, &e.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		byID[serialID].Events = append(byID[serialID].Events, e)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,400 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"kasir-api/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ShiftRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewShiftRepository(db *pgxpool.Pool) *ShiftRepository {
+	return &ShiftRepository{db: db}
+}
+
+const shiftColumns = `s.id, s.cashier_id, c.name, s.outlet_id, o.code, s.terminal, s.status, s.opening_float,
+	s.opened_at, s.closed_at, s.expected_cash, s.counted_cash, s.over_short, s.notes`
+
+const shiftFrom = ` FROM shifts s JOIN cashiers c ON c.id = s.cashier_id JOIN outlets o ON o.id = s.outlet_id`
+
+func scanShift(row pgx.Row) (*models.Shift, error) {
+	var s models.Shift
+	err := row.Scan(&s.ID, &s.CashierID, &s.CashierName, &s.OutletID, &s.OutletCode, &s.Terminal, &s.Status, &s.OpeningFloat,
+		&s.OpenedAt, &s.ClosedAt, &s.ExpectedCash, &s.CountedCash, &s.OverShort, &s.Notes)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *ShiftRepository) Open(req models.OpenShiftRequest) (*models.Shift, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var active bool
+	var homeOutlet *int
+	err = tx.QueryRow(ctx, `SELECT active, outlet_id FROM cashiers WHERE id=$1`, req.CashierID).Scan(&active, &homeOutlet)
+	if err != nil {
+		return nil, errors.New("kasir belum ada")
+	}
+	if !active {
+		return nil, errors.New("kasir tidak aktif")
+	}
+
+	// Kasir yang terikat outlet hanya boleh buka shift di outletnya
+	if req.OutletID == nil {
+		req.OutletID = homeOutlet
+	}
+	if homeOutlet != nil && *req.OutletID != *homeOutlet {
+		return nil, errors.New("kasir terdaftar di outlet lain")
+	}
+	outletID, _, err := resolveOutlet(ctx, tx, req.OutletID)
+	if err != nil {
+		return nil, err
+	}
+
+	var openID int
+	err = tx.QueryRow(ctx,
+		`SELECT id FROM shifts WHERE cashier_id=$1 AND status='open'`,
+		req.CashierID,
+	).Scan(&openID)
+	if err == nil {
+		return nil, fmt.Errorf("kasir masih punya shift terbuka (shift_id=%d)", openID)
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+
+	var id int
+	err = tx.QueryRow(ctx,
+		`INSERT INTO shifts (cashier_id, outlet_id, terminal, opening_float) VALUES ($1,$2,$3,$4) RETURNING id`,
+		req.CashierID, outletID, req.Terminal, req.OpeningFloat,
+	).Scan(&id)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := scanShift(tx.QueryRow(ctx,
+		`SELECT `+shiftColumns+shiftFrom+` WHERE s.id=$1`, id))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (r *ShiftRepository) GetAll(status string) ([]models.Shift, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	query := `SELECT ` + shiftColumns + shiftFrom
+	args := []any{}
+
+	if status != "" {
+		query += ` WHERE s.status = $1`
+		args = append(args, status)
+	}
+
+	query += ` ORDER BY s.id DESC`
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]models.Shift, 0)
+	for rows.Next() {
+		s, err := scanShift(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *s)
+	}
+	return out, nil
+}
+
+func (r *ShiftRepository) GetByID(id int) (*models.Shift, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return getShift(ctx, r.db, id, false)
+}
+
+func getShift(ctx context.Context, q querier, id int, forUpdate bool) (*models.Shift, error) {
+	query := `SELECT ` + shiftColumns + shiftFrom + ` WHERE s.id=$1`
+	if forUpdate {
+		query += ` FOR UPDATE OF s`
+	}
+
+	s, err := scanShift(q.QueryRow(ctx, query, id))
+	if err != nil {
+		return nil, errors.New("shift belum ada")
+	}
+
+	rows, err := q.Query(ctx,
+		`SELECT denomination, quantity FROM shift_cash_counts WHERE shift_id=$1 ORDER BY denomination DESC`,
+		id,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cc models.CashCount
+		if err := rows.Scan(&cc.Denomination, &cc.Quantity); err != nil {
+			return nil, err
+		}
+		s.CashCounts = append(s.CashCounts, cc)
+	}
+	return s, rows.Err()
+}
+
+func (r *ShiftRepository) AddCashMovement(m *models.CashMovement) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock shift supaya tidak bisa ditutup bersamaan
+	var status string
+	err = tx.QueryRow(ctx, `SELECT status FROM shifts WHERE id=$1 FOR SHARE`, m.ShiftID).Scan(&status)
+	if err != nil {
+		return errors.New("shift belum ada")
+	}
+	if status != models.ShiftOpen {
+		return errors.New("shift sudah ditutup")
+	}
+
+	err = tx.QueryRow(ctx,
+		`INSERT INTO cash_movements (shift_id, type, amount, reason) VALUES ($1,$2,$3,$4)
+		 RETURNING id, created_at`,
+		m.ShiftID, m.Type, m.Amount, m.Reason,
+	).Scan(&m.ID, &m.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *ShiftRepository) GetCashMovements(shiftID int) ([]models.CashMovement, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := r.db.Query(ctx,
+		`SELECT id, shift_id, type, amount, reason, created_at
+		 FROM cash_movements WHERE shift_id=$1 ORDER BY id`,
+		shiftID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]models.CashMovement, 0)
+	for rows.Next() {
+		var m models.CashMovement
+		if err := rows.Scan(&m.ID, &m.ShiftID, &m.Type, &m.Amount, &m.Reason, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	return out, nil
+}
+
+func (r *ShiftRepository) Summary(id int) (*models.ShiftSummary, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	s, err := getShift(ctx, r.db, id, false)
+	if err != nil {
+		return nil, err
+	}
+	return shiftSummary(ctx, r.db, s)
+}
+
+// shiftSummary menghitung kas seharusnya:
+// modal awal + penjualan cash (net kembalian) - refund cash + cicilan kasbon cash
+// + penjualan/top-up gift card cash + pay-in - pay-out.
+func shiftSummary(ctx context.Context, q querier, s *models.Shift) (*models.ShiftSummary, error) {
+	sum := models.ShiftSummary{
+		Shift:        *s,
+		OpeningFloat: s.OpeningFloat,
+		Payments:     make([]models.PaymentTotal, 0),
+		CountedCash:  s.CountedCash,
+		OverShort:    s.OverShort,
+	}
+
+	err := q.QueryRow(ctx, `
+		SELECT COUNT(*), COALESCE(SUM(total_amount), 0)
+		FROM transactions
+		WHERE shift_id = $1
+	`, s.ID).Scan(&sum.TotalTransaksi, &sum.TotalSales)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := q.Query(ctx, `
+		SELECT tp.method, COALESCE(SUM(tp.amount), 0)
+		FROM transaction_payments tp
+		JOIN transactions t ON t.id = tp.transaction_id
+		WHERE t.shift_id = $1
+		GROUP BY tp.method
+		ORDER BY tp.method
+	`, s.ID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var p models.PaymentTotal
+		if err := rows.Scan(&p.Method, &p.Amount); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if p.Method == models.PaymentCash {
+			sum.CashSales = p.Amount
+		}
+		sum.Payments = append(sum.Payments, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	err = q.QueryRow(ctx, `
+		SELECT
+			COALESCE(SUM(amount) FILTER (WHERE type = 'pay_in'), 0),
+			COALESCE(SUM(amount) FILTER (WHERE type = 'pay_out'), 0)
+		FROM cash_movements
+		WHERE shift_id = $1
+	`, s.ID).Scan(&sum.PayIns, &sum.PayOuts)
+	if err != nil {
+		return nil, err
+	}
+
+	err = q.QueryRow(ctx, `
+		SELECT
+			COALESCE(SUM(r.total_amount), 0),
+			COALESCE((SELECT SUM(rp.amount) FROM refund_payments rp
+				JOIN refunds r2 ON r2.id = rp.refund_id
+				WHERE r2.shift_id = $1 AND rp.method = 'cash'), 0)
+		FROM refunds r
+		WHERE r.shift_id = $1
+	`, s.ID).Scan(&sum.TotalRefund, &sum.CashRefunds)
+	if err != nil {
+		return nil, err
+	}
+
+	err = q.QueryRow(ctx, `
+		SELECT
+			COALESCE(-SUM(amount), 0),
+			COALESCE(-SUM(amount) FILTER (WHERE method = 'cash'), 0)
+		FROM receivable_entries
+		WHERE shift_id = $1 AND type = 'payment'
+	`, s.ID).Scan(&sum.DebtPayments, &sum.CashDebtPayments)
+	if err != nil {
+		return nil, err
+	}
+
+	err = q.QueryRow(ctx, `
+		SELECT
+			COALESCE(SUM(amount), 0),
+			COALESCE(SUM(amount) FILTER (WHERE method = 'cash'), 0)
+		FROM gift_card_entries
+		WHERE shift_id = $1 AND type IN ('issue', 'topup')
+	`, s.ID).Scan(&sum.GiftCardSales, &sum.CashGiftCardSales)
+	if err != nil {
+		return nil, err
+	}
+
+	sum.ExpectedCash = sum.OpeningFloat + sum.CashSales - sum.CashRefunds + sum.CashDebtPayments +
+		sum.CashGiftCardSales + sum.PayIns - sum.PayOuts
+	return &sum, nil
+}
+
+func (r *ShiftRepository) Close(id int, req models.CloseShiftRequest) (*models.ShiftSummary, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// ✅ Lock shift: checkout & pay-in/out yang sedang jalan ditunggu dulu
+	s, err := getShift(ctx, tx, id, true)
+	if err != nil {
+		return nil, err
+	}
+	if s.Status != models.ShiftOpen {
+		return nil, errors.New("shift sudah ditutup")
+	}
+
+	counted := 0
+	for _, cc := range req.CashCounts {
+		counted += cc.Denomination * cc.Quantity
+		_, err := tx.Exec(ctx,
+			`INSERT INTO shift_cash_counts (shift_id, denomination, quantity) VALUES ($1,$2,$3)`,
+			id, cc.Denomination, cc.Quantity,
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sum, err := shiftSummary(ctx, tx, s)
+	if err != nil {
+		return nil, err
+	}
+	overShort := counted - sum.ExpectedCash
+
+	err = tx.QueryRow(ctx,
+		`UPDATE shifts
+		 SET status='closed', closed_at=now(), expected_cash=$1, counted_cash=$2, over_short=$3, notes=$4
+		 WHERE id=$5
+		 RETURNING closed_at`,
+		sum.ExpectedCash, counted, overShort, req.Notes, id,
+	).Scan(&s.ClosedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	s.Status = models.ShiftClosed
+	s.ExpectedCash = &sum.ExpectedCash
+	s.CountedCash = &counted
+	s.OverShort = &overShort
+	s.Notes = req.Notes
+	s.CashCounts = req.CashCounts
+
+	sum.Shift = *s
+	sum.CountedCash = &counted
+	sum.OverShort = &overShort
+	return sum, nil
+}
@@ -0,0 +1,441 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"kasir-api/models"
+	"sort"
+)
+
+// availableStock = stok yang bisa dijual untuk produk alias p di outlet (ekspresi SQL,
+// NULL = semua outlet): paket = paket yang bisa dirakit dari komponennya (komponen komposit
+// dihitung dari bahannya), komposit = porsi dari bahan, selain itu stok sendiri.
+func availableStock(outlet string) string {
+	stock := func(alias string) string { return `outlet_stock(` + alias + `.id, ` + outlet + `)` }
+	return `COALESCE(
+		(SELECT MIN(COALESCE(
+			(SELECT MIN(` + stock("i") + ` / r.quantity) FROM product_recipes r JOIN products i ON i.id = r.ingredient_id WHERE r.product_id = c.id),
+			` + stock("c") + `) / b.quantity)
+		 FROM bundle_items b JOIN products c ON c.id = b.product_id
+		 WHERE b.bundle_id = p.id),
+		(SELECT MIN(` + stock("i") + ` / r.quantity) FROM product_recipes r JOIN products i ON i.id = r.ingredient_id WHERE r.product_id = p.id),
+		` + stock("p") + `)`
+}
+
+// stockPlan mengumpulkan semua pemotongan stok satu checkout (barang, modifier, bahan resep)
+// di outlet shift. Baris stok di-lock sekaligus urut product_id sebelum dipakai, jadi dua
+// checkout yang menyentuh produk/bahan yang sama selalu mengunci dengan urutan yang sama.
+type stockPlan struct {
+	outletID   int
+	need       map[int]int
+	ingredient map[int]bool          // produk ini (juga) dipotong sebagai bahan resep
+	low        []models.LowStockItem // produk yang stoknya baru turun melewati reorder point
+	lots       []lotUse              // lot yang dipotong (produk track_lots)
+	serials    map[int][]string      // serial yang dijual per produk (produk track_serials)
+}
+
+func newStockPlan(outletID int) *stockPlan {
+	return &stockPlan{outletID: outletID, need: make(map[int]int), ingredient: make(map[int]bool), serials: make(map[int][]string)}
+}
+
+func (p *stockPlan) addSerials(productID int, serials []string) {
+	p.serials[productID] = append(p.serials[productID], serials...)
+}
+
+func (p *stockPlan) add(productID, qty int) { p.need[productID] += qty }
+
+func (p *stockPlan) addIngredient(productID, qty int) {
+	p.need[productID] += qty
+	p.ingredient[productID] = true
+}
+
+// lockStock mengunci baris stok produk di satu outlet FOR UPDATE urut product_id.
+func lockStock(ctx context.Context, q querier, outletID int, ids []int) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := q.Exec(ctx,
+		`SELECT product_id FROM outlet_products WHERE outlet_id = $1 AND product_id = ANY($2)
+		 ORDER BY product_id FOR UPDATE`,
+		outletID, ids,
+	)
+	return err
+}
+
+// apply mengecek stok lalu memotongnya, per produk urut id. Baris stok harus sudah di-lock;
+// produk yang belum punya baris di outlet ini dianggap stok 0.
+func (p *stockPlan) apply(ctx context.Context, q querier) error {
+	ids := make([]int, 0, len(p.need))
+	for id := range p.need {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	for id := range p.serials {
+		if _, ok := p.need[id]; !ok {
+			return fmt.Errorf("serial_numbers hanya untuk produk ber-serial (product_id=%d)", id)
+		}
+	}
+
+	for _, id := range ids {
+		var name, unit string
+		var stock, leadTime int
+		var reorderPoint *int
+		var avgDaily *float64
+		var target *int
+		var trackLots, trackSerials bool
+		err := q.QueryRow(ctx,
+			`SELECT p.name, p.unit, COALESCE(op.stock, 0), p.reorder_point, p.lead_time_days,
+				rs.avg_daily_sales::float8, rs.target_stock, p.track_lots, p.track_serials
+			 FROM products p
+			 LEFT JOIN outlet_products op ON op.product_id = p.id AND op.outlet_id = $2
+			 LEFT JOIN stock_reorder_suggestions rs ON rs.product_id = p.id AND rs.outlet_id = $2
+			 WHERE p.id = $1`,
+			id, p.outletID,
+		).Scan(&name, &unit, &stock, &reorderPoint, &leadTime, &avgDaily, &target, &trackLots, &trackSerials)
+		if err != nil {
+			return fmt.Errorf("product id %d not found", id)
+		}
+		need := p.need[id]
+		if stock < need {
+			if p.ingredient[id] {
+				return fmt.Errorf("bahan tidak cukup: %s (stok=%d%s, butuh=%d%s)", name, stock, unit, need, unit)
+			}
+			return fmt.Errorf("stok tidak cukup untuk %s (stok=%d, qty=%d)", name, stock, need)
+		}
+		if trackLots {
+			uses, err := takeLots(ctx, q, p.outletID, id, name, need)
+			if err != nil {
+				return err
+			}
+			p.lots = append(p.lots, uses...)
+		}
+		if trackSerials {
+			if err := takeSerials(ctx, q, p.outletID, id, name, need, p.serials[id]); err != nil {
+				return err
+			}
+		} else if len(p.serials[id]) > 0 {
+			return fmt.Errorf("%s tidak ber-serial, kosongkan serial_numbers", name)
+		}
+		_, err = q.Exec(ctx,
+			`UPDATE outlet_products SET stock = stock - $1 WHERE outlet_id = $2 AND product_id = $3`,
+			need, p.outletID, id,
+		)
+		if err != nil {
+			return err
+		}
+
+		if rp := reorderPoint; rp != nil && stock > *rp && stock-need <= *rp {
+			item := models.LowStockItem{
+				OutletID:     p.outletID,
+				ProductID:    id,
+				ProductName:  name,
+				Unit:         unit,
+				Stock:        stock - need,
+				ReorderPoint: *rp,
+				LeadTimeDays: leadTime,
+			}
+			if avgDaily != nil {
+				item.AvgDailySales = *avgDaily
+			}
+			if target != nil && *target > item.Stock {
+				item.SuggestedQty = *target - item.Stock
+			}
+			p.low = append(p.low, item)
+		}
+	}
+	return nil
+}
+
+// lotUse = quantity yang diambil dari satu lot.
+type lotUse struct {
+	lotID     int
+	productID int
+	qty       int
+}
+
+// takeLots memotong need dari lot produk di outlet, yang paling cepat kedaluwarsa dulu (FEFO).
+// Lot yang sudah lewat tanggal kedaluwarsa (hari bisnis outlet) tidak boleh dijual.
+// Baris stok produk harus sudah di-lock, jadi lock lot selalu di bawah lock stoknya.
+func takeLots(ctx context.Context, q querier, outletID, productID int, name string, need int) ([]lotUse, error) {
+	rows, err := q.Query(ctx,
+		`SELECT id, quantity, COALESCE(expiry_date < outlet_business_date(now(), $1), FALSE)
+		 FROM stock_lots
+		 WHERE outlet_id = $1 AND product_id = $2 AND quantity > 0
+		 ORDER BY expiry_date NULLS LAST, id
+		 FOR UPDATE`,
+		outletID, productID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	type lot struct {
+		id, quantity int
+		expired      bool
+	}
+	lots := make([]lot, 0)
+	for rows.Next() {
+		var l lot
+		if err := rows.Scan(&l.id, &l.quantity, &l.expired); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		lots = append(lots, l)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	uses := make([]lotUse, 0)
+	left, expired := need, 0
+	for _, l := range lots {
+		if l.expired {
+			expired += l.quantity
+			continue
+		}
+		if left == 0 {
+			break
+		}
+		take := min(l.quantity, left)
+		uses = append(uses, lotUse{lotID: l.id, productID: productID, qty: take})
+		left -= take
+	}
+	if left > 0 {
+		if expired > 0 {
+			return nil, fmt.Errorf("stok %s yang belum kedaluwarsa tidak cukup (kurang %d, %d kedaluwarsa)", name, left, expired)
+		}
+		return nil, fmt.Errorf("stok lot %s tidak cukup (kurang %d)", name, left)
+	}
+
+	for _, u := range uses {
+		if _, err := q.Exec(ctx, `UPDATE stock_lots SET quantity = quantity - $1 WHERE id = $2`, u.qty, u.lotID); err != nil {
+			return nil, err
+		}
+	}
+	return uses, nil
+}
+
+// upsertLot menambah quantity lot (dibuat kalau belum ada) dan mengembalikan id-nya.
+// Tanggal kedaluwarsa lot yang sudah ada tidak ditimpa.
+func upsertLot(ctx context.Context, q querier, outletID, productID int, lotNumber string, expiry *string, qty int) (int, error) {
+	var id int
+	err := q.QueryRow(ctx,
+		`INSERT INTO stock_lots (outlet_id, product_id, lot_number, expiry_date, quantity)
+		 VALUES ($1, $2, $3, $4::date, $5)
+		 ON CONFLICT (outlet_id, product_id, lot_number) DO UPDATE
+		 SET quantity = stock_lots.quantity + EXCLUDED.quantity,
+		     expiry_date = COALESCE(stock_lots.expiry_date, EXCLUDED.expiry_date)
+		 RETURNING id`,
+		outletID, productID, lotNumber, expiry, qty,
+	).Scan(&id)
+	return id, err
+}
+
+func insertLotUsage(ctx context.Context, q querier, transactionID int, uses []lotUse) error {
+	for _, u := range uses {
+		_, err := q.Exec(ctx,
+			`INSERT INTO transaction_lot_usage (transaction_id, lot_id, quantity) VALUES ($1,$2,$3)`,
+			transactionID, u.lotID, u.qty,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unlottedLot menampung stok produk track_lots yang belum tercatat di lot mana pun
+// (stok sebelum tracking diaktifkan, kelebihan terima transfer).
+const unlottedLot = "TANPA-LOT"
+
+// syncLotRemainder memasukkan selisih stok outlet - jumlah lot ke lot TANPA-LOT
+// untuk produk track_lots (outletID nil = semua outlet).
+func syncLotRemainder(ctx context.Context, q querier, outletID *int, productID int) error {
+	_, err := q.Exec(ctx, `
+		INSERT INTO stock_lots (outlet_id, product_id, lot_number, quantity)
+		SELECT op.outlet_id, op.product_id, $3, op.stock - l.total
+		FROM outlet_products op
+		JOIN products p ON p.id = op.product_id AND p.track_lots
+		CROSS JOIN LATERAL (
+			SELECT COALESCE(SUM(quantity), 0) AS total FROM stock_lots
+			WHERE outlet_id = op.outlet_id AND product_id = op.product_id
+		) l
+		WHERE op.product_id = $2 AND ($1::int IS NULL OR op.outlet_id = $1) AND op.stock > l.total
+		ON CONFLICT (outlet_id, product_id, lot_number) DO UPDATE
+		SET quantity = stock_lots.quantity + EXCLUDED.quantity
+	`, outletID, productID, unlottedLot)
+	return err
+}
+
+// takeSerials menandai serial yang dijual sebagai sold. Jumlahnya harus sama dengan
+// quantity terjual dan semuanya harus ada di stok outlet ini. Seperti lot, lock serial
+// selalu di bawah lock stok produknya.
+func takeSerials(ctx context.Context, q querier, outletID, productID int, name string, need int, serials []string) error {
+	if len(serials) != need {
+		return fmt.Errorf("%s wajib menyebut %d serial number (diisi %d)", name, need, len(serials))
+	}
+	seen := make(map[string]bool, len(serials))
+	for _, sn := range serials {
+		if seen[sn] {
+			return fmt.Errorf("serial %s disebut lebih dari sekali", sn)
+		}
+		seen[sn] = true
+	}
+
+	rows, err := q.Query(ctx,
+		`SELECT serial_number, status, outlet_id FROM product_serials
+		 WHERE product_id = $1 AND serial_number = ANY($2)
+		 ORDER BY id
+		 FOR UPDATE`,
+		productID, serials,
+	)
+	if err != nil {
+		return err
+	}
+	type unit struct {
+		status   string
+		outletID int
+	}
+	units := make(map[string]unit, len(serials))
+	for rows.Next() {
+		var sn string
+		var u unit
+		if err := rows.Scan(&sn, &u.status, &u.outletID); err != nil {
+			rows.Close()
+			return err
+		}
+		units[sn] = u
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, sn := range serials {
+		u, ok := units[sn]
+		switch {
+		case !ok:
+			return fmt.Errorf("serial %s tidak terdaftar untuk %s", sn, name)
+		case u.status != models.SerialInStock:
+			return fmt.Errorf("serial %s sudah terjual", sn)
+		case u.outletID != outletID:
+			return fmt.Errorf("serial %s ada di outlet lain", sn)
+		}
+	}
+
+	_, err = q.Exec(ctx,
+		`UPDATE product_serials SET status = $3 WHERE product_id = $1 AND serial_number = ANY($2)`,
+		productID, serials, models.SerialSold,
+	)
+	return err
+}
+
+// insertSerialSales mencatat serial satu baris transaksi + kejadian "sold" di riwayatnya.
+func insertSerialSales(ctx context.Context, q querier, transactionID, detailID, outletID, productID int, serials []string) error {
+	if len(serials) == 0 {
+		return nil
+	}
+	_, err := q.Exec(ctx,
+		`INSERT INTO transaction_serials (transaction_id, transaction_detail_id, serial_id)
+		 SELECT $1, $2, id FROM product_serials WHERE product_id = $3 AND serial_number = ANY($4)`,
+		transactionID, detailID, productID, serials,
+	)
+	if err != nil {
+		return err
+	}
+	_, err = q.Exec(ctx,
+		`INSERT INTO serial_events (serial_id, kind, outlet_id, transaction_id)
+		 SELECT id, $5, $3, $1 FROM product_serials WHERE product_id = $2 AND serial_number = ANY($4)`,
+		transactionID, productID, outletID, serials, models.SerialEventSold,
+	)
+	return err
+}
+
+const (
+	usageRecipe = "recipe" // bahan resep
+	usageBundle = "bundle" // komponen paket
+)
+
+// stockUse = stok yang dipotong satu baris checkout di luar stok produknya sendiri.
+type stockUse struct {
+	productID int
+	qty       int
+	source    string
+}
+
+// lineStockUse memecah satu baris jadi pemakaian stok: paket -> komponen (komponen komposit
+// -> bahannya), produk komposit -> bahan. Hasil kosong = produk biasa, potong stok sendiri.
+func lineStockUse(productID, qty int, bundles map[int][]bundleLine, recipes map[int][]recipeLine) []stockUse {
+	if bundle := bundles[productID]; len(bundle) > 0 {
+		var out []stockUse
+		for _, c := range bundle {
+			uses := lineStockUse(c.productID, c.quantity*qty, nil, recipes)
+			if len(uses) == 0 {
+				uses = []stockUse{{productID: c.productID, qty: c.quantity * qty, source: usageBundle}}
+			}
+			out = append(out, uses...)
+		}
+		return out
+	}
+	var out []stockUse
+	for _, l := range recipes[productID] {
+		out = append(out, stockUse{productID: l.ingredientID, qty: l.quantity * qty, source: usageRecipe})
+	}
+	return out
+}
+
+func (p *stockPlan) addUses(uses []stockUse) {
+	for _, u := range uses {
+		if u.source == usageRecipe {
+			p.addIngredient(u.productID, u.qty)
+		} else {
+			p.add(u.productID, u.qty)
+		}
+	}
+}
+
+func insertStockUsage(ctx context.Context, q querier, transactionID, detailID int, uses []stockUse) error {
+	for _, u := range uses {
+		_, err := q.Exec(ctx,
+			`INSERT INTO transaction_ingredient_usage (transaction_id, transaction_detail_id, ingredient_id, quantity, source)
+			 VALUES ($1,$2,$3,$4,$5)`,
+			transactionID, detailID, u.productID, u.qty, u.source,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordSale mencatat semua pemotongan stok plan ke buku mutasi (kind sale), urut product_id.
+// Dipanggil setelah header transaksi ada, karena apply jalan sebelum nomor transaksi dibuat.
+func (p *stockPlan) recordSale(ctx context.Context, q querier, transactionID int, receiptNo string) error {
+	ids := make([]int, 0, len(p.need))
+	for id := range p.need {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		_, err := q.Exec(ctx,
+			`INSERT INTO stock_movements (outlet_id, product_id, quantity, kind, transaction_id, note)
+			 VALUES ($1,$2,$3,$4,$5,$6)`,
+			p.outletID, id, -p.need[id], models.MovementSale, transactionID, receiptNo,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addStockMovement mencatat mutasi stok outlet (quantity bertanda) ke buku mutasi.
+func addStockMovement(ctx context.Context, q querier, outletID, productID, qty int, kind string, transferID *int, note string) error {
+	_, err := q.Exec(ctx,
+		`INSERT INTO stock_movements (outlet_id, product_id, quantity, kind, transfer_id, note)
+		 VALUES ($1,$2,$3,$4,$5,$6)`,
+		outletID, productID, qty, kind, transferID, note,
+	)
+	return err
+}
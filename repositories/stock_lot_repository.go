@@ -0,0 +1,338 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"kasir-api/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type StockLotRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewStockLotRepository(db *pgxpool.Pool) *StockLotRepository {
+	return &StockLotRepository{db: db}
+}
+
+// lotColumns: days_left dihitung dari hari bisnis outlet lot itu.
+const lotColumns = `l.id, l.outlet_id, o.code, l.product_id, p.name, l.lot_number, to_char(l.expiry_date, 'YYYY-MM-DD'),
+	l.quantity, l.received_at, l.expiry_date - outlet_business_date(now(), l.outlet_id)`
+
+const lotFrom = ` FROM stock_lots l JOIN outlets o ON o.id = l.outlet_id JOIN products p ON p.id = l.product_id`
+
+func scanLot(row pgx.Row) (*models.StockLot, error) {
+	var l models.StockLot
+	err := row.Scan(&l.ID, &l.OutletID, &l.OutletCode, &l.ProductID, &l.ProductName, &l.LotNumber, &l.ExpiryDate,
+		&l.Quantity, &l.ReceivedAt, &l.DaysLeft)
+	if err != nil {
+		return nil, err
+	}
+	l.Expired = l.DaysLeft != nil && *l.DaysLeft < 0
+	return &l, nil
+}
+
+func queryLots(ctx context.Context, q querier, query string, args ...any) ([]models.StockLot, error) {
+	rows, err := q.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]models.StockLot, 0)
+	for rows.Next() {
+		l, err := scanLot(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *l)
+	}
+	return out, rows.Err()
+}
+
+// GetLots = lot yang masih ada isinya (all = termasuk yang sudah habis), urut FEFO.
+func (r *StockLotRepository) GetLots(outletID *int, productID int, all bool) ([]models.StockLot, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return queryLots(ctx, r.db, `SELECT `+lotColumns+lotFrom+`
+		WHERE ($1::int IS NULL OR l.outlet_id = $1) AND ($2 = 0 OR l.product_id = $2) AND ($3 OR l.quantity > 0)
+		ORDER BY l.outlet_id, l.product_id, l.expiry_date NULLS LAST, l.id
+	`, outletID, productID, all)
+}
+
+// AdjustLot men-set quantity satu lot (stok opname / buang barang kedaluwarsa);
+// selisihnya ikut ke stok outlet dan tercatat sebagai mutasi adjustment.
+func (r *StockLotRepository) AdjustLot(id int, req models.LotAdjustRequest) (*models.StockLot, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var outletID, productID int
+	var lotNumber string
+	err = tx.QueryRow(ctx, `SELECT outlet_id, product_id, lot_number FROM stock_lots WHERE id = $1`, id).
+		Scan(&outletID, &productID, &lotNumber)
+	if err != nil {
+		return nil, errors.New("lot belum ada")
+	}
+	// Urutan lock sama seperti checkout: stok produk dulu, baru lotnya
+	if err := lockStock(ctx, tx, outletID, []int{productID}); err != nil {
+		return nil, err
+	}
+	var old int
+	if err := tx.QueryRow(ctx, `SELECT quantity FROM stock_lots WHERE id = $1 FOR UPDATE`, id).Scan(&old); err != nil {
+		return nil, err
+	}
+
+	if delta := req.Quantity - old; delta != 0 {
+		if _, err := tx.Exec(ctx, `UPDATE stock_lots SET quantity = $1 WHERE id = $2`, req.Quantity, id); err != nil {
+			return nil, err
+		}
+		_, err = tx.Exec(ctx,
+			`INSERT INTO outlet_products (outlet_id, product_id, stock) VALUES ($1,$2,$3)
+			 ON CONFLICT (outlet_id, product_id) DO UPDATE SET stock = outlet_products.stock + EXCLUDED.stock`,
+			outletID, productID, delta,
+		)
+		if err != nil {
+			return nil, err
+		}
+		note := "lot " + lotNumber
+		if req.Note != "" {
+			note += ": " + req.Note
+		}
+		if err := addStockMovement(ctx, tx, outletID, productID, delta, models.MovementAdjustment, nil, note); err != nil {
+			return nil, err
+		}
+	}
+
+	l, err := scanLot(tx.QueryRow(ctx, `SELECT `+lotColumns+lotFrom+` WHERE l.id = $1`, id))
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+const goodsReceiptColumns = `g.id, g.outlet_id, o.code, g.supplier, g.note, g.created_at`
+
+func scanGoodsReceipt(row pgx.Row) (*models.GoodsReceipt, error) {
+	var g models.GoodsReceipt
+	if err := row.Scan(&g.ID, &g.OutletID, &g.OutletCode, &g.Supplier, &g.Note, &g.CreatedAt); err != nil {
+		return nil, err
+	}
+	g.Items = make([]models.GoodsReceiptItem, 0)
+	return &g, nil
+}
+
+// GetReceipts = 100 penerimaan barang terakhir (tanpa item), outletID nil = semua outlet.
+func (r *StockLotRepository) GetReceipts(outletID *int) ([]models.GoodsReceipt, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := r.db.Query(ctx, `
+		SELECT `+goodsReceiptColumns+`
+		FROM goods_receipts g JOIN outlets o ON o.id = g.outlet_id
+		WHERE $1::int IS NULL OR g.outlet_id = $1
+		ORDER BY g.id DESC
+		LIMIT 100
+	`, outletID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]models.GoodsReceipt, 0)
+	for rows.Next() {
+		g, err := scanGoodsReceipt(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *g)
+	}
+	return out, rows.Err()
+}
+
+func (r *StockLotRepository) GetReceipt(id int) (*models.GoodsReceipt, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return getGoodsReceipt(ctx, r.db, id)
+}
+
+func getGoodsReceipt(ctx context.Context, q querier, id int) (*models.GoodsReceipt, error) {
+	g, err := scanGoodsReceipt(q.QueryRow(ctx,
+		`SELECT `+goodsReceiptColumns+` FROM goods_receipts g JOIN outlets o ON o.id = g.outlet_id WHERE g.id = $1`, id))
+	if err != nil {
+		return nil, errors.New("goods receipt belum ada")
+	}
+
+	rows, err := q.Query(ctx, `
+		SELECT i.product_id, p.name, i.quantity, i.lot_number, to_char(i.expiry_date, 'YYYY-MM-DD'), i.lot_id, i.serial_numbers
+		FROM goods_receipt_items i JOIN products p ON p.id = i.product_id
+		WHERE i.receipt_id = $1
+		ORDER BY i.line_no
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var it models.GoodsReceiptItem
+		if err := rows.Scan(&it.ProductID, &it.ProductName, &it.Quantity, &it.LotNumber, &it.ExpiryDate, &it.LotID, &it.SerialNumbers); err != nil {
+			return nil, err
+		}
+		g.Items = append(g.Items, it)
+	}
+	return g, rows.Err()
+}
+
+// CreateReceipt menambah stok outlet dari penerimaan barang. Produk track_lots wajib
+// punya lot_number; lot dengan nomor yang sama di outlet itu ditambah quantity-nya.
+func (r *StockLotRepository) CreateReceipt(g *models.GoodsReceipt) (*models.GoodsReceipt, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	outletID, _, err := resolveOutlet(ctx, tx, g.OutletID)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int, 0, len(g.Items))
+	for _, it := range g.Items {
+		if err := checkTransferable(ctx, tx, it.ProductID); err != nil {
+			return nil, err
+		}
+		ids = append(ids, it.ProductID)
+	}
+	if err := lockStock(ctx, tx, outletID, ids); err != nil {
+		return nil, err
+	}
+
+	var id int
+	err = tx.QueryRow(ctx,
+		`INSERT INTO goods_receipts (outlet_id, supplier, note) VALUES ($1,$2,$3) RETURNING id`,
+		outletID, g.Supplier, g.Note,
+	).Scan(&id)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, it := range g.Items {
+		var trackLots, trackSerials bool
+		err := tx.QueryRow(ctx, `SELECT track_lots, track_serials FROM products WHERE id = $1`, it.ProductID).
+			Scan(&trackLots, &trackSerials)
+		if err != nil {
+			return nil, fmt.Errorf("product id %d not found", it.ProductID)
+		}
+		if trackLots && it.LotNumber == "" {
+			return nil, fmt.Errorf("lot_number wajib untuk produk ber-lot (product_id=%d)", it.ProductID)
+		}
+		if trackSerials && len(it.SerialNumbers) != it.Quantity {
+			return nil, fmt.Errorf("produk ber-serial wajib menyebut %d serial number (product_id=%d, diisi %d)",
+				it.Quantity, it.ProductID, len(it.SerialNumbers))
+		}
+		if !trackSerials && len(it.SerialNumbers) > 0 {
+			return nil, fmt.Errorf("serial_numbers hanya untuk produk ber-serial (product_id=%d)", it.ProductID)
+		}
+
+		_, err = tx.Exec(ctx,
+			`INSERT INTO outlet_products (outlet_id, product_id, stock) VALUES ($1,$2,$3)
+			 ON CONFLICT (outlet_id, product_id) DO UPDATE SET stock = outlet_products.stock + EXCLUDED.stock`,
+			outletID, it.ProductID, it.Quantity,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if err := receiveSerials(ctx, tx, id, outletID, it.ProductID, it.SerialNumbers); err != nil {
+			return nil, err
+		}
+		var lotID *int
+		if trackLots {
+			lot, err := upsertLot(ctx, tx, outletID, it.ProductID, it.LotNumber, it.ExpiryDate, it.Quantity)
+			if err != nil {
+				return nil, err
+			}
+			lotID = &lot
+		}
+
+		_, err = tx.Exec(ctx,
+			`INSERT INTO goods_receipt_items (receipt_id, line_no, product_id, quantity, lot_id, lot_number, expiry_date, serial_numbers)
+			 VALUES ($1,$2,$3,$4,$5,$6,$7::date,$8)`,
+			id, i+1, it.ProductID, it.Quantity, lotID, it.LotNumber, it.ExpiryDate, serialNumbers(it.SerialNumbers),
+		)
+		if err != nil {
+			return nil, err
+		}
+		note := fmt.Sprintf("goods receipt #%d", id)
+		if g.Supplier != "" {
+			note += " " + g.Supplier
+		}
+		if err := addStockMovement(ctx, tx, outletID, it.ProductID, it.Quantity, models.MovementReceipt, nil, note); err != nil {
+			return nil, err
+		}
+	}
+
+	out, err := getGoodsReceipt(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// receiveSerials mendaftarkan unit yang diterima ke stok outlet. Serial yang pernah terjual
+// (retur / trade-in) boleh diterima lagi; yang masih in_stock ditolak.
+func receiveSerials(ctx context.Context, q querier, receiptID, outletID, productID int, serials []string) error {
+	for _, sn := range serials {
+		var serialID int
+		err := q.QueryRow(ctx,
+			`INSERT INTO product_serials (product_id, serial_number, outlet_id, status) VALUES ($1,$2,$3,$4)
+			 ON CONFLICT (product_id, serial_number) DO UPDATE
+			 SET outlet_id = EXCLUDED.outlet_id, status = EXCLUDED.status, received_at = now()
+			 WHERE product_serials.status <> EXCLUDED.status
+			 RETURNING id`,
+			productID, sn, outletID, models.SerialInStock,
+		).Scan(&serialID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("serial %s masih tercatat di stok", sn)
+		}
+		if err != nil {
+			return err
+		}
+		_, err = q.Exec(ctx,
+			`INSERT INTO serial_events (serial_id, kind, outlet_id, goods_receipt_id) VALUES ($1,$2,$3,$4)`,
+			serialID, models.SerialEventReceived, outletID, receiptID,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// serialNumbers: kolom TEXT[] NOT NULL, nil dikirim sebagai array kosong.
+func serialNumbers(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
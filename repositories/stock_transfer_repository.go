@@ -0,0 +1,504 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"kasir-api/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// StockTransferRepository = transfer stok antar outlet: draft -> sent (stok keluar dari asal,
+// jadi in-transit) -> received (stok masuk tujuan sesuai yang diterima).
+type StockTransferRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewStockTransferRepository(db *pgxpool.Pool) *StockTransferRepository {
+	return &StockTransferRepository{db: db}
+}
+
+const transferColumns = `st.id, st.from_outlet_id, fo.code, st.to_outlet_id, tou.code, st.status, st.note,
+	st.created_at, st.sent_at, st.received_at`
+
+const transferFrom = ` FROM stock_transfers st
+	JOIN outlets fo ON fo.id = st.from_outlet_id
+	JOIN outlets tou ON tou.id = st.to_outlet_id`
+
+func scanTransfer(row pgx.Row) (*models.StockTransfer, error) {
+	var t models.StockTransfer
+	err := row.Scan(&t.ID, &t.FromOutletID, &t.FromOutletCode, &t.ToOutletID, &t.ToOutletCode, &t.Status, &t.Note,
+		&t.CreatedAt, &t.SentAt, &t.ReceivedAt)
+	if err != nil {
+		return nil, err
+	}
+	t.Items = make([]models.StockTransferItem, 0)
+	return &t, nil
+}
+
+// GetAll: status & outletID (asal atau tujuan) opsional.
+func (r *StockTransferRepository) GetAll(status string, outletID int) ([]models.StockTransfer, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	query := `SELECT ` + transferColumns + transferFrom + ` WHERE TRUE`
+	args := []any{}
+	if status != "" {
+		args = append(args, status)
+		query += fmt.Sprintf(` AND st.status = $%d`, len(args))
+	}
+	if outletID != 0 {
+		args = append(args, outletID)
+		query += fmt.Sprintf(` AND (st.from_outlet_id = $%d OR st.to_outlet_id = $%d)`, len(args), len(args))
+	}
+	query += ` ORDER BY st.id DESC LIMIT 200`
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]models.StockTransfer, 0)
+	for rows.Next() {
+		t, err := scanTransfer(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *t)
+	}
+	return out, rows.Err()
+}
+
+func (r *StockTransferRepository) GetByID(id int) (*models.StockTransfer, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return getTransfer(ctx, r.db, id)
+}
+
+func getTransfer(ctx context.Context, q querier, id int) (*models.StockTransfer, error) {
+	t, err := scanTransfer(q.QueryRow(ctx, `SELECT `+transferColumns+transferFrom+` WHERE st.id = $1`, id))
+	if err != nil {
+		return nil, errors.New("transfer belum ada")
+	}
+
+	rows, err := q.Query(ctx, `
+		SELECT ti.product_id, p.name, ti.quantity, ti.received_quantity, ti.note
+		FROM stock_transfer_items ti
+		JOIN products p ON p.id = ti.product_id
+		WHERE ti.transfer_id = $1
+		ORDER BY ti.product_id
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var it models.StockTransferItem
+		if err := rows.Scan(&it.ProductID, &it.ProductName, &it.Quantity, &it.ReceivedQuantity, &it.Note); err != nil {
+			return nil, err
+		}
+		if it.ReceivedQuantity != nil {
+			it.Discrepancy = it.Quantity - *it.ReceivedQuantity
+		}
+		t.Items = append(t.Items, it)
+	}
+	return t, rows.Err()
+}
+
+// lockTransfer mengunci transfer dan memastikan statusnya sesuai.
+func lockTransfer(ctx context.Context, q querier, id int, want string) error {
+	var status string
+	err := q.QueryRow(ctx, `SELECT status FROM stock_transfers WHERE id = $1 FOR UPDATE`, id).Scan(&status)
+	if err != nil {
+		return errors.New("transfer belum ada")
+	}
+	if status != want {
+		return fmt.Errorf("transfer berstatus %s, harus %s", status, want)
+	}
+	return nil
+}
+
+// checkTransferable: yang dipindah harus barang fisik, bukan induk varian, produk komposit atau paket.
+func checkTransferable(ctx context.Context, q querier, productID int) error {
+	var name string
+	var composite bool
+	err := q.QueryRow(ctx, `
+		SELECT p.name,
+			EXISTS (SELECT 1 FROM product_recipes r WHERE r.product_id = p.id)
+			OR EXISTS (SELECT 1 FROM bundle_items b WHERE b.bundle_id = p.id)
+		FROM products p WHERE p.id = $1
+	`, productID).Scan(&name, &composite)
+	if err != nil {
+		return fmt.Errorf("product id %d not found", productID)
+	}
+	if composite {
+		return fmt.Errorf("%s dirakit dari bahan/komponen, transfer bahannya", name)
+	}
+	return checkSellable(ctx, q, productID)
+}
+
+// writeTransfer mengisi header & item transfer draft (insert kalau t.ID = 0).
+func writeTransfer(ctx context.Context, q querier, t *models.StockTransfer) error {
+	for _, id := range []int{t.FromOutletID, t.ToOutletID} {
+		if _, _, err := resolveOutlet(ctx, q, &id); err != nil {
+			return err
+		}
+	}
+
+	if t.ID == 0 {
+		err := q.QueryRow(ctx,
+			`INSERT INTO stock_transfers (from_outlet_id, to_outlet_id, note) VALUES ($1,$2,$3) RETURNING id`,
+			t.FromOutletID, t.ToOutletID, t.Note,
+		).Scan(&t.ID)
+		if err != nil {
+			return err
+		}
+	} else {
+		_, err := q.Exec(ctx,
+			`UPDATE stock_transfers SET from_outlet_id = $1, to_outlet_id = $2, note = $3 WHERE id = $4`,
+			t.FromOutletID, t.ToOutletID, t.Note, t.ID,
+		)
+		if err != nil {
+			return err
+		}
+		if _, err := q.Exec(ctx, `DELETE FROM stock_transfer_items WHERE transfer_id = $1`, t.ID); err != nil {
+			return err
+		}
+	}
+
+	for _, it := range t.Items {
+		if err := checkTransferable(ctx, q, it.ProductID); err != nil {
+			return err
+		}
+		// Transfer belum membawa serial number, jadi unit ber-serial belum bisa pindah outlet
+		var trackSerials bool
+		if err := q.QueryRow(ctx, `SELECT track_serials FROM products WHERE id = $1`, it.ProductID).Scan(&trackSerials); err != nil {
+			return err
+		}
+		if trackSerials {
+			return fmt.Errorf("produk ber-serial belum bisa ditransfer (product_id=%d)", it.ProductID)
+		}
+		_, err := q.Exec(ctx,
+			`INSERT INTO stock_transfer_items (transfer_id, product_id, quantity, note) VALUES ($1,$2,$3,$4)`,
+			t.ID, it.ProductID, it.Quantity, it.Note,
+		)
+		if isUniqueViolation(err) {
+			return fmt.Errorf("product id %d muncul dua kali", it.ProductID)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *StockTransferRepository) Create(t *models.StockTransfer) (*models.StockTransfer, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	t.ID = 0
+	if err := writeTransfer(ctx, tx, t); err != nil {
+		return nil, err
+	}
+	return r.finish(ctx, tx, t.ID)
+}
+
+// Update mengganti outlet, catatan dan item; hanya untuk draft.
+func (r *StockTransferRepository) Update(t *models.StockTransfer) (*models.StockTransfer, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockTransfer(ctx, tx, t.ID, models.TransferDraft); err != nil {
+		return nil, err
+	}
+	if err := writeTransfer(ctx, tx, t); err != nil {
+		return nil, err
+	}
+	return r.finish(ctx, tx, t.ID)
+}
+
+// Send memotong stok outlet asal (dicek seperti checkout) dan mencatat mutasi transfer_out.
+func (r *StockTransferRepository) Send(id int) (*models.StockTransfer, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockTransfer(ctx, tx, id, models.TransferDraft); err != nil {
+		return nil, err
+	}
+	t, err := getTransfer(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(t.Items) == 0 {
+		return nil, errors.New("transfer belum punya item")
+	}
+	if _, _, err := resolveOutlet(ctx, tx, &t.FromOutletID); err != nil {
+		return nil, err
+	}
+
+	stock := newStockPlan(t.FromOutletID)
+	ids := make([]int, 0, len(t.Items))
+	for _, it := range t.Items {
+		stock.add(it.ProductID, it.Quantity)
+		ids = append(ids, it.ProductID)
+	}
+	if err := lockStock(ctx, tx, t.FromOutletID, ids); err != nil {
+		return nil, err
+	}
+	if err := stock.apply(ctx, tx); err != nil {
+		return nil, err
+	}
+	// Lot yang dikirim (FEFO) ikut pindah ke outlet tujuan saat diterima
+	for _, u := range stock.lots {
+		_, err := tx.Exec(ctx,
+			`INSERT INTO stock_transfer_lots (transfer_id, lot_id, product_id, quantity) VALUES ($1,$2,$3,$4)`,
+			id, u.lotID, u.productID, u.qty,
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, it := range t.Items {
+		err := addStockMovement(ctx, tx, t.FromOutletID, it.ProductID, -it.Quantity, models.MovementTransferOut, &id,
+			"ke "+t.ToOutletCode)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE stock_transfers SET status = 'sent', sent_at = now() WHERE id = $1`, id); err != nil {
+		return nil, err
+	}
+	return r.finish(ctx, tx, id)
+}
+
+// Receive menambah stok outlet tujuan sebanyak yang benar-benar diterima. Selisih dengan
+// quantity kirim dicatat di item (discrepancy) dan tidak kembali ke outlet asal.
+func (r *StockTransferRepository) Receive(id int, req models.ReceiveTransferRequest) (*models.StockTransfer, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockTransfer(ctx, tx, id, models.TransferSent); err != nil {
+		return nil, err
+	}
+	t, err := getTransfer(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int, 0, len(t.Items))
+	inTransfer := make(map[int]bool, len(t.Items))
+	for _, it := range t.Items {
+		ids = append(ids, it.ProductID)
+		inTransfer[it.ProductID] = true
+	}
+	received := make(map[int]models.StockTransferItem, len(req.Items))
+	for _, it := range req.Items {
+		if !inTransfer[it.ProductID] {
+			return nil, fmt.Errorf("product id %d tidak ada di transfer ini", it.ProductID)
+		}
+		received[it.ProductID] = it
+	}
+
+	if err := lockStock(ctx, tx, t.ToOutletID, ids); err != nil {
+		return nil, err
+	}
+	for _, it := range t.Items {
+		qty, note := it.Quantity, ""
+		if got, ok := received[it.ProductID]; ok {
+			if got.ReceivedQuantity != nil {
+				qty = *got.ReceivedQuantity
+			}
+			note = got.Note
+		}
+
+		_, err := tx.Exec(ctx,
+			`UPDATE stock_transfer_items SET received_quantity = $1, note = CASE WHEN $2 = '' THEN note ELSE $2 END
+			 WHERE transfer_id = $3 AND product_id = $4`,
+			qty, note, id, it.ProductID,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if qty == 0 {
+			continue
+		}
+		_, err = tx.Exec(ctx,
+			`INSERT INTO outlet_products (outlet_id, product_id, stock) VALUES ($1,$2,$3)
+			 ON CONFLICT (outlet_id, product_id) DO UPDATE SET stock = outlet_products.stock + EXCLUDED.stock`,
+			t.ToOutletID, it.ProductID, qty,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if err := receiveLots(ctx, tx, id, t.ToOutletID, it.ProductID, qty); err != nil {
+			return nil, err
+		}
+		err = addStockMovement(ctx, tx, t.ToOutletID, it.ProductID, qty, models.MovementTransferIn, &id,
+			"dari "+t.FromOutletCode)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = tx.Exec(ctx,
+		`UPDATE stock_transfers SET status = 'received', received_at = now(),
+		 note = CASE WHEN $1 = '' THEN note WHEN note = '' THEN $1 ELSE note || E'\n' || $1 END
+		 WHERE id = $2`,
+		req.Note, id,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return r.finish(ctx, tx, id)
+}
+
+// receiveLots membuat ulang lot yang dikirim di outlet tujuan (nomor lot & kedaluwarsa sama).
+// Kalau yang diterima kurang, yang dianggap hilang adalah lot yang paling lama kedaluwarsanya;
+// kelebihan atau produk yang dikirim sebelum ber-lot masuk lot TANPA-LOT.
+func receiveLots(ctx context.Context, tx pgx.Tx, transferID, outletID, productID, qty int) error {
+	rows, err := tx.Query(ctx, `
+		SELECT l.lot_number, to_char(l.expiry_date, 'YYYY-MM-DD'), tl.quantity
+		FROM stock_transfer_lots tl
+		JOIN stock_lots l ON l.id = tl.lot_id
+		WHERE tl.transfer_id = $1 AND tl.product_id = $2
+		ORDER BY l.expiry_date NULLS LAST, l.id
+	`, transferID, productID)
+	if err != nil {
+		return err
+	}
+	type lot struct {
+		number   string
+		expiry   *string
+		quantity int
+	}
+	lots := make([]lot, 0)
+	for rows.Next() {
+		var l lot
+		if err := rows.Scan(&l.number, &l.expiry, &l.quantity); err != nil {
+			rows.Close()
+			return err
+		}
+		lots = append(lots, l)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	left := qty
+	for _, l := range lots {
+		if left == 0 {
+			break
+		}
+		take := min(l.quantity, left)
+		if _, err := upsertLot(ctx, tx, outletID, productID, l.number, l.expiry, take); err != nil {
+			return err
+		}
+		left -= take
+	}
+	return syncLotRemainder(ctx, tx, &outletID, productID)
+}
+
+// Cancel membatalkan transfer yang belum dikirim.
+func (r *StockTransferRepository) Cancel(id int) (*models.StockTransfer, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockTransfer(ctx, tx, id, models.TransferDraft); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE stock_transfers SET status = 'cancelled' WHERE id = $1`, id); err != nil {
+		return nil, err
+	}
+	return r.finish(ctx, tx, id)
+}
+
+// finish memuat transfer terbaru lalu commit.
+func (r *StockTransferRepository) finish(ctx context.Context, tx pgx.Tx, id int) (*models.StockTransfer, error) {
+	t, err := getTransfer(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Movements = buku mutasi stok, terbaru dulu.
+func (r *StockTransferRepository) Movements(f models.StockMovementFilter) ([]models.StockMovement, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	query := `
+		SELECT m.id, m.outlet_id, o.code, m.product_id, p.name, m.quantity, m.kind, m.transfer_id, m.transaction_id, m.note, m.created_at
+		FROM stock_movements m
+		JOIN outlets o ON o.id = m.outlet_id
+		JOIN products p ON p.id = m.product_id
+		WHERE m.created_at >= $1 AND m.created_at < $2`
+	args := []any{f.Start, f.End}
+	if f.OutletID != 0 {
+		args = append(args, f.OutletID)
+		query += fmt.Sprintf(` AND m.outlet_id = $%d`, len(args))
+	}
+	if f.ProductID != 0 {
+		args = append(args, f.ProductID)
+		query += fmt.Sprintf(` AND m.product_id = $%d`, len(args))
+	}
+	query += ` ORDER BY m.id DESC`
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]models.StockMovement, 0)
+	for rows.Next() {
+		var m models.StockMovement
+		err := rows.Scan(&m.ID, &m.OutletID, &m.OutletCode, &m.ProductID, &m.ProductName, &m.Quantity, &m.Kind,
+			&m.TransferID, &m.TransactionID, &m.Note, &m.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
@@ -2,22 +2,25 @@ package repositories
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"kasir-api/models"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type TransactionRepository struct {
-	db *pgxpool.Pool
+	db        *pgxpool.Pool
+	numbering ReceiptNumbering
 }
 
-func NewTransactionRepository(db *pgxpool.Pool) *TransactionRepository {
-	return &TransactionRepository{db: db}
+func NewTransactionRepository(db *pgxpool.Pool, numbering ReceiptNumbering) *TransactionRepository {
+	return &TransactionRepository{db: db, numbering: numbering}
 }
 
-func (r *TransactionRepository) CreateTransaction(items []models.CheckoutItem) (*models.Transaction, error) {
+func (r *TransactionRepository) CreateTransaction(req models.CheckoutRequest) (*models.Transaction, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
@@ -27,57 +30,266 @@ func (r *TransactionRepository) CreateTransaction(items []models.CheckoutItem) (
 	}
 	defer tx.Rollback(ctx)
 
+	t, err := r.createTransaction(ctx, tx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// createTransaction = seluruh validasi + penulisan checkout di dalam tx milik pemanggil,
+// dipakai checkout langsung maupun checkout dari cart.
+func (r *TransactionRepository) createTransaction(ctx context.Context, tx pgx.Tx, req models.CheckoutRequest) (*models.Transaction, error) {
+	// ✅ Lock shift (FOR SHARE) supaya tidak bisa ditutup di tengah checkout.
+	// Outlet shift menentukan stok, harga outlet dan nomor struk.
+	// Hari bisnis mengikuti zona waktu & jam tutup buku outlet shift.
+	var cashierID, outletID int
+	var shiftStatus, cashierRole, outletCode string
+	var businessDate time.Time
+	err := tx.QueryRow(ctx,
+		`SELECT s.cashier_id, s.status, COALESCE(c.role, ''), s.outlet_id, o.code, outlet_business_date(now(), o.id)
+		 FROM shifts s
+		 LEFT JOIN cashiers c ON c.id = s.cashier_id
+		 JOIN outlets o ON o.id = s.outlet_id
+		 WHERE s.id = $1 FOR SHARE OF s`,
+		req.ShiftID,
+	).Scan(&cashierID, &shiftStatus, &cashierRole, &outletID, &outletCode, &businessDate)
+	if err != nil {
+		return nil, fmt.Errorf("shift id %d not found", req.ShiftID)
+	}
+	if shiftStatus != models.ShiftOpen {
+		return nil, errors.New("shift sudah ditutup, buka shift baru dulu")
+	}
+
+	// Hari yang sudah di-Z tidak boleh menerima transaksi baru
+	if z, closed, err := closedZNumber(ctx, tx, businessDate); err != nil {
+		return nil, err
+	} else if closed {
+		return nil, fmt.Errorf("hari bisnis sudah ditutup (Z #%d)", z)
+	}
+
+	customerID, customerName, customerPriceList, err := resolveCustomer(ctx, tx, req.CustomerID, req.CustomerPhone)
+	if err != nil {
+		return nil, err
+	}
+
+	// Quantity break dihitung dari total qty per produk di keranjang
+	qtyByProduct := make(map[int]int, len(req.Items))
+	modifierIDs := make([]int, 0)
+	for _, item := range req.Items {
+		qtyByProduct[item.ProductID] += item.Quantity
+		modifierIDs = append(modifierIDs, item.Modifiers...)
+	}
+
+	// ✅ Semua stok outlet yang tersentuh (barang, bahan resep, stok modifier)
+	// di-lock sekaligus urut id, supaya checkout yang bersamaan tidak saling deadlock.
+	productIDs := make([]int, 0, len(qtyByProduct))
+	for id := range qtyByProduct {
+		productIDs = append(productIDs, id)
+	}
+	bundles, err := loadBundles(ctx, tx, productIDs)
+	if err != nil {
+		return nil, err
+	}
+	lockIDs := append([]int{}, productIDs...)
+	for _, bundle := range bundles {
+		for _, c := range bundle {
+			lockIDs = append(lockIDs, c.productID)
+		}
+	}
+	recipes, err := loadRecipes(ctx, tx, lockIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, recipe := range recipes {
+		for _, l := range recipe {
+			lockIDs = append(lockIDs, l.ingredientID)
+		}
+	}
+	modifierStock, err := modifierStockProducts(ctx, tx, modifierIDs)
+	if err != nil {
+		return nil, err
+	}
+	lockIDs = append(lockIDs, modifierStock...)
+	if err := lockStock(ctx, tx, outletID, lockIDs); err != nil {
+		return nil, err
+	}
+	stock := newStockPlan(outletID)
+
 	totalAmount := 0
-	details := make([]models.TransactionDetail, 0, len(items))
+	details := make([]models.TransactionDetail, 0, len(req.Items))
+	earnLines := make([]earnLine, 0, len(req.Items))
+	overrides := make([]overrideLine, 0)
+	lineModifiers := make([][]selectedModifier, 0, len(req.Items))
+	lineStock := make([][]stockUse, 0, len(req.Items))
 
-	for _, item := range items {
+	for _, item := range req.Items {
 		if item.Quantity <= 0 {
 			return nil, fmt.Errorf("quantity harus > 0 (product_id=%d)", item.ProductID)
 		}
 
 		var productName string
 		var price int
-		var stock int
+		var categoryID *int
+		var hasVariants bool
 
-		// ✅ Lock row agar stok aman (race-free)
+		// Stok & harga dipegang varian, bukan induknya. Harga dasar = harga khusus outlet kalau ada.
 		err := tx.QueryRow(ctx,
-			`SELECT name, price, stock FROM products WHERE id = $1 FOR UPDATE`,
-			item.ProductID,
-		).Scan(&productName, &price, &stock)
+			`SELECT p.name, COALESCE(op.price, p.price), p.category_id, EXISTS (SELECT 1 FROM products v WHERE v.parent_id = p.id)
+			 FROM products p
+			 LEFT JOIN outlet_products op ON op.product_id = p.id AND op.outlet_id = $2
+			 WHERE p.id = $1`,
+			item.ProductID, outletID,
+		).Scan(&productName, &price, &categoryID, &hasVariants)
 		if err != nil {
 			return nil, fmt.Errorf("product id %d not found", item.ProductID)
 		}
+		if hasVariants {
+			return nil, fmt.Errorf("pilih varian untuk %s", productName)
+		}
 
-		if stock < item.Quantity {
-			return nil, fmt.Errorf("stok tidak cukup untuk %s (stok=%d, qty=%d)", productName, stock, item.Quantity)
+		// Paket memotong komponennya, produk komposit memotong bahan resepnya,
+		// produk biasa memotong stok sendiri
+		uses := lineStockUse(item.ProductID, item.Quantity, bundles, recipes)
+		if len(uses) > 0 {
+			stock.addUses(uses)
+		} else {
+			stock.add(item.ProductID, item.Quantity)
+		}
+		if len(item.SerialNumbers) > 0 {
+			stock.addSerials(item.ProductID, item.SerialNumbers)
 		}
 
-		subtotal := price * item.Quantity
+		unitPrice, priceListID, err := resolvePrice(ctx, tx, item.ProductID, qtyByProduct[item.ProductID], price, customerPriceList, outletCode)
+		if err != nil {
+			return nil, err
+		}
+
+		// Override harga / diskon baris: dicatat ke audit trail, approval dicek setelah loop
+		originalPrice := unitPrice
+		if item.PriceOverride != nil {
+			if *item.PriceOverride < 0 {
+				return nil, fmt.Errorf("price_override tidak boleh negatif (product_id=%d)", item.ProductID)
+			}
+			if *item.PriceOverride != unitPrice {
+				unitPrice, priceListID = *item.PriceOverride, nil
+			}
+		}
+		overridePrice := unitPrice
+
+		// Modifier (extra shot, oat milk, ...) ditambahkan ke harga satuan baris
+		modifiers, modifierDelta, err := resolveModifiers(ctx, tx, item.ProductID, productName, item.Modifiers)
+		if err != nil {
+			return nil, err
+		}
+		unitPrice += modifierDelta
+		if unitPrice < 0 {
+			return nil, fmt.Errorf("harga %s jadi negatif setelah modifier", productName)
+		}
+		for _, m := range modifiers {
+			if m.stockProductID != nil && m.stockQty > 0 {
+				stock.add(*m.stockProductID, m.stockQty*item.Quantity)
+			}
+		}
+
+		if item.Discount < 0 {
+			return nil, fmt.Errorf("discount tidak boleh negatif (product_id=%d)", item.ProductID)
+		}
+		if item.Discount > unitPrice*item.Quantity {
+			return nil, fmt.Errorf("discount melebihi subtotal %s", productName)
+		}
+		if overridePrice != originalPrice || item.Discount > 0 {
+			overrides = append(overrides, overrideLine{
+				detail:        len(details),
+				originalPrice: originalPrice,
+				overridePrice: overridePrice,
+				reason:        item.Reason,
+			})
+		}
+
+		subtotal := unitPrice*item.Quantity - item.Discount
 		totalAmount += subtotal
 
-		// Update stok
-		_, err = tx.Exec(ctx,
-			`UPDATE products SET stock = stock - $1 WHERE id = $2`,
-			item.Quantity, item.ProductID,
-		)
+		detail := models.TransactionDetail{
+			ProductID:      item.ProductID,
+			ProductName:    productName,
+			Quantity:       item.Quantity,
+			UnitPrice:      unitPrice,
+			PriceListID:    priceListID,
+			DiscountAmount: item.Discount,
+			Subtotal:       subtotal,
+			SerialNumbers:  item.SerialNumbers,
+		}
+		for _, m := range modifiers {
+			detail.Modifiers = append(detail.Modifiers, m.DetailModifier)
+		}
+		details = append(details, detail)
+		lineModifiers = append(lineModifiers, modifiers)
+		lineStock = append(lineStock, uses)
+		earnLines = append(earnLines, earnLine{categoryID: categoryID, subtotal: subtotal})
+	}
+
+	// Cek + potong stok per produk (urut id)
+	if err := stock.apply(ctx, tx); err != nil {
+		return nil, err
+	}
+
+	approvedBy := 0
+	if len(overrides) > 0 {
+		approvedBy, err = resolveApproval(ctx, tx, req, cashierID, cashierRole)
 		if err != nil {
 			return nil, err
 		}
+	}
 
-		details = append(details, models.TransactionDetail{
-			ProductID:   item.ProductID,
-			ProductName: productName,
-			Quantity:    item.Quantity,
-			Subtotal:    subtotal,
-		})
+	// Voucher: diskon level transaksi dari subtotal
+	subtotalAmount := totalAmount
+	discountAmount, voucherCode := 0, ""
+	if req.VoucherCode != "" {
+		discountAmount, err = redeemVoucher(ctx, tx, req.VoucherCode, subtotalAmount)
+		if err != nil {
+			return nil, err
+		}
+		voucherCode = req.VoucherCode
+		totalAmount -= discountAmount
 	}
 
+	payments, paidAmount, changeAmount, err := allocatePayments(totalAmount, req.Payments)
+	if err != nil {
+		return nil, err
+	}
+
+	// ✅ Nomor struk gap-free: counter di-lock sampai commit,
+	// kalau checkout gagal nomornya ikut di-rollback.
+	var seq int
+	err = tx.QueryRow(ctx,
+		`INSERT INTO receipt_counters (outlet_code, business_date, last_seq)
+		 VALUES ($1, $2::date, 1)
+		 ON CONFLICT (outlet_code, business_date)
+		 DO UPDATE SET last_seq = receipt_counters.last_seq + 1
+		 RETURNING last_seq`,
+		outletCode, businessDate.Format("2006-01-02"),
+	).Scan(&seq)
+	if err != nil {
+		return nil, err
+	}
+	receiptNo := r.numbering.format(outletCode, businessDate, seq)
+
 	// Insert transaction header
 	var transactionID int
+	var createdAt time.Time
 	err = tx.QueryRow(ctx,
-		`INSERT INTO transactions (total_amount) VALUES ($1) RETURNING id`,
-		totalAmount,
-	).Scan(&transactionID)
+		`INSERT INTO transactions (total_amount, shift_id, cashier_id, paid_amount, change_amount, outlet_id, outlet_code, receipt_no,
+		 customer_id, discount_amount, voucher_code, business_date)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NULLIF($11, ''), $12::date)
+		 RETURNING id, created_at`,
+		totalAmount, req.ShiftID, cashierID, paidAmount, changeAmount, outletID, outletCode, receiptNo,
+		customerID, discountAmount, voucherCode, businessDate.Format("2006-01-02"),
+	).Scan(&transactionID, &createdAt)
 	if err != nil {
 		return nil, err
 	}
@@ -89,24 +301,728 @@ func (r *TransactionRepository) CreateTransaction(items []models.CheckoutItem) (
 
 		var detailID int
 		err = tx.QueryRow(ctx,
-			`INSERT INTO transaction_details (transaction_id, product_id, quantity, subtotal)
-			 VALUES ($1, $2, $3, $4)
+			`INSERT INTO transaction_details (transaction_id, product_id, quantity, unit_price, price_list_id, discount_amount, subtotal)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7)
 			 RETURNING id`,
-			transactionID, details[i].ProductID, details[i].Quantity, details[i].Subtotal,
+			transactionID, details[i].ProductID, details[i].Quantity, details[i].UnitPrice, details[i].PriceListID,
+			details[i].DiscountAmount, details[i].Subtotal,
 		).Scan(&detailID)
 		if err != nil {
 			return nil, err
 		}
 		details[i].ID = detailID
+
+		if err := insertDetailModifiers(ctx, tx, detailID, details[i].Quantity, lineModifiers[i]); err != nil {
+			return nil, err
+		}
+		if err := insertStockUsage(ctx, tx, transactionID, detailID, lineStock[i]); err != nil {
+			return nil, err
+		}
+		if err := insertSerialSales(ctx, tx, transactionID, detailID, outletID, details[i].ProductID, details[i].SerialNumbers); err != nil {
+			return nil, err
+		}
+		if bundle := bundles[details[i].ProductID]; len(bundle) > 0 {
+			if err := insertBundleAllocations(ctx, tx, transactionID, details[i], bundle); err != nil {
+				return nil, err
+			}
+		}
 	}
 
-	if err := tx.Commit(ctx); err != nil {
+	if err := insertLotUsage(ctx, tx, transactionID, stock.lots); err != nil {
+		return nil, err
+	}
+	if err := stock.recordSale(ctx, tx, transactionID, receiptNo); err != nil {
+		return nil, err
+	}
+
+	if err := insertPriceOverrides(ctx, tx, transactionID, cashierID, approvedBy, details, overrides); err != nil {
+		return nil, err
+	}
+
+	// ✅ Saldo gift card dipotong di transaksi yang sama dengan penjualan
+	if err := redeemGiftCards(ctx, tx, transactionID, req.ShiftID, payments); err != nil {
+		return nil, err
+	}
+
+	for i := range payments {
+		payments[i].TransactionID = transactionID
+
+		err = tx.QueryRow(ctx,
+			`INSERT INTO transaction_payments (transaction_id, method, amount, reference)
+			 VALUES ($1, $2, $3, $4)
+			 RETURNING id`,
+			transactionID, payments[i].Method, payments[i].Amount, payments[i].Reference,
+		).Scan(&payments[i].ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	accountPaid := 0
+	for _, p := range payments {
+		if p.Method == models.PaymentAccount {
+			accountPaid += p.Amount
+		}
+	}
+	if accountPaid > 0 {
+		if customerID == nil {
+			return nil, errors.New("kasbon wajib menyertakan pelanggan")
+		}
+		if err := lockCustomer(ctx, tx, *customerID); err != nil {
+			return nil, err
+		}
+		if err := chargeAccount(ctx, tx, *customerID, transactionID, req.ShiftID, accountPaid); err != nil {
+			return nil, err
+		}
+	}
+
+	pointsEarned, pointsUsed, err := applyLoyalty(ctx, tx, customerID, transactionID, earnLines, totalAmount, payments)
+	if err != nil {
 		return nil, err
 	}
 
+	for i := range stock.low {
+		stock.low[i].OutletCode = outletCode
+	}
+
 	return &models.Transaction{
-		ID:          transactionID,
-		TotalAmount: totalAmount,
-		Details:     details,
+		ID:             transactionID,
+		ReceiptNo:      receiptNo,
+		OutletID:       &outletID,
+		OutletCode:     outletCode,
+		ShiftID:        &req.ShiftID,
+		CashierID:      &cashierID,
+		CustomerID:     customerID,
+		CustomerName:   customerName,
+		VoucherCode:    voucherCode,
+		DiscountAmount: discountAmount,
+		TotalAmount:    totalAmount,
+		PaidAmount:     paidAmount,
+		ChangeAmount:   changeAmount,
+		PointsEarned:   pointsEarned,
+		PointsUsed:     pointsUsed,
+		CreatedAt:      createdAt,
+		Details:        details,
+		Payments:       payments,
+		LowStock:       stock.low,
 	}, nil
 }
+
+const transactionColumns = `t.id, COALESCE(t.receipt_no, ''), t.outlet_id, COALESCE(t.outlet_code, ''), t.shift_id, t.cashier_id,
+	COALESCE(c.name, ''), t.customer_id, COALESCE(cu.name, ''), COALESCE(t.voucher_code, ''), t.discount_amount,
+	t.total_amount, t.paid_amount, t.change_amount, t.created_at, COALESCE(o.timezone, '')`
+
+const transactionFrom = ` FROM transactions t
+	LEFT JOIN outlets o ON o.id = t.outlet_id
+	LEFT JOIN cashiers c ON c.id = t.cashier_id
+	LEFT JOIN customers cu ON cu.id = t.customer_id`
+
+func scanTransaction(row pgx.Row) (*models.Transaction, error) {
+	var t models.Transaction
+	err := row.Scan(&t.ID, &t.ReceiptNo, &t.OutletID, &t.OutletCode, &t.ShiftID, &t.CashierID,
+		&t.CashierName, &t.CustomerID, &t.CustomerName, &t.VoucherCode, &t.DiscountAmount, &t.TotalAmount, &t.PaidAmount, &t.ChangeAmount, &t.CreatedAt,
+		&t.OutletTimezone)
+	if err != nil {
+		return nil, err
+	}
+	t.Details = make([]models.TransactionDetail, 0)
+	t.Payments = make([]models.TransactionPayment, 0)
+	return &t, nil
+}
+
+func (r *TransactionRepository) GetByID(id int) (*models.Transaction, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	t, err := scanTransaction(r.db.QueryRow(ctx,
+		`SELECT `+transactionColumns+transactionFrom+` WHERE t.id=$1`, id))
+	if err != nil {
+		return nil, errors.New("transaksi belum ada")
+	}
+
+	txs := []*models.Transaction{t}
+	if err := loadTransactionLines(ctx, r.db, txs); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// GetRefund = refund milik transaksi (header saja), nil kalau belum di-refund.
+func (r *TransactionRepository) GetRefund(transactionID int) (*models.Refund, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var rf models.Refund
+	err := r.db.QueryRow(ctx,
+		`SELECT id, transaction_id, shift_id, cashier_id, total_amount, reason, created_at
+		 FROM refunds WHERE transaction_id = $1`,
+		transactionID,
+	).Scan(&rf.ID, &rf.TransactionID, &rf.ShiftID, &rf.CashierID, &rf.TotalAmount, &rf.Reason, &rf.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	rf.Payments = make([]models.TransactionPayment, 0)
+	return &rf, nil
+}
+
+func (r *TransactionRepository) GetAll(f models.TransactionFilter) ([]models.Transaction, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	query := `SELECT ` + transactionColumns + transactionFrom + ` WHERE TRUE`
+	args := []any{}
+
+	if f.ReceiptNo != "" {
+		args = append(args, "%"+f.ReceiptNo+"%")
+		query += fmt.Sprintf(` AND t.receipt_no ILIKE $%d`, len(args))
+	}
+	if f.ShiftID > 0 {
+		args = append(args, f.ShiftID)
+		query += fmt.Sprintf(` AND t.shift_id = $%d`, len(args))
+	}
+	if f.CustomerID > 0 {
+		args = append(args, f.CustomerID)
+		query += fmt.Sprintf(` AND t.customer_id = $%d`, len(args))
+	}
+
+	args = append(args, f.Limit)
+	query += fmt.Sprintf(` ORDER BY t.id DESC LIMIT $%d`, len(args))
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	txs := make([]*models.Transaction, 0)
+	for rows.Next() {
+		t, err := scanTransaction(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		txs = append(txs, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := loadTransactionLines(ctx, r.db, txs); err != nil {
+		return nil, err
+	}
+
+	out := make([]models.Transaction, 0, len(txs))
+	for _, t := range txs {
+		out = append(out, *t)
+	}
+	return out, nil
+}
+
+// RecordReceiptPrint mencatat cetak struk dan mengembalikan urutan cetaknya
+// (1 = cetakan asli, >1 = reprint/COPY).
+func (r *TransactionRepository) RecordReceiptPrint(transactionID int, format string) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	var id int
+	err = tx.QueryRow(ctx, `SELECT id FROM transactions WHERE id=$1`, transactionID).Scan(&id)
+	if err != nil {
+		return 0, errors.New("transaksi belum ada")
+	}
+
+	// Advisory lock per transaksi supaya dua cetak bersamaan tidak sama-sama dianggap asli
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('receipt_prints'), $1)`, transactionID); err != nil {
+		return 0, err
+	}
+
+	var printNo int
+	err = tx.QueryRow(ctx,
+		`INSERT INTO receipt_prints (transaction_id, print_no, format)
+		 SELECT $1, COALESCE(MAX(print_no), 0) + 1, $2 FROM receipt_prints WHERE transaction_id = $1
+		 RETURNING print_no`,
+		transactionID, format,
+	).Scan(&printNo)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return printNo, nil
+}
+
+// ReceiptPrintCount = berapa kali struk transaksi sudah dicetak (preview tidak dihitung).
+func (r *TransactionRepository) ReceiptPrintCount(transactionID int) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var n int
+	err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM receipt_prints WHERE transaction_id = $1`, transactionID).Scan(&n)
+	return n, err
+}
+
+// loadTransactionLines mengisi details + payments untuk banyak transaksi sekaligus.
+func loadTransactionLines(ctx context.Context, q querier, txs []*models.Transaction) error {
+	if len(txs) == 0 {
+		return nil
+	}
+	ids := make([]int, 0, len(txs))
+	byID := make(map[int]*models.Transaction, len(txs))
+	for _, t := range txs {
+		ids = append(ids, t.ID)
+		byID[t.ID] = t
+	}
+
+	rows, err := q.Query(ctx, `
+		SELECT td.id, td.transaction_id, td.product_id, COALESCE(p.name, ''), td.quantity,
+			COALESCE(td.unit_price, td.subtotal / NULLIF(td.quantity, 0), 0), td.price_list_id, td.discount_amount, td.subtotal
+		FROM transaction_details td
+		LEFT JOIN products p ON p.id = td.product_id
+		WHERE td.transaction_id = ANY($1)
+		ORDER BY td.id
+	`, ids)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var d models.TransactionDetail
+		if err := rows.Scan(&d.ID, &d.TransactionID, &d.ProductID, &d.ProductName, &d.Quantity, &d.UnitPrice, &d.PriceListID, &d.DiscountAmount, &d.Subtotal); err != nil {
+			rows.Close()
+			return err
+		}
+		byID[d.TransactionID].Details = append(byID[d.TransactionID].Details, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	type detailRef struct {
+		t     *models.Transaction
+		index int
+	}
+	details := make(map[int]detailRef)
+	for _, t := range txs {
+		for i, d := range t.Details {
+			details[d.ID] = detailRef{t: t, index: i}
+		}
+	}
+	rows, err = q.Query(ctx, `
+		SELECT m.transaction_detail_id, m.modifier_id, m.group_name, m.name, m.price_delta
+		FROM transaction_detail_modifiers m
+		JOIN transaction_details td ON td.id = m.transaction_detail_id
+		WHERE td.transaction_id = ANY($1)
+		ORDER BY m.id
+	`, ids)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var detailID int
+		var m models.DetailModifier
+		if err := rows.Scan(&detailID, &m.ModifierID, &m.GroupName, &m.Name, &m.PriceDelta); err != nil {
+			rows.Close()
+			return err
+		}
+		ref := details[detailID]
+		ref.t.Details[ref.index].Modifiers = append(ref.t.Details[ref.index].Modifiers, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = q.Query(ctx, `
+		SELECT ts.transaction_detail_id, s.serial_number
+		FROM transaction_serials ts
+		JOIN product_serials s ON s.id = ts.serial_id
+		WHERE ts.transaction_id = ANY($1)
+		ORDER BY ts.transaction_detail_id, s.serial_number
+	`, ids)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var detailID int
+		var sn string
+		if err := rows.Scan(&detailID, &sn); err != nil {
+			rows.Close()
+			return err
+		}
+		ref := details[detailID]
+		ref.t.Details[ref.index].SerialNumbers = append(ref.t.Details[ref.index].SerialNumbers, sn)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = q.Query(ctx, `
+		SELECT id, transaction_id, method, amount, reference
+		FROM transaction_payments
+		WHERE transaction_id = ANY($1)
+		ORDER BY id
+	`, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var p models.TransactionPayment
+		if err := rows.Scan(&p.ID, &p.TransactionID, &p.Method, &p.Amount, &p.Reference); err != nil {
+			return err
+		}
+		byID[p.TransactionID].Payments = append(byID[p.TransactionID].Payments, p)
+	}
+	return rows.Err()
+}
+
+// applyLoyalty menukar poin (tender "points") lalu mencatat poin yang didapat.
+func applyLoyalty(ctx context.Context, q querier, customerID *int, transactionID int, lines []earnLine, total int, payments []models.TransactionPayment) (int, int, error) {
+	pointsPaid := 0
+	for _, p := range payments {
+		if p.Method == models.PaymentPoints {
+			pointsPaid += p.Amount
+		}
+	}
+	if customerID == nil {
+		if pointsPaid > 0 {
+			return 0, 0, errors.New("bayar pakai poin wajib menyertakan pelanggan")
+		}
+		return 0, 0, nil
+	}
+
+	settings, err := loadLoyaltySettings(ctx, q)
+	if err != nil {
+		return 0, 0, err
+	}
+	if !settings.Enabled {
+		if pointsPaid > 0 {
+			return 0, 0, errors.New("program poin sedang nonaktif")
+		}
+		return 0, 0, nil
+	}
+
+	if err := lockCustomer(ctx, q, *customerID); err != nil {
+		return 0, 0, err
+	}
+
+	pointsUsed := 0
+	if pointsPaid > 0 {
+		if pointsPaid%settings.PointValue != 0 {
+			return 0, 0, fmt.Errorf("pembayaran poin harus kelipatan Rp%d", settings.PointValue)
+		}
+		pointsUsed = pointsPaid / settings.PointValue
+		if err := redeemPoints(ctx, q, *customerID, transactionID, pointsUsed); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	// lines masih harga sebelum diskon voucher, jadi poin dihitung proporsional dari subtotal
+	subtotal := 0
+	for _, l := range lines {
+		subtotal += l.subtotal
+	}
+	earned, err := earnPoints(ctx, q, settings, *customerID, transactionID, lines, subtotal, total-pointsPaid)
+	if err != nil {
+		return 0, 0, err
+	}
+	return earned, pointsUsed, nil
+}
+
+// refundStockSource = semua stok yang dipotong transaksi $1 (product_id, qty):
+// barang biasa, bahan resep / komponen paket, dan stok modifier.
+const refundStockSource = `(
+		SELECT td.product_id, td.quantity AS qty FROM transaction_details td
+		WHERE td.transaction_id = $1
+		  AND NOT EXISTS (SELECT 1 FROM transaction_ingredient_usage u WHERE u.transaction_detail_id = td.id)
+		UNION ALL
+		SELECT ingredient_id, quantity FROM transaction_ingredient_usage WHERE transaction_id = $1
+		UNION ALL
+		SELECT m.stock_product_id, m.stock_qty
+		FROM transaction_detail_modifiers m
+		JOIN transaction_details td ON td.id = m.transaction_detail_id
+		WHERE td.transaction_id = $1 AND m.stock_product_id IS NOT NULL
+	) s`
+
+// Refund membatalkan seluruh transaksi: stok dikembalikan, tender dikembalikan
+// lewat metode yang sama (cash keluar dari laci shift yang melakukan refund,
+// kasbon mengurangi piutang, gift card diisi kembali), dan poin loyalitas dibalik.
+func (r *TransactionRepository) Refund(transactionID int, req models.RefundRequest) (*models.Refund, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var cashierID, shiftOutletID int
+	var shiftStatus string
+	err = tx.QueryRow(ctx,
+		`SELECT cashier_id, status, outlet_id FROM shifts WHERE id = $1 FOR SHARE`,
+		req.ShiftID,
+	).Scan(&cashierID, &shiftStatus, &shiftOutletID)
+	if err != nil {
+		return nil, fmt.Errorf("shift id %d not found", req.ShiftID)
+	}
+	if shiftStatus != models.ShiftOpen {
+		return nil, errors.New("shift sudah ditutup, buka shift baru dulu")
+	}
+
+	// Lock header transaksi supaya tidak di-refund dua kali bersamaan.
+	// Refund masuk hari bisnis berjalan di outlet transaksi aslinya.
+	var customerID *int
+	var totalAmount, outletID int
+	var voucherCode *string
+	var businessDate time.Time
+	err = tx.QueryRow(ctx,
+		`SELECT customer_id, total_amount, voucher_code, outlet_id, outlet_business_date(now(), outlet_id)
+		 FROM transactions WHERE id = $1 FOR UPDATE`,
+		transactionID,
+	).Scan(&customerID, &totalAmount, &voucherCode, &outletID, &businessDate)
+	if err != nil {
+		return nil, errors.New("transaksi belum ada")
+	}
+	// Cash refund keluar dari laci shift, stok kembali ke outlet transaksi: keduanya harus outlet yang sama
+	if shiftOutletID != outletID {
+		return nil, errors.New("refund harus dilakukan dari shift di outlet transaksi")
+	}
+	if z, closed, err := closedZNumber(ctx, tx, businessDate); err != nil {
+		return nil, err
+	} else if closed {
+		return nil, fmt.Errorf("hari bisnis sudah ditutup (Z #%d)", z)
+	}
+
+	var refundedID int
+	err = tx.QueryRow(ctx, `SELECT id FROM refunds WHERE transaction_id = $1`, transactionID).Scan(&refundedID)
+	if err == nil {
+		return nil, fmt.Errorf("transaksi sudah di-refund (refund_id=%d)", refundedID)
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+
+	// Kembalikan stok (produk + stok yang dipotong modifier) ke outlet transaksi aslinya;
+	// lock baris stok urut product_id dulu supaya urutan lock konsisten dengan checkout
+	_, err = tx.Exec(ctx, `
+		SELECT product_id FROM outlet_products
+		WHERE outlet_id = $2 AND product_id IN (SELECT product_id FROM `+refundStockSource+`)
+		ORDER BY product_id
+		FOR UPDATE
+	`, transactionID, outletID)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO outlet_products (outlet_id, product_id, stock)
+		SELECT $2::int, product_id, SUM(qty)
+		FROM `+refundStockSource+`
+		WHERE EXISTS (SELECT 1 FROM products p WHERE p.id = s.product_id)
+		GROUP BY product_id
+		ORDER BY product_id
+		ON CONFLICT (outlet_id, product_id) DO UPDATE SET stock = outlet_products.stock + EXCLUDED.stock
+	`, transactionID, outletID)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO stock_movements (outlet_id, product_id, quantity, kind, transaction_id, note)
+		SELECT $2::int, product_id, SUM(qty), $3, $1, $4
+		FROM `+refundStockSource+`
+		WHERE EXISTS (SELECT 1 FROM products p WHERE p.id = s.product_id)
+		GROUP BY product_id
+		ORDER BY product_id
+	`, transactionID, outletID, models.MovementRefund, req.Reason)
+	if err != nil {
+		return nil, err
+	}
+	// Lot yang dipotong checkout kembali ke lot asalnya
+	_, err = tx.Exec(ctx, `
+		UPDATE stock_lots l SET quantity = l.quantity + u.quantity
+		FROM transaction_lot_usage u
+		WHERE u.transaction_id = $1 AND l.id = u.lot_id
+	`, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	// Unit ber-serial kembali ke stok outlet transaksi
+	_, err = tx.Exec(ctx, `
+		UPDATE product_serials s SET status = $3, outlet_id = $2
+		FROM transaction_serials ts
+		WHERE ts.transaction_id = $1 AND s.id = ts.serial_id
+	`, transactionID, outletID, models.SerialInStock)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO serial_events (serial_id, kind, outlet_id, transaction_id)
+		SELECT serial_id, $3, $2, $1 FROM transaction_serials WHERE transaction_id = $1
+	`, transactionID, outletID, models.SerialEventReturned)
+	if err != nil {
+		return nil, err
+	}
+
+	refund := models.Refund{
+		TransactionID: transactionID,
+		ShiftID:       req.ShiftID,
+		CashierID:     cashierID,
+		TotalAmount:   totalAmount,
+		Reason:        req.Reason,
+		Payments:      make([]models.TransactionPayment, 0),
+	}
+	err = tx.QueryRow(ctx,
+		`INSERT INTO refunds (transaction_id, shift_id, cashier_id, total_amount, reason, business_date)
+		 VALUES ($1, $2, $3, $4, $5, $6::date)
+		 RETURNING id, created_at`,
+		transactionID, req.ShiftID, cashierID, totalAmount, req.Reason, businessDate.Format("2006-01-02"),
+	).Scan(&refund.ID, &refund.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx,
+		`INSERT INTO refund_payments (refund_id, method, amount)
+		 SELECT $1, method, SUM(amount) FROM transaction_payments
+		 WHERE transaction_id = $2
+		 GROUP BY method
+		 RETURNING id, method, amount`,
+		refund.ID, transactionID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		p := models.TransactionPayment{TransactionID: transactionID}
+		if err := rows.Scan(&p.ID, &p.Method, &p.Amount); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		refund.Payments = append(refund.Payments, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := restoreGiftCards(ctx, tx, transactionID, req.ShiftID); err != nil {
+		return nil, err
+	}
+
+	// Kuota voucher dikembalikan
+	if voucherCode != nil {
+		_, err := tx.Exec(ctx,
+			`UPDATE vouchers SET used_count = used_count - 1 WHERE code = $1 AND used_count > 0`,
+			*voucherCode,
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if customerID != nil {
+		for _, p := range refund.Payments {
+			if p.Method != models.PaymentAccount {
+				continue
+			}
+			if err := reverseReceivable(ctx, tx, *customerID, transactionID, req.ShiftID, p.Amount); err != nil {
+				return nil, err
+			}
+		}
+		if err := reverseLoyalty(ctx, tx, *customerID, transactionID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return &refund, nil
+}
+
+// resolveCustomer mencari pelanggan dari customer_id atau no HP (sudah dinormalisasi).
+// Dua-duanya kosong = transaksi anonim.
+// Ikut dikembalikan price list pelanggan (tier member/grosir) kalau ada.
+func resolveCustomer(ctx context.Context, q querier, id *int, phone string) (*int, string, *int, error) {
+	var customerID int
+	var name string
+	var priceListID *int
+	switch {
+	case id != nil:
+		err := q.QueryRow(ctx, `SELECT id, name, price_list_id FROM customers WHERE id = $1`, *id).Scan(&customerID, &name, &priceListID)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("customer id %d not found", *id)
+		}
+	case phone != "":
+		err := q.QueryRow(ctx, `SELECT id, name, price_list_id FROM customers WHERE phone = $1`, phone).Scan(&customerID, &name, &priceListID)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("pelanggan dengan no HP %s belum terdaftar", phone)
+		}
+	default:
+		return nil, "", nil, nil
+	}
+	return &customerID, name, priceListID, nil
+}
+
+// allocatePayments mencocokkan tender dengan total belanja.
+// Tanpa tender = dianggap cash pas. Kembalian hanya boleh dari cash,
+// dan amount cash yang disimpan sudah net (dikurangi kembalian).
+func allocatePayments(total int, in []models.CheckoutPayment) ([]models.TransactionPayment, int, int, error) {
+	if len(in) == 0 {
+		return []models.TransactionPayment{{Method: models.PaymentCash, Amount: total}}, total, 0, nil
+	}
+
+	paid := 0
+	cash := 0
+	out := make([]models.TransactionPayment, 0, len(in))
+	for _, p := range in {
+		switch p.Method {
+		case models.PaymentCash, models.PaymentCard, models.PaymentQRIS, models.PaymentTransfer, models.PaymentPoints, models.PaymentAccount:
+		case models.PaymentGiftCard:
+			if p.Reference == "" {
+				return nil, 0, 0, errors.New("pembayaran gift card wajib menyertakan kode (reference)")
+			}
+		default:
+			return nil, 0, 0, fmt.Errorf("metode pembayaran %q tidak dikenal", p.Method)
+		}
+		if p.Amount <= 0 {
+			return nil, 0, 0, fmt.Errorf("amount pembayaran %s harus > 0", p.Method)
+		}
+		paid += p.Amount
+		if p.Method == models.PaymentCash {
+			cash += p.Amount
+		}
+		out = append(out, models.TransactionPayment{Method: p.Method, Amount: p.Amount, Reference: p.Reference})
+	}
+
+	if paid < total {
+		return nil, 0, 0, fmt.Errorf("pembayaran kurang (total=%d, dibayar=%d)", total, paid)
+	}
+	change := paid - total
+	if change > cash {
+		return nil, 0, 0, errors.New("kembalian hanya bisa dari pembayaran cash")
+	}
+
+	// kurangi kembalian dari tender cash, mulai dari yang terakhir
+	rest := change
+	for i := len(out) - 1; i >= 0 && rest > 0; i-- {
+		if out[i].Method != models.PaymentCash {
+			continue
+		}
+		cut := min(out[i].Amount, rest)
+		out[i].Amount -= cut
+		rest -= cut
+	}
+
+	return out, paid, change, nil
+}
@@ -0,0 +1,166 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"kasir-api/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type VoucherRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewVoucherRepository(db *pgxpool.Pool) *VoucherRepository {
+	return &VoucherRepository{db: db}
+}
+
+const voucherColumns = `id, code, name, type, value, min_spend, max_discount, starts_at, ends_at,
+	usage_limit, used_count, active, created_at`
+
+func scanVoucher(row pgx.Row) (*models.Voucher, error) {
+	var v models.Voucher
+	err := row.Scan(&v.ID, &v.Code, &v.Name, &v.Type, &v.Value, &v.MinSpend, &v.MaxDiscount, &v.StartsAt, &v.EndsAt,
+		&v.UsageLimit, &v.UsedCount, &v.Active, &v.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func (r *VoucherRepository) GetAll() ([]models.Voucher, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := r.db.Query(ctx, `SELECT `+voucherColumns+` FROM vouchers ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]models.Voucher, 0)
+	for rows.Next() {
+		v, err := scanVoucher(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *v)
+	}
+	return out, nil
+}
+
+func (r *VoucherRepository) Create(v *models.Voucher) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := r.db.QueryRow(ctx,
+		`INSERT INTO vouchers (code, name, type, value, min_spend, max_discount, starts_at, ends_at, usage_limit, active)
+		 VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)
+		 RETURNING id, used_count, created_at`,
+		v.Code, v.Name, v.Type, v.Value, v.MinSpend, v.MaxDiscount, v.StartsAt, v.EndsAt, v.UsageLimit, v.Active,
+	).Scan(&v.ID, &v.UsedCount, &v.CreatedAt)
+	if isUniqueViolation(err) {
+		return errors.New("kode voucher sudah dipakai")
+	}
+	return err
+}
+
+func (r *VoucherRepository) GetByID(id int) (*models.Voucher, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	v, err := scanVoucher(r.db.QueryRow(ctx, `SELECT `+voucherColumns+` FROM vouchers WHERE id=$1`, id))
+	if err != nil {
+		return nil, errors.New("voucher belum ada")
+	}
+	return v, nil
+}
+
+func (r *VoucherRepository) Update(v *models.Voucher) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := r.db.QueryRow(ctx,
+		`UPDATE vouchers
+		 SET code=$1, name=$2, type=$3, value=$4, min_spend=$5, max_discount=$6, starts_at=$7, ends_at=$8,
+		     usage_limit=$9, active=$10
+		 WHERE id=$11
+		 RETURNING used_count, created_at`,
+		v.Code, v.Name, v.Type, v.Value, v.MinSpend, v.MaxDiscount, v.StartsAt, v.EndsAt, v.UsageLimit, v.Active, v.ID,
+	).Scan(&v.UsedCount, &v.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return errors.New("voucher belum ada")
+	}
+	if isUniqueViolation(err) {
+		return errors.New("kode voucher sudah dipakai")
+	}
+	return err
+}
+
+func (r *VoucherRepository) Delete(id int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	ct, err := r.db.Exec(ctx, `DELETE FROM vouchers WHERE id=$1`, id)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return errors.New("voucher belum ada")
+	}
+	return nil
+}
+
+// voucherDiscount memvalidasi voucher terhadap subtotal dan menghitung potongannya.
+func voucherDiscount(v *models.Voucher, subtotal int, now time.Time) (int, error) {
+	switch {
+	case !v.Active:
+		return 0, fmt.Errorf("voucher %s tidak aktif", v.Code)
+	case v.StartsAt != nil && now.Before(*v.StartsAt):
+		return 0, fmt.Errorf("voucher %s belum berlaku", v.Code)
+	case v.EndsAt != nil && !now.Before(*v.EndsAt):
+		return 0, fmt.Errorf("voucher %s sudah berakhir", v.Code)
+	case v.UsageLimit > 0 && v.UsedCount >= v.UsageLimit:
+		return 0, fmt.Errorf("kuota voucher %s sudah habis", v.Code)
+	case subtotal < v.MinSpend:
+		return 0, fmt.Errorf("voucher %s butuh minimal belanja %d", v.Code, v.MinSpend)
+	}
+
+	discount := v.Value
+	if v.Type == models.VoucherPercent {
+		discount = subtotal * v.Value / 100
+	}
+	if v.MaxDiscount > 0 {
+		discount = min(discount, v.MaxDiscount)
+	}
+	return min(discount, subtotal), nil
+}
+
+// previewVoucher = hitung diskon tanpa memakai kuota (untuk preview cart).
+func previewVoucher(ctx context.Context, q querier, code string, subtotal int) (int, error) {
+	v, err := scanVoucher(q.QueryRow(ctx, `SELECT `+voucherColumns+` FROM vouchers WHERE code=$1`, code))
+	if err != nil {
+		return 0, fmt.Errorf("voucher %s belum ada", code)
+	}
+	return voucherDiscount(v, subtotal, time.Now())
+}
+
+// redeemVoucher dipanggil saat checkout: voucher di-lock supaya kuota tidak terpakai lebih.
+func redeemVoucher(ctx context.Context, q querier, code string, subtotal int) (int, error) {
+	v, err := scanVoucher(q.QueryRow(ctx, `SELECT `+voucherColumns+` FROM vouchers WHERE code=$1 FOR UPDATE`, code))
+	if err != nil {
+		return 0, fmt.Errorf("voucher %s belum ada", code)
+	}
+	discount, err := voucherDiscount(v, subtotal, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	if _, err := q.Exec(ctx, `UPDATE vouchers SET used_count = used_count + 1 WHERE id=$1`, v.ID); err != nil {
+		return 0, err
+	}
+	return discount, nil
+}
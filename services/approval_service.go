@@ -0,0 +1,98 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"kasir-api/models"
+	"kasir-api/repositories"
+	"time"
+)
+
+// Token approval berlaku singkat dan hanya sekali pakai.
+const approvalTokenTTL = 5 * time.Minute
+
+// PIN manager dikunci sementara setelah beberapa kali salah berturut-turut.
+const (
+	maxPINFailures = 5
+	pinLockout     = 15 * time.Minute
+)
+
+type ApprovalService struct {
+	repo    *repositories.ApprovalRepository
+	reports *repositories.ReportRepository // hari bisnis outlet untuk filter periode
+}
+
+func NewApprovalService(repo *repositories.ApprovalRepository, reports *repositories.ReportRepository) *ApprovalService {
+	return &ApprovalService{repo: repo, reports: reports}
+}
+
+// Verify mengecek PIN manager dan mengembalikan id manager yang meng-approve.
+// source = asal percobaan, dicatat di audit kalau PIN salah.
+func (s *ApprovalService) Verify(a models.Approval, source string) (int, error) {
+	if a.ManagerID <= 0 || a.PIN == "" {
+		return 0, errors.New("manager_id dan pin wajib diisi")
+	}
+	check := func(hash string) bool { return checkPIN(hash, a.PIN) }
+	if err := s.repo.VerifyManagerPIN(a.ManagerID, source, check, maxPINFailures, pinLockout); err != nil {
+		return 0, err
+	}
+	return a.ManagerID, nil
+}
+
+// CheckoutApproval mengecek PIN manager di approval checkout; token dipakai di dalam tx checkout.
+// nil = tidak ada PIN yang perlu dicek.
+func (s *ApprovalService) CheckoutApproval(a *models.Approval, source string) (*int, error) {
+	if a == nil || a.Token != "" {
+		return nil, nil
+	}
+	managerID, err := s.Verify(*a, source)
+	if err != nil {
+		return nil, err
+	}
+	return &managerID, nil
+}
+
+// IssueToken = manager memasukkan PIN sekali di perangkatnya, kasir cukup mengirim token.
+func (s *ApprovalService) IssueToken(a models.Approval) (*models.ApprovalToken, error) {
+	managerID, err := s.Verify(a, models.ApprovalSourceToken)
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	t := models.ApprovalToken{
+		Token:     hex.EncodeToString(buf),
+		ManagerID: managerID,
+		ExpiresAt: time.Now().Add(approvalTokenTTL),
+	}
+	if err := s.repo.CreateToken(&t); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// PriceOverrides periode from..to (YYYY-MM-DD, hari bisnis outlet default, inklusif). Default: hari ini.
+func (s *ApprovalService) PriceOverrides(from, to string, cashierID, approvedBy int) ([]models.PriceOverride, error) {
+	start, end, err := businessRange(s.reports, from, to, nil, func(today time.Time) time.Time { return today })
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.PriceOverrides(models.PriceOverrideFilter{
+		Start:      start,
+		End:        end,
+		CashierID:  cashierID,
+		ApprovedBy: approvedBy,
+	})
+}
+
+// Failures periode from..to (YYYY-MM-DD, hari bisnis outlet default, inklusif). Default: hari ini.
+func (s *ApprovalService) Failures(from, to string, managerID int) ([]models.ApprovalFailure, error) {
+	start, end, err := businessRange(s.reports, from, to, nil, func(today time.Time) time.Time { return today })
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.Failures(models.ApprovalFailureFilter{Start: start, End: end, ManagerID: managerID})
+}
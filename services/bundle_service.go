@@ -0,0 +1,30 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+type BundleService struct {
+	repo *repositories.BundleRepository
+}
+
+func NewBundleService(repo *repositories.BundleRepository) *BundleService {
+	return &BundleService{repo: repo}
+}
+
+func (s *BundleService) Get(productID int) ([]models.BundleItem, error) { return s.repo.Get(productID) }
+
+func (s *BundleService) Set(productID int, items []models.BundleItem) error {
+	for _, it := range items {
+		if it.Quantity <= 0 {
+			return fmt.Errorf("quantity isi paket harus > 0 (product_id=%d)", it.ProductID)
+		}
+		if it.ProductID == productID {
+			return errors.New("paket tidak bisa berisi dirinya sendiri")
+		}
+	}
+	return s.repo.Set(productID, items)
+}
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"kasir-api/models"
+	"kasir-api/repositories"
+	"math"
+	"sort"
+)
+
+type CartService struct {
+	repo      *repositories.CartRepository
+	approvals *ApprovalService
+	alerts    *StockAlertService
+	taxRate   float64 // persen PPN, harga sudah termasuk pajak
+}
+
+func NewCartService(repo *repositories.CartRepository, approvals *ApprovalService, alerts *StockAlertService, taxRate float64) *CartService {
+	return &CartService{repo: repo, approvals: approvals, alerts: alerts, taxRate: taxRate}
+}
+
+// withTax mengisi rincian PPN yang sudah termasuk di total preview.
+func (s *CartService) withTax(c *models.Cart, err error) (*models.Cart, error) {
+	if err != nil {
+		return nil, err
+	}
+	if c.Preview != nil && s.taxRate > 0 {
+		c.Preview.TaxRate = s.taxRate
+		c.Preview.Tax = int(math.Round(float64(c.Preview.Total) * s.taxRate / (100 + s.taxRate)))
+	}
+	return c, nil
+}
+
+func normalizeCartRequest(req *models.CartRequest) {
+	if req.CustomerPhone != "" {
+		req.CustomerPhone = NormalizePhone(req.CustomerPhone)
+	}
+	if req.VoucherCode != nil {
+		code := NormalizeVoucherCode(*req.VoucherCode)
+		req.VoucherCode = &code
+	}
+}
+
+func (s *CartService) GetAll(status string) ([]models.Cart, error) {
+	switch status {
+	case "", models.CartOpen, models.CartParked, models.CartCheckedOut, models.CartCancelled:
+	default:
+		return nil, fmt.Errorf("status %q tidak dikenal", status)
+	}
+	return s.repo.GetAll(status)
+}
+
+func (s *CartService) Create(req models.CartRequest) (*models.Cart, error) {
+	normalizeCartRequest(&req)
+	return s.withTax(s.repo.Create(req))
+}
+
+func (s *CartService) GetByID(id int) (*models.Cart, error) { return s.withTax(s.repo.GetByID(id)) }
+
+func (s *CartService) Update(id int, req models.CartRequest) (*models.Cart, error) {
+	normalizeCartRequest(&req)
+	return s.withTax(s.repo.Update(id, req))
+}
+
+// normalizeCartItem merapikan serial & modifier dan mengecek override / diskon baris.
+func normalizeCartItem(item *models.CheckoutItem) error {
+	if item.PriceOverride != nil && *item.PriceOverride < 0 {
+		return errors.New("price_override tidak boleh negatif")
+	}
+	if item.Discount < 0 {
+		return errors.New("discount tidak boleh negatif")
+	}
+	serials, err := normalizeSerials(item.SerialNumbers)
+	if err != nil {
+		return err
+	}
+	item.SerialNumbers = serials
+	item.Modifiers = sortedModifiers(item.Modifiers)
+	return nil
+}
+
+func (s *CartService) AddItem(id int, item models.CheckoutItem) (*models.Cart, error) {
+	if item.Quantity <= 0 {
+		return nil, errors.New("quantity harus > 0")
+	}
+	if err := normalizeCartItem(&item); err != nil {
+		return nil, err
+	}
+	return s.withTax(s.repo.AddItem(id, item))
+}
+
+func (s *CartService) SetItem(id, itemID int, item models.CheckoutItem) (*models.Cart, error) {
+	if item.Quantity < 0 {
+		return nil, errors.New("quantity tidak boleh negatif")
+	}
+	if err := normalizeCartItem(&item); err != nil {
+		return nil, err
+	}
+	return s.withTax(s.repo.SetItem(id, itemID, item))
+}
+
+func (s *CartService) RemoveItem(id, itemID int) (*models.Cart, error) {
+	return s.withTax(s.repo.RemoveItem(id, itemID))
+}
+
+// sortedModifiers menyamakan urutan modifier supaya pilihan yang sama masuk ke baris yang sama.
+func sortedModifiers(ids []int) []int {
+	out := append([]int{}, ids...)
+	sort.Ints(out)
+	return out
+}
+
+func (s *CartService) Park(id int) (*models.Cart, error) { return s.withTax(s.repo.Park(id)) }
+
+func (s *CartService) Resume(id int, terminal string) (*models.Cart, error) {
+	return s.withTax(s.repo.Resume(id, terminal))
+}
+
+func (s *CartService) Cancel(id int) (*models.Cart, error) { return s.withTax(s.repo.Cancel(id)) }
+
+func (s *CartService) Checkout(id int, req models.CartCheckoutRequest) (*models.Transaction, error) {
+	if req.ShiftID <= 0 {
+		return nil, errors.New("shift_id wajib diisi")
+	}
+	for i := range req.Payments {
+		if req.Payments[i].Method == models.PaymentGiftCard {
+			req.Payments[i].Reference = NormalizeGiftCardCode(req.Payments[i].Reference)
+		}
+	}
+
+	// PIN manager dicek di sini; token approval dipakai di dalam tx checkout
+	approvedBy, err := s.approvals.CheckoutApproval(req.Approval, models.ApprovalSourceCart)
+	if err != nil {
+		return nil, err
+	}
+	req.ApprovedBy = approvedBy
+
+	t, err := s.repo.Checkout(id, req)
+	if err != nil {
+		return nil, err
+	}
+	s.alerts.Notify(t.LowStock)
+	return t, nil
+}
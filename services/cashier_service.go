@@ -0,0 +1,54 @@
+package services
+
+import (
+	"errors"
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+type CashierService struct {
+	repo *repositories.CashierRepository
+}
+
+func NewCashierService(repo *repositories.CashierRepository) *CashierService {
+	return &CashierService{repo: repo}
+}
+
+func validateCashier(c *models.Cashier) error {
+	if c.Name == "" {
+		return errors.New("name wajib diisi")
+	}
+	if c.Role == "" {
+		c.Role = models.RoleCashier
+	}
+	if c.Role != models.RoleCashier && c.Role != models.RoleManager {
+		return errors.New("role harus cashier atau manager")
+	}
+	if c.PIN != "" {
+		hash, err := hashPIN(c.PIN)
+		if err != nil {
+			return err
+		}
+		c.PINHash = hash
+		c.PIN = ""
+	}
+	return nil
+}
+
+func (s *CashierService) GetAll() ([]models.Cashier, error) { return s.repo.GetAll() }
+func (s *CashierService) Create(c *models.Cashier) error {
+	if err := validateCashier(c); err != nil {
+		return err
+	}
+	return s.repo.Create(c)
+}
+func (s *CashierService) GetByID(id int) (*models.Cashier, error) {
+	return s.repo.GetByID(id)
+}
+func (s *CashierService) Update(c *models.Cashier) error {
+	if err := validateCashier(c); err != nil {
+		return err
+	}
+	return s.repo.Update(c)
+}
+func (s *CashierService) Delete(id int) error { return s.repo.Delete(id) }
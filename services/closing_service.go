@@ -0,0 +1,33 @@
+package services
+
+import (
+	"errors"
+	"kasir-api/models"
+	"kasir-api/repositories"
+	"time"
+)
+
+type ClosingService struct {
+	repo    *repositories.ClosingRepository
+	taxRate float64 // persen PPN, disimpan di snapshot Z
+}
+
+func NewClosingService(repo *repositories.ClosingRepository, taxRate float64) *ClosingService {
+	return &ClosingService{repo: repo, taxRate: taxRate}
+}
+
+// CloseDay membuat Z-report. Date kosong = hari bisnis berjalan di outlet default;
+// hari yang belum dimulai di semua outlet aktif tidak bisa ditutup.
+func (s *ClosingService) CloseDay(req models.CloseDayRequest) (*models.ZReport, error) {
+	if req.Date != "" {
+		if _, err := time.Parse("2006-01-02", req.Date); err != nil {
+			return nil, errors.New("format date harus YYYY-MM-DD")
+		}
+	}
+	return s.repo.CloseDay(req.Date, req.ClosedBy, s.taxRate)
+}
+
+func (s *ClosingService) GetAll() ([]models.ZReport, error) { return s.repo.GetAll() }
+func (s *ClosingService) GetByNumber(number int, outletID *int) (*models.ZReport, error) {
+	return s.repo.GetByNumber(number, outletID)
+}
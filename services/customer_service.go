@@ -0,0 +1,109 @@
+package services
+
+import (
+	"errors"
+	"kasir-api/models"
+	"kasir-api/repositories"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+type CustomerService struct {
+	repo   *repositories.CustomerRepository
+	txRepo *repositories.TransactionRepository
+}
+
+func NewCustomerService(repo *repositories.CustomerRepository, txRepo *repositories.TransactionRepository) *CustomerService {
+	return &CustomerService{repo: repo, txRepo: txRepo}
+}
+
+// NormalizePhone menyeragamkan no HP ke format 62xxxx:
+// "0812-3456 789", "+62 812 3456789" dan "62812..." jadi sama.
+func NormalizePhone(phone string) string {
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, phone)
+
+	switch {
+	case strings.HasPrefix(digits, "0"):
+		return "62" + digits[1:]
+	case strings.HasPrefix(digits, "8"):
+		return "62" + digits
+	default:
+		return digits
+	}
+}
+
+func validateCustomer(c *models.Customer) error {
+	c.Name = strings.TrimSpace(c.Name)
+	if c.Name == "" {
+		return errors.New("name wajib diisi")
+	}
+	c.Phone = NormalizePhone(c.Phone)
+	if len(c.Phone) < 9 {
+		return errors.New("phone wajib diisi dan harus no HP yang valid")
+	}
+	if c.Email != "" {
+		if _, err := mail.ParseAddress(c.Email); err != nil {
+			return errors.New("email tidak valid")
+		}
+	}
+	if c.CreditLimit < 0 {
+		return errors.New("credit_limit tidak boleh negatif")
+	}
+	if c.Birthday != nil {
+		if *c.Birthday == "" {
+			c.Birthday = nil
+		} else if _, err := time.Parse("2006-01-02", *c.Birthday); err != nil {
+			return errors.New("format birthday harus YYYY-MM-DD")
+		}
+	}
+	return nil
+}
+
+func (s *CustomerService) GetAll(search string) ([]models.Customer, error) {
+	return s.repo.GetAll(search)
+}
+
+func (s *CustomerService) Create(c *models.Customer) error {
+	if err := validateCustomer(c); err != nil {
+		return err
+	}
+	return s.repo.Create(c)
+}
+
+func (s *CustomerService) GetByID(id int) (*models.Customer, error) { return s.repo.GetByID(id) }
+
+func (s *CustomerService) GetByPhone(phone string) (*models.Customer, error) {
+	return s.repo.GetByPhone(NormalizePhone(phone))
+}
+
+func (s *CustomerService) Update(c *models.Customer) error {
+	if err := validateCustomer(c); err != nil {
+		return err
+	}
+	return s.repo.Update(c)
+}
+
+func (s *CustomerService) Delete(id int) error { return s.repo.Delete(id) }
+
+func (s *CustomerService) Transactions(id int, limit int) ([]models.Transaction, error) {
+	if _, err := s.repo.GetByID(id); err != nil {
+		return nil, err
+	}
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	return s.txRepo.GetAll(models.TransactionFilter{CustomerID: id, Limit: limit})
+}
+
+func (s *CustomerService) Stats(id int) (*models.CustomerStats, error) {
+	if _, err := s.repo.GetByID(id); err != nil {
+		return nil, err
+	}
+	return s.repo.Stats(id, 5)
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"kasir-api/models"
+	"kasir-api/repositories"
+	"strings"
+)
+
+type GiftCardService struct {
+	repo *repositories.GiftCardRepository
+}
+
+func NewGiftCardService(repo *repositories.GiftCardRepository) *GiftCardService {
+	return &GiftCardService{repo: repo}
+}
+
+// NormalizeGiftCardCode: "abcd-efgh 1234" -> "ABCDEFGH1234".
+func NormalizeGiftCardCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return -1
+		}
+	}, code)
+}
+
+// tanpa 0/O/1/I supaya tidak salah ketik
+const giftCardAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+func newGiftCardCode() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	for i, b := range buf {
+		buf[i] = giftCardAlphabet[int(b)%len(giftCardAlphabet)]
+	}
+	return string(buf), nil
+}
+
+func validateTender(method string) error {
+	switch method {
+	case models.PaymentCash, models.PaymentCard, models.PaymentQRIS, models.PaymentTransfer:
+		return nil
+	default:
+		return fmt.Errorf("metode pembayaran %q tidak bisa dipakai membeli gift card", method)
+	}
+}
+
+func (s *GiftCardService) Issue(req models.IssueGiftCardRequest) (*models.GiftCard, error) {
+	if req.ShiftID <= 0 {
+		return nil, errors.New("shift_id wajib diisi")
+	}
+	if req.Amount <= 0 {
+		return nil, errors.New("amount harus > 0")
+	}
+	if req.Method == "" {
+		req.Method = models.PaymentCash
+	}
+	if err := validateTender(req.Method); err != nil {
+		return nil, err
+	}
+
+	req.Code = NormalizeGiftCardCode(req.Code)
+	if req.Code == "" {
+		code, err := newGiftCardCode()
+		if err != nil {
+			return nil, err
+		}
+		req.Code = code
+	} else if len(req.Code) < 8 {
+		return nil, errors.New("kode gift card minimal 8 karakter")
+	}
+	return s.repo.Issue(req)
+}
+
+func (s *GiftCardService) TopUp(code string, req models.TopUpGiftCardRequest) (*models.GiftCard, error) {
+	if req.ShiftID <= 0 {
+		return nil, errors.New("shift_id wajib diisi")
+	}
+	if req.Amount <= 0 {
+		return nil, errors.New("amount harus > 0")
+	}
+	if req.Method == "" {
+		req.Method = models.PaymentCash
+	}
+	if err := validateTender(req.Method); err != nil {
+		return nil, err
+	}
+	return s.repo.TopUp(NormalizeGiftCardCode(code), req)
+}
+
+func (s *GiftCardService) GetByCode(code string) (*models.GiftCard, error) {
+	return s.repo.GetByCode(NormalizeGiftCardCode(code))
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"errors"
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+type LoyaltyService struct {
+	repo *repositories.LoyaltyRepository
+}
+
+func NewLoyaltyService(repo *repositories.LoyaltyRepository) *LoyaltyService {
+	return &LoyaltyService{repo: repo}
+}
+
+func (s *LoyaltyService) GetSettings() (models.LoyaltySettings, error) { return s.repo.GetSettings() }
+
+func (s *LoyaltyService) UpdateSettings(set models.LoyaltySettings) error {
+	if set.AmountPerPoint <= 0 {
+		return errors.New("amount_per_point harus > 0")
+	}
+	if set.PointValue <= 0 {
+		return errors.New("point_value harus > 0")
+	}
+	if set.ExpiryDays < 0 {
+		return errors.New("expiry_days tidak boleh negatif")
+	}
+	return s.repo.UpdateSettings(set)
+}
+
+func validateLoyaltyRule(rule *models.LoyaltyRule) error {
+	if rule.Name == "" {
+		return errors.New("name wajib diisi")
+	}
+	if rule.Multiplier <= 0 {
+		return errors.New("multiplier harus > 0")
+	}
+	if rule.StartsAt != nil && rule.EndsAt != nil && !rule.EndsAt.After(*rule.StartsAt) {
+		return errors.New("ends_at harus setelah starts_at")
+	}
+	return nil
+}
+
+func (s *LoyaltyService) GetRules() ([]models.LoyaltyRule, error) { return s.repo.GetRules() }
+
+func (s *LoyaltyService) CreateRule(rule *models.LoyaltyRule) error {
+	if err := validateLoyaltyRule(rule); err != nil {
+		return err
+	}
+	return s.repo.CreateRule(rule)
+}
+
+func (s *LoyaltyService) UpdateRule(rule *models.LoyaltyRule) error {
+	if err := validateLoyaltyRule(rule); err != nil {
+		return err
+	}
+	return s.repo.UpdateRule(rule)
+}
+
+func (s *LoyaltyService) DeleteRule(id int) error { return s.repo.DeleteRule(id) }
+
+func (s *LoyaltyService) Balance(customerID int) (*models.PointsBalance, error) {
+	return s.repo.Balance(customerID, 100)
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"time"
+)
+
+type MailAttachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+type Mail struct {
+	To          []string
+	Subject     string
+	Body        string // text/plain
+	Attachments []MailAttachment
+}
+
+// Mailer = pengirim email. Implementasi default lewat SMTP;
+// bisa diganti fake/implementasi lain tanpa mengubah ReceiptService.
+type Mailer interface {
+	Send(m Mail) error
+}
+
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+type SMTPMailer struct {
+	cfg SMTPConfig
+}
+
+func NewSMTPMailer(cfg SMTPConfig) *SMTPMailer {
+	if cfg.Port == 0 {
+		cfg.Port = 587
+	}
+	return &SMTPMailer{cfg: cfg}
+}
+
+func (s *SMTPMailer) Send(m Mail) error {
+	msg, err := buildMIME(s.cfg.From, m)
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if s.cfg.Username != "" {
+		auth = smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)
+	}
+	addr := net.JoinHostPort(s.cfg.Host, strconv.Itoa(s.cfg.Port))
+	return smtp.SendMail(addr, auth, s.cfg.From, m.To, msg)
+}
+
+func buildMIME(from string, m Mail) ([]byte, error) {
+	var b bytes.Buffer
+	w := multipart.NewWriter(&b)
+
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	for _, to := range m.To {
+		fmt.Fprintf(&b, "To: %s\r\n", to)
+	}
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&b, "Content-Type: multipart/mixed; boundary=%q\r\n\r\n", w.Boundary())
+
+	body, err := w.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/plain; charset=utf-8"},
+		"Content-Transfer-Encoding": {"8bit"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := body.Write([]byte(m.Body)); err != nil {
+		return nil, err
+	}
+
+	for _, a := range m.Attachments {
+		part, err := w.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {a.ContentType},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": a.Filename})},
+		})
+		if err != nil {
+			return nil, err
+		}
+		enc := base64.StdEncoding.EncodeToString(a.Data)
+		for len(enc) > 0 {
+			n := min(len(enc), 76)
+			if _, err := part.Write([]byte(enc[:n] + "\r\n")); err != nil {
+				return nil, err
+			}
+			enc = enc[n:]
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
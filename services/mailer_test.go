@@ -0,0 +1,172 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"strings"
+	"testing"
+)
+
+// smtpSession = apa yang diterima fake server dari satu koneksi.
+type smtpSession struct {
+	from string
+	rcpt []string
+	data string
+}
+
+// fakeSMTP menerima satu koneksi dan menjawab perintah SMTP minimal
+// (tanpa STARTTLS/AUTH) sampai QUIT.
+func fakeSMTP(t *testing.T) (port int, done <-chan smtpSession) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	ch := make(chan smtpSession, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var s smtpSession
+		r := bufio.NewReader(conn)
+		reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+		reply("220 localhost ESMTP fake")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			cmd := strings.ToUpper(line)
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(cmd, "MAIL FROM:"):
+				s.from = strings.Trim(line[len("MAIL FROM:"):], "<> ")
+				reply("250 OK")
+			case strings.HasPrefix(cmd, "RCPT TO:"):
+				s.rcpt = append(s.rcpt, strings.Trim(line[len("RCPT TO:"):], "<> "))
+				reply("250 OK")
+			case cmd == "DATA":
+				reply("354 lanjut")
+				var data strings.Builder
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if l == ".\r\n" {
+						break
+					}
+					data.WriteString(strings.TrimPrefix(l, "."))
+				}
+				s.data = data.String()
+				reply("250 OK")
+			case cmd == "QUIT":
+				reply("221 bye")
+				ch <- s
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+	return ln.Addr().(*net.TCPAddr).Port, ch
+}
+
+func TestSMTPMailerSend(t *testing.T) {
+	port, done := fakeSMTP(t)
+
+	pdf := []byte("%PDF-1.4\n" + strings.Repeat("struk ", 40) + "\n%%EOF")
+	m := NewSMTPMailer(SMTPConfig{Host: "127.0.0.1", Port: port, From: "kasir@toko.test"})
+	err := m.Send(Mail{
+		To:      []string{"budi@contoh.test"},
+		Subject: "Struk INV-001 - Toko Maju",
+		Body:    "Terima kasih sudah berbelanja.",
+		Attachments: []MailAttachment{{
+			Filename:    "struk-1.pdf",
+			ContentType: "application/pdf",
+			Data:        pdf,
+		}},
+	})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	s := <-done
+
+	if s.from != "kasir@toko.test" {
+		t.Errorf("MAIL FROM = %q", s.from)
+	}
+	if len(s.rcpt) != 1 || s.rcpt[0] != "budi@contoh.test" {
+		t.Errorf("RCPT TO = %v", s.rcpt)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(s.data))
+	if err != nil {
+		t.Fatalf("pesan tidak valid: %v", err)
+	}
+	if got := msg.Header.Get("From"); got != "kasir@toko.test" {
+		t.Errorf("From = %q", got)
+	}
+	if got := msg.Header.Get("To"); got != "budi@contoh.test" {
+		t.Errorf("To = %q", got)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil || subject != "Struk INV-001 - Toko Maju" {
+		t.Errorf("Subject = %q (%v)", subject, err)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/mixed" {
+		t.Fatalf("Content-Type = %q (%v)", msg.Header.Get("Content-Type"), err)
+	}
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+
+	body, err := mr.NextPart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, _ := io.ReadAll(body)
+	if string(text) != "Terima kasih sudah berbelanja." {
+		t.Errorf("body = %q", text)
+	}
+
+	att, err := mr.NextPart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := att.Header.Get("Content-Type"); got != "application/pdf" {
+		t.Errorf("Content-Type lampiran = %q", got)
+	}
+	if att.FileName() != "struk-1.pdf" {
+		t.Errorf("filename = %q", att.FileName())
+	}
+	encoded, _ := io.ReadAll(att)
+	for _, line := range strings.Split(strings.TrimSpace(string(encoded)), "\r\n") {
+		if len(line) > 76 {
+			t.Errorf("baris base64 lebih dari 76 karakter: %d", len(line))
+		}
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(string(encoded), "\r\n", ""))
+	if err != nil {
+		t.Fatalf("decode lampiran: %v", err)
+	}
+	if !bytes.Equal(decoded, pdf) {
+		t.Error("isi lampiran PDF berbeda")
+	}
+
+	if _, err := mr.NextPart(); err != io.EOF {
+		t.Errorf("part berlebih: %v", err)
+	}
+}
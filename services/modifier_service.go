@@ -0,0 +1,74 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"kasir-api/models"
+	"kasir-api/repositories"
+	"strings"
+)
+
+type ModifierService struct {
+	repo *repositories.ModifierRepository
+}
+
+func NewModifierService(repo *repositories.ModifierRepository) *ModifierService {
+	return &ModifierService{repo: repo}
+}
+
+func validateModifierGroup(g *models.ModifierGroup) error {
+	g.Name = strings.TrimSpace(g.Name)
+	if g.Name == "" {
+		return errors.New("name wajib diisi")
+	}
+	if g.MinSelect < 0 || g.MaxSelect < 0 {
+		return errors.New("min_select / max_select tidak boleh negatif")
+	}
+	if g.Required && g.MinSelect == 0 {
+		g.MinSelect = 1
+	}
+	if g.MaxSelect > 0 && g.MinSelect > g.MaxSelect {
+		return errors.New("min_select tidak boleh lebih dari max_select")
+	}
+	return nil
+}
+
+func (s *ModifierService) GetAll() ([]models.ModifierGroup, error) { return s.repo.GetAll() }
+
+func (s *ModifierService) Create(g *models.ModifierGroup) error {
+	if err := validateModifierGroup(g); err != nil {
+		return err
+	}
+	return s.repo.Create(g)
+}
+
+func (s *ModifierService) GetByID(id int) (*models.ModifierGroup, error) { return s.repo.GetByID(id) }
+
+func (s *ModifierService) Update(g *models.ModifierGroup) error {
+	if err := validateModifierGroup(g); err != nil {
+		return err
+	}
+	return s.repo.Update(g)
+}
+
+func (s *ModifierService) Delete(id int) error { return s.repo.Delete(id) }
+
+func (s *ModifierService) SetModifiers(groupID int, mods []models.Modifier) error {
+	for i := range mods {
+		mods[i].Name = strings.TrimSpace(mods[i].Name)
+		if mods[i].Name == "" {
+			return errors.New("name modifier wajib diisi")
+		}
+		if mods[i].ProductID != nil && mods[i].StockQty == 0 {
+			mods[i].StockQty = 1
+		}
+		if mods[i].StockQty < 0 {
+			return fmt.Errorf("stock_qty tidak boleh negatif (%s)", mods[i].Name)
+		}
+	}
+	return s.repo.SetModifiers(groupID, mods)
+}
+
+func (s *ModifierService) SetProducts(groupID int, productIDs []int) error {
+	return s.repo.SetProducts(groupID, productIDs)
+}
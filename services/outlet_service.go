@@ -0,0 +1,85 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"kasir-api/models"
+	"kasir-api/repositories"
+	"strings"
+	"time"
+)
+
+// DefaultOutletTimezone dipakai kalau timezone outlet tidak diisi (WIB).
+const DefaultOutletTimezone = "Asia/Jakarta"
+
+type OutletService struct {
+	repo *repositories.OutletRepository
+}
+
+func NewOutletService(repo *repositories.OutletRepository) *OutletService {
+	return &OutletService{repo: repo}
+}
+
+func validateOutlet(o *models.Outlet) error {
+	o.Code = strings.ToUpper(strings.TrimSpace(o.Code))
+	o.Name = strings.TrimSpace(o.Name)
+	o.Address = strings.TrimSpace(o.Address)
+	if o.Code == "" {
+		return errors.New("code wajib diisi")
+	}
+	if strings.ContainsAny(o.Code, "/{} ") {
+		return errors.New("code tidak boleh mengandung spasi, / atau {}")
+	}
+	if o.Name == "" {
+		o.Name = o.Code
+	}
+	o.Timezone = strings.TrimSpace(o.Timezone)
+	if o.Timezone == "" {
+		o.Timezone = DefaultOutletTimezone
+	}
+	// "Local" = zona server, justru yang mau dihindari (dan tidak dikenal Postgres)
+	if _, err := time.LoadLocation(o.Timezone); err != nil || o.Timezone == "Local" {
+		return fmt.Errorf("timezone %q tidak dikenal, pakai nama IANA mis. Asia/Jakarta", o.Timezone)
+	}
+	if o.CutoffHour < 0 || o.CutoffHour > 23 {
+		return errors.New("day_cutoff_hour harus 0-23")
+	}
+	if o.IsDefault && !o.Active {
+		return errors.New("outlet default harus aktif")
+	}
+	return nil
+}
+
+func (s *OutletService) GetAll() ([]models.Outlet, error) { return s.repo.GetAll() }
+
+func (s *OutletService) Create(o *models.Outlet) error {
+	if err := validateOutlet(o); err != nil {
+		return err
+	}
+	return s.repo.Create(o)
+}
+
+func (s *OutletService) GetByID(id int) (*models.Outlet, error) { return s.repo.GetByID(id) }
+
+func (s *OutletService) Update(o *models.Outlet) error {
+	if err := validateOutlet(o); err != nil {
+		return err
+	}
+	return s.repo.Update(o)
+}
+
+func (s *OutletService) Delete(id int) error { return s.repo.Delete(id) }
+
+func (s *OutletService) GetStock(id int) ([]models.OutletStock, error) { return s.repo.GetStock(id) }
+
+func (s *OutletService) SetStock(id int, items []models.OutletStock) ([]models.OutletStock, error) {
+	for _, it := range items {
+		if it.Stock < 0 {
+			return nil, fmt.Errorf("stock tidak boleh negatif (product_id=%d)", it.ProductID)
+		}
+		if it.Price != nil && *it.Price < 0 {
+			return nil, fmt.Errorf("price tidak boleh negatif (product_id=%d)", it.ProductID)
+		}
+	}
+	return s.repo.SetStock(id, items)
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	pinIterations = 100_000
+	pinKeyLength  = 32
+)
+
+func validatePIN(pin string) error {
+	if len(pin) < 4 || len(pin) > 8 {
+		return errors.New("pin harus 4-8 digit")
+	}
+	for _, r := range pin {
+		if r < '0' || r > '9' {
+			return errors.New("pin harus 4-8 digit")
+		}
+	}
+	return nil
+}
+
+// hashPIN menyimpan PIN sebagai "pbkdf2$iterasi$salt$hash" (base64).
+func hashPIN(pin string) (string, error) {
+	if err := validatePIN(pin); err != nil {
+		return "", err
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key, err := pbkdf2.Key(sha256.New, pin, salt, pinIterations, pinKeyLength)
+	if err != nil {
+		return "", err
+	}
+	enc := base64.RawStdEncoding
+	return fmt.Sprintf("pbkdf2$%d$%s$%s", pinIterations, enc.EncodeToString(salt), enc.EncodeToString(key)), nil
+}
+
+func checkPIN(hash, pin string) bool {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 4 || parts[0] != "pbkdf2" {
+		return false
+	}
+	iter, err := strconv.Atoi(parts[1])
+	if err != nil || iter <= 0 {
+		return false
+	}
+	enc := base64.RawStdEncoding
+	salt, err := enc.DecodeString(parts[2])
+	if err != nil {
+		return false
+	}
+	want, err := enc.DecodeString(parts[3])
+	if err != nil {
+		return false
+	}
+	got, err := pbkdf2.Key(sha256.New, pin, salt, iter, len(want))
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare(got, want) == 1
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"kasir-api/models"
+	"kasir-api/repositories"
+	"strings"
+)
+
+type PriceListService struct {
+	repo *repositories.PriceListRepository
+}
+
+func NewPriceListService(repo *repositories.PriceListRepository) *PriceListService {
+	return &PriceListService{repo: repo}
+}
+
+func validatePriceList(pl *models.PriceList) error {
+	pl.Name = strings.TrimSpace(pl.Name)
+	if pl.Name == "" {
+		return errors.New("name wajib diisi")
+	}
+	switch pl.Kind {
+	case models.PriceListRetail, models.PriceListMember, models.PriceListWholesale:
+		pl.OutletCode = nil
+	case models.PriceListOutlet:
+		if pl.OutletCode == nil || strings.TrimSpace(*pl.OutletCode) == "" {
+			return errors.New("outlet_code wajib diisi untuk price list outlet")
+		}
+	default:
+		return fmt.Errorf("kind %q tidak dikenal (retail, member, wholesale, outlet)", pl.Kind)
+	}
+	return nil
+}
+
+func (s *PriceListService) GetAll() ([]models.PriceList, error) { return s.repo.GetAll() }
+
+func (s *PriceListService) Create(pl *models.PriceList) error {
+	if err := validatePriceList(pl); err != nil {
+		return err
+	}
+	return s.repo.Create(pl)
+}
+
+func (s *PriceListService) GetByID(id int) (*models.PriceList, error) { return s.repo.GetByID(id) }
+
+func (s *PriceListService) Update(pl *models.PriceList) error {
+	if err := validatePriceList(pl); err != nil {
+		return err
+	}
+	return s.repo.Update(pl)
+}
+
+func (s *PriceListService) Delete(id int) error { return s.repo.Delete(id) }
+
+func (s *PriceListService) SetItems(id int, items []models.PriceListItem) error {
+	for i := range items {
+		if items[i].MinQty == 0 {
+			items[i].MinQty = 1
+		}
+		if items[i].MinQty < 0 {
+			return fmt.Errorf("min_qty harus > 0 (product_id=%d)", items[i].ProductID)
+		}
+		if items[i].Price < 0 {
+			return fmt.Errorf("price tidak boleh negatif (product_id=%d)", items[i].ProductID)
+		}
+	}
+	return s.repo.SetItems(id, items)
+}
@@ -1,8 +1,12 @@
 package services
 
 import (
+	"errors"
+	"fmt"
 	"kasir-api/models"
 	"kasir-api/repositories"
+	"sort"
+	"strings"
 )
 
 type ProductService struct {
@@ -13,13 +17,118 @@ func NewProductService(repo *repositories.ProductRepository) *ProductService {
 	return &ProductService{repo: repo}
 }
 
-func (s *ProductService) GetAll(name string) ([]models.Product, error) {
-	return s.repo.GetAll(name)
+// outletID nil = stok total semua outlet.
+func (s *ProductService) GetAll(name, code string, outletID *int) ([]models.Product, error) {
+	return s.repo.GetAll(name, strings.TrimSpace(code), outletID)
 }
 
-func (s *ProductService) Create(p *models.Product) error { return s.repo.Create(p) }
-func (s *ProductService) GetByID(id int) (*models.Product, error) {
-	return s.repo.GetByID(id)
+func normalizeProduct(p *models.Product) {
+	p.SKU = strings.TrimSpace(p.SKU)
+	p.Barcode = strings.TrimSpace(p.Barcode)
+	if p.Attributes == nil {
+		p.Attributes = map[string]string{}
+	}
+	if p.Options == nil {
+		p.Options = []string{}
+	}
+}
+
+func validateStockSettings(p *models.Product) error {
+	if p.ReorderPoint != nil && *p.ReorderPoint < 0 {
+		return errors.New("reorder_point tidak boleh negatif")
+	}
+	if p.LeadTimeDays < 0 {
+		return errors.New("lead_time_days tidak boleh negatif")
+	}
+	if p.TrackLots && p.TrackSerials {
+		return errors.New("pilih salah satu: track_lots atau track_serials")
+	}
+	return nil
+}
+
+func (s *ProductService) Create(p *models.Product) error {
+	normalizeProduct(p)
+	if err := validateStockSettings(p); err != nil {
+		return err
+	}
+	if p.ParentID != nil {
+		return s.CreateVariant(*p.ParentID, p)
+	}
+	return s.repo.Create(p)
+}
+func (s *ProductService) GetByID(id int, outletID *int) (*models.Product, error) {
+	return s.repo.GetByID(id, outletID)
+}
+func (s *ProductService) GetVariants(parentID int, outletID *int) ([]models.Product, error) {
+	return s.repo.GetVariants(parentID, outletID)
+}
+func (s *ProductService) Update(p *models.Product, outletID *int) error {
+	normalizeProduct(p)
+	if err := validateStockSettings(p); err != nil {
+		return err
+	}
+	return s.repo.Update(p, outletID)
+}
+func (s *ProductService) Delete(id int) error { return s.repo.Delete(id) }
+
+// CreateVariant menambah varian di bawah produk induk. Atribut harus sesuai options induk;
+// nama, harga dan kategori yang kosong diambil dari induk.
+func (s *ProductService) CreateVariant(parentID int, v *models.Product) error {
+	normalizeProduct(v)
+	if err := validateStockSettings(v); err != nil {
+		return err
+	}
+	parent, err := s.repo.GetByID(parentID, nil)
+	if err != nil {
+		return err
+	}
+	if parent.ParentID != nil {
+		return errors.New("varian tidak bisa punya varian")
+	}
+	if len(v.Attributes) == 0 {
+		return errors.New("attributes varian wajib diisi")
+	}
+	if len(parent.Options) > 0 {
+		if len(v.Attributes) != len(parent.Options) {
+			return fmt.Errorf("attributes varian harus: %s", strings.Join(parent.Options, ", "))
+		}
+		for _, opt := range parent.Options {
+			if strings.TrimSpace(v.Attributes[opt]) == "" {
+				return fmt.Errorf("attribute %s wajib diisi", opt)
+			}
+		}
+	}
+
+	v.ParentID = &parent.ID
+	v.Options = []string{}
+	if v.Name == "" {
+		v.Name = variantName(parent, v.Attributes)
+	}
+	if v.Price == 0 {
+		v.Price = parent.Price
+	}
+	if v.CategoryID == nil {
+		v.CategoryID = parent.CategoryID
+	}
+	return s.repo.Create(v)
+}
+
+// variantName, mis. "Kaos Polos - L / Hitam" (urut options induk).
+func variantName(parent *models.Product, attrs map[string]string) string {
+	values := make([]string, 0, len(attrs))
+	if len(parent.Options) > 0 {
+		for _, opt := range parent.Options {
+			values = append(values, attrs[opt])
+		}
+	} else {
+		keys := make([]string, 0, len(attrs))
+		for k := range attrs {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			values = append(values, attrs[k])
+		}
+	}
+	return parent.Name + " - " + strings.Join(values, " / ")
 }
-func (s *ProductService) Update(p *models.Product) error { return s.repo.Update(p) }
-func (s *ProductService) Delete(id int) error            { return s.repo.Delete(id) }
@@ -0,0 +1,33 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+	"strings"
+)
+
+// receiptSigner membuat link struk publik yang ditandatangani HMAC,
+// jadi pelanggan bisa lihat struk tanpa login tapi id lain tidak bisa ditebak.
+type receiptSigner struct {
+	secret  []byte
+	baseURL string
+}
+
+func (s receiptSigner) enabled() bool { return len(s.secret) > 0 && s.baseURL != "" }
+
+func (s receiptSigner) sign(transactionID int, receiptNo string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte("receipt:" + strconv.Itoa(transactionID) + ":" + receiptNo))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:18])
+}
+
+func (s receiptSigner) verify(transactionID int, receiptNo, sig string) bool {
+	return hmac.Equal([]byte(s.sign(transactionID, receiptNo)), []byte(sig))
+}
+
+// publicURL: {base}/r/{id}.{sig}
+func (s receiptSigner) publicURL(transactionID int, receiptNo string) string {
+	return strings.TrimRight(s.baseURL, "/") + "/r/" + strconv.Itoa(transactionID) + "." + s.sign(transactionID, receiptNo)
+}
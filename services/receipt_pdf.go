@@ -0,0 +1,90 @@
+package services
+
+import (
+	"bytes"
+	"image/png"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// Ukuran PDF mengikuti kertas thermal: lebar = paper (mm), tinggi menyesuaikan isi.
+const (
+	pdfMargin     = 4.0
+	pdfLineHeight = 3.6
+)
+
+func renderPDF(d *receiptDoc) ([]byte, error) {
+	width := float64(d.paper)
+	cols := float64(paperColumns(d.paper))
+
+	// Courier: lebar karakter 0.6 em -> pilih font size supaya semua kolom muat
+	charWidth := (width - 2*pdfMargin) / cols
+	fontSize := charWidth / 0.6 * 72 / 25.4
+
+	var logoPNG, qr []byte
+	logoH, qrSize := 0.0, 0.0
+	if d.logo != nil {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, d.logo); err == nil {
+			logoPNG = buf.Bytes()
+			b := d.logo.Bounds()
+			logoH = (width - 2*pdfMargin) / 2 * float64(b.Dy()) / float64(b.Dx())
+		}
+	}
+	if d.qrURL != "" {
+		img, err := qrPNG(d.qrURL)
+		if err != nil {
+			return nil, err
+		}
+		qr = img
+		qrSize = (width - 2*pdfMargin) * 0.6
+	}
+
+	height := 2*pdfMargin + logoH + float64(len(d.lines))*pdfLineHeight
+	if qr != nil {
+		height += pdfLineHeight + qrSize + 2
+	}
+
+	pdf := fpdf.NewCustom(&fpdf.InitType{
+		UnitStr: "mm",
+		Size:    fpdf.SizeType{Wd: width, Ht: height},
+	})
+	pdf.SetMargins(pdfMargin, pdfMargin, pdfMargin)
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.SetTitle(d.title, true)
+	pdf.AddPage()
+
+	y := pdfMargin
+	if logoPNG != nil {
+		w := (width - 2*pdfMargin) / 2
+		pdf.RegisterImageOptionsReader("logo", fpdf.ImageOptions{ImageType: "PNG"}, bytes.NewReader(logoPNG))
+		pdf.ImageOptions("logo", (width-w)/2, y, w, logoH, false, fpdf.ImageOptions{ImageType: "PNG"}, 0, "")
+		y += logoH
+	}
+
+	for _, l := range d.lines {
+		style := ""
+		if l.bold {
+			style = "B"
+		}
+		pdf.SetFont("Courier", style, fontSize)
+		pdf.SetXY(pdfMargin, y)
+		pdf.CellFormat(width-2*pdfMargin, pdfLineHeight, asciiOnly(l.text), "", 0, "L", false, 0, "")
+		y += pdfLineHeight
+	}
+
+	if qr != nil {
+		pdf.SetFont("Courier", "", fontSize)
+		pdf.SetXY(pdfMargin, y)
+		pdf.CellFormat(width-2*pdfMargin, pdfLineHeight, qrCaption, "", 0, "C", false, 0, d.qrURL)
+		y += pdfLineHeight + 1
+		pdf.RegisterImageOptionsReader("qr", fpdf.ImageOptions{ImageType: "PNG"}, bytes.NewReader(qr))
+		pdf.ImageOptions("qr", (width-qrSize)/2, y, qrSize, qrSize, false, fpdf.ImageOptions{ImageType: "PNG"}, 0, d.qrURL)
+	}
+
+	var out bytes.Buffer
+	if err := pdf.Output(&out); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
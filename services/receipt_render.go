@@ -0,0 +1,472 @@
+package services
+
+import (
+	"bytes"
+	"encoding/base64"
+	"html/template"
+	"image"
+	"image/png"
+	"kasir-api/models"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/skip2/go-qrcode"
+)
+
+// Lebar kertas thermal -> jumlah kolom (Font A) dan titik raster.
+const (
+	Paper58mm = 58
+	Paper80mm = 80
+)
+
+func paperColumns(paper int) int {
+	if paper == Paper80mm {
+		return 48
+	}
+	return 32
+}
+
+func paperDots(paper int) int {
+	if paper == Paper80mm {
+		return 576
+	}
+	return 384
+}
+
+type receiptLine struct {
+	text string // sudah di-pad sesuai lebar kolom
+	bold bool
+	tall bool // double height
+}
+
+type receiptDoc struct {
+	paper int
+	logo  image.Image
+	title string
+	lines []receiptLine
+	qrURL string // link struk digital, kosong = tanpa QR
+}
+
+const qrCaption = "Scan untuk struk digital"
+
+// add menulis teks apa adanya, dipotong per lebar kolom kalau kepanjangan.
+func (d *receiptDoc) add(text string) {
+	w := paperColumns(d.paper)
+	r := []rune(text)
+	for len(r) > w {
+		d.lines = append(d.lines, receiptLine{text: string(r[:w])})
+		r = r[w:]
+	}
+	d.lines = append(d.lines, receiptLine{text: string(r)})
+}
+
+// addWrapped membungkus teks per kata.
+func (d *receiptDoc) addWrapped(text string) {
+	for _, l := range wrapText(text, paperColumns(d.paper)) {
+		d.lines = append(d.lines, receiptLine{text: l})
+	}
+}
+
+func (d *receiptDoc) addStyled(text string, bold, tall bool) {
+	d.lines = append(d.lines, receiptLine{text: text, bold: bold, tall: tall})
+}
+
+func (d *receiptDoc) center(text string, bold, tall bool) {
+	w := paperColumns(d.paper)
+	for _, l := range wrapText(text, w) {
+		pad := (w - utf8.RuneCountInString(l)) / 2
+		d.addStyled(strings.Repeat(" ", pad)+l, bold, tall)
+	}
+}
+
+func (d *receiptDoc) divider() { d.add(strings.Repeat("-", paperColumns(d.paper))) }
+
+// leftRight menaruh teks kiri dan kanan di satu baris; kalau tidak muat,
+// teks kiri dibungkus dan nilai kanan pindah ke baris sendiri.
+func (d *receiptDoc) leftRight(left, right string, bold bool) {
+	w := paperColumns(d.paper)
+	lw, rw := utf8.RuneCountInString(left), utf8.RuneCountInString(right)
+	if lw+1+rw <= w {
+		d.addStyled(left+strings.Repeat(" ", w-lw-rw)+right, bold, false)
+		return
+	}
+	for _, l := range wrapText(left, w) {
+		d.addStyled(l, bold, false)
+	}
+	d.addStyled(strings.Repeat(" ", max(w-rw, 0))+right, bold, false)
+}
+
+func wrapText(s string, width int) []string {
+	words := strings.Fields(s)
+	if len(words) == 0 {
+		return []string{""}
+	}
+
+	var out []string
+	cur := ""
+	for _, word := range words {
+		for utf8.RuneCountInString(word) > width {
+			if cur != "" {
+				out = append(out, cur)
+				cur = ""
+			}
+			r := []rune(word)
+			out = append(out, string(r[:width]))
+			word = string(r[width:])
+		}
+		switch {
+		case cur == "":
+			cur = word
+		case utf8.RuneCountInString(cur)+1+utf8.RuneCountInString(word) <= width:
+			cur += " " + word
+		default:
+			out = append(out, cur)
+			cur = word
+		}
+	}
+	if cur != "" {
+		out = append(out, cur)
+	}
+	return out
+}
+
+// formatRupiah: 1500000 -> "1.500.000"
+func formatRupiah(n int) string {
+	neg := n < 0
+	if neg {
+		n = -n
+	}
+	s := strconv.Itoa(n)
+	var b strings.Builder
+	for i, c := range s {
+		if i > 0 && (len(s)-i)%3 == 0 {
+			b.WriteByte('.')
+		}
+		b.WriteRune(c)
+	}
+	if neg {
+		return "-" + b.String()
+	}
+	return b.String()
+}
+
+func paymentLabel(method string) string {
+	switch method {
+	case models.PaymentCash:
+		return "TUNAI"
+	case models.PaymentCard:
+		return "KARTU"
+	case models.PaymentAccount:
+		return "KASBON"
+	case models.PaymentGiftCard:
+		return "GIFT CARD"
+	default:
+		return strings.ToUpper(method)
+	}
+}
+
+// outletTime = jam di zona waktu outlet transaksi (bukan zona server);
+// zona kosong / tidak dikenal jatuh ke zona server.
+func outletTime(ts time.Time, timezone string) time.Time {
+	if timezone != "" {
+		if loc, err := time.LoadLocation(timezone); err == nil {
+			return ts.In(loc)
+		}
+	}
+	return ts.Local()
+}
+
+func buildReceipt(t *models.Transaction, tmpl ReceiptTemplate, logo image.Image, paper int, isCopy bool, qrURL string) *receiptDoc {
+	d := &receiptDoc{paper: paper, logo: logo, title: t.ReceiptNo, qrURL: qrURL}
+
+	if tmpl.StoreName != "" {
+		d.center(tmpl.StoreName, true, true)
+	}
+	if tmpl.Address != "" {
+		d.center(tmpl.Address, false, false)
+	}
+	if tmpl.NPWP != "" {
+		d.center("NPWP: "+tmpl.NPWP, false, false)
+	}
+	if isCopy {
+		d.center("*** COPY ***", true, false)
+	}
+	d.divider()
+
+	d.leftRight("No", t.ReceiptNo, false)
+	d.leftRight("Tgl", outletTime(t.CreatedAt, t.OutletTimezone).Format("02/01/2006 15:04"), false)
+	if t.CashierName != "" {
+		d.leftRight("Kasir", t.CashierName, false)
+	}
+	if t.CustomerName != "" {
+		d.leftRight("Pelanggan", t.CustomerName, false)
+	}
+	d.divider()
+
+	for _, it := range t.Details {
+		d.addWrapped(it.ProductName)
+		for _, m := range it.Modifiers {
+			line := "  + " + m.Name
+			if m.PriceDelta != 0 {
+				line += " (" + signedRupiah(m.PriceDelta) + ")"
+			}
+			d.addWrapped(line)
+		}
+		d.leftRight("  "+strconv.Itoa(it.Quantity)+" x "+formatRupiah(it.UnitPrice), formatRupiah(it.Subtotal+it.DiscountAmount), false)
+		if it.DiscountAmount > 0 {
+			d.leftRight("  Diskon", "-"+formatRupiah(it.DiscountAmount), false)
+		}
+		for _, sn := range it.SerialNumbers {
+			d.addWrapped("  SN: " + sn)
+		}
+	}
+	d.divider()
+
+	if t.DiscountAmount > 0 {
+		d.leftRight("SUBTOTAL", formatRupiah(t.TotalAmount+t.DiscountAmount), false)
+		label := "DISKON"
+		if t.VoucherCode != "" {
+			label += " " + t.VoucherCode
+		}
+		d.leftRight(label, "-"+formatRupiah(t.DiscountAmount), false)
+	}
+	d.leftRight("TOTAL", formatRupiah(t.TotalAmount), true)
+	lastCash := -1
+	for i, p := range t.Payments {
+		if p.Method == models.PaymentCash {
+			lastCash = i
+		}
+	}
+	for i, p := range t.Payments {
+		amount := p.Amount
+		if i == lastCash {
+			// tampilkan uang yang diterima, bukan net setelah kembalian
+			amount += t.ChangeAmount
+		}
+		label := paymentLabel(p.Method)
+		if p.Reference != "" {
+			label += " " + p.Reference
+		}
+		d.leftRight(label, formatRupiah(amount), false)
+	}
+	if t.ChangeAmount > 0 {
+		d.leftRight("KEMBALI", formatRupiah(t.ChangeAmount), false)
+	}
+	d.divider()
+
+	if tmpl.Footer != "" {
+		for _, l := range strings.Split(tmpl.Footer, "\n") {
+			d.center(l, false, false)
+		}
+	}
+	if isCopy {
+		d.center("*** COPY ***", true, false)
+	}
+	return d
+}
+
+func renderText(d *receiptDoc) []byte {
+	var b bytes.Buffer
+	for _, l := range d.lines {
+		b.WriteString(l.text)
+		b.WriteByte('\n')
+	}
+	if d.qrURL != "" {
+		b.WriteString(qrCaption + ":\n" + d.qrURL + "\n")
+	}
+	return b.Bytes()
+}
+
+// ESC/POS command bytes
+var (
+	escInit        = []byte{0x1b, 0x40}
+	escAlignLeft   = []byte{0x1b, 0x61, 0x00}
+	escAlignCenter = []byte{0x1b, 0x61, 0x01}
+	escBoldOn      = []byte{0x1b, 0x45, 0x01}
+	escBoldOff     = []byte{0x1b, 0x45, 0x00}
+	escTallOn      = []byte{0x1d, 0x21, 0x01}
+	escSizeReset   = []byte{0x1d, 0x21, 0x00}
+	escFeedCut     = []byte{0x1d, 0x56, 0x42, 0x03} // feed 3 baris lalu partial cut
+)
+
+func renderESCPOS(d *receiptDoc) []byte {
+	var b bytes.Buffer
+	b.Write(escInit)
+
+	if d.logo != nil {
+		b.Write(escAlignCenter)
+		writeRaster(&b, d.logo, paperDots(d.paper))
+		b.Write(escAlignLeft)
+	}
+
+	for _, l := range d.lines {
+		if l.bold {
+			b.Write(escBoldOn)
+		}
+		if l.tall {
+			b.Write(escTallOn)
+		}
+		b.WriteString(asciiOnly(l.text))
+		b.WriteByte('\n')
+		if l.tall {
+			b.Write(escSizeReset)
+		}
+		if l.bold {
+			b.Write(escBoldOff)
+		}
+	}
+
+	if d.qrURL != "" {
+		b.Write(escAlignCenter)
+		b.WriteString(qrCaption + "\n")
+		writeQR(&b, d.qrURL, d.paper)
+		b.Write(escAlignLeft)
+	}
+
+	b.Write(escFeedCut)
+	return b.Bytes()
+}
+
+// writeQR memakai perintah QR bawaan printer (GS ( k, model 2).
+func writeQR(b *bytes.Buffer, data string, paper int) {
+	size := byte(5)
+	if paper == Paper80mm {
+		size = 7
+	}
+	n := len(data) + 3
+	b.Write([]byte{0x1d, 0x28, 0x6b, 0x04, 0x00, 0x31, 0x41, 0x32, 0x00}) // model 2
+	b.Write([]byte{0x1d, 0x28, 0x6b, 0x03, 0x00, 0x31, 0x43, size})       // ukuran modul
+	b.Write([]byte{0x1d, 0x28, 0x6b, 0x03, 0x00, 0x31, 0x45, 0x31})       // error correction M
+	b.Write([]byte{0x1d, 0x28, 0x6b, byte(n), byte(n >> 8), 0x31, 0x50, 0x30})
+	b.WriteString(data)
+	b.Write([]byte{0x1d, 0x28, 0x6b, 0x03, 0x00, 0x31, 0x51, 0x30}) // print
+	b.WriteByte('\n')
+}
+
+// qrPNG membuat gambar QR untuk html/pdf.
+func qrPNG(data string) ([]byte, error) {
+	return qrcode.Encode(data, qrcode.Medium, 256)
+}
+
+// asciiOnly: printer default memakai code page PC437, karakter di luar ASCII diganti '?'.
+func asciiOnly(s string) string {
+	return strings.Map(func(r rune) rune {
+		// Byte kontrol (ESC, GS, ...) dari nama produk/toko/pelanggan tidak boleh jadi perintah printer
+		if r > 0x7e || (r < 0x20 && r != '\n') {
+			return '?'
+		}
+		return r
+	}, s)
+}
+
+// writeRaster mencetak gambar monokrom dengan perintah GS v 0.
+func writeRaster(b *bytes.Buffer, img image.Image, maxDots int) {
+	bounds := img.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	if srcW == 0 || srcH == 0 {
+		return
+	}
+	w, h := srcW, srcH
+	if w > maxDots {
+		w = maxDots
+		h = max(srcH*maxDots/srcW, 1)
+	}
+
+	rowBytes := (w + 7) / 8
+	b.Write([]byte{0x1d, 0x76, 0x30, 0x00, byte(rowBytes), byte(rowBytes >> 8), byte(h), byte(h >> 8)})
+	for y := 0; y < h; y++ {
+		row := make([]byte, rowBytes)
+		sy := bounds.Min.Y + y*srcH/h
+		for x := 0; x < w; x++ {
+			sx := bounds.Min.X + x*srcW/w
+			r, g, bl, a := img.At(sx, sy).RGBA()
+			if a < 0x8000 {
+				continue // transparan = putih
+			}
+			lum := (299*r + 587*g + 114*bl) / 1000
+			if lum < 0x8000 {
+				row[x/8] |= 0x80 >> (x % 8)
+			}
+		}
+		b.Write(row)
+	}
+	b.WriteByte('\n')
+}
+
+var receiptHTML = template.Must(template.New("receipt").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Struk {{.Title}}</title>
+<style>
+body { background: #f4f4f4; }
+.receipt { width: {{.Columns}}ch; margin: 16px auto; padding: 12px; background: #fff; font-family: monospace; white-space: pre; }
+.logo { text-align: center; }
+.logo img { max-width: 100%; }
+.qr { text-align: center; margin-top: 8px; }
+.qr img { width: 60%; }
+.b { font-weight: bold; }
+.t { font-size: 1.3em; }
+</style>
+</head>
+<body>
+<div class="receipt">
+{{- if .Logo}}<div class="logo"><img src="{{.Logo}}" alt=""></div>{{end}}
+{{- range .Lines}}
+<div class="{{if .Bold}}b {{end}}{{if .Tall}}t{{end}}">{{.Text}}</div>
+{{- end}}
+{{- if .QR}}
+<div class="qr"><img src="{{.QR}}" alt="QR"><br><a href="{{.QRURL}}">{{.QRCaption}}</a></div>
+{{- end}}
+</div>
+</body>
+</html>
+`))
+
+func renderHTML(d *receiptDoc) ([]byte, error) {
+	type line struct {
+		Text       string
+		Bold, Tall bool
+	}
+	data := struct {
+		Title     string
+		Columns   int
+		Logo      template.URL
+		Lines     []line
+		QR        template.URL
+		QRURL     string
+		QRCaption string
+	}{Title: d.title, Columns: paperColumns(d.paper), QRURL: d.qrURL, QRCaption: qrCaption}
+
+	if d.logo != nil {
+		var img bytes.Buffer
+		if err := png.Encode(&img, d.logo); err == nil {
+			data.Logo = template.URL("data:image/png;base64," + base64.StdEncoding.EncodeToString(img.Bytes()))
+		}
+	}
+	if d.qrURL != "" {
+		img, err := qrPNG(d.qrURL)
+		if err != nil {
+			return nil, err
+		}
+		data.QR = template.URL("data:image/png;base64," + base64.StdEncoding.EncodeToString(img))
+	}
+	for _, l := range d.lines {
+		data.Lines = append(data.Lines, line{Text: l.text, Bold: l.bold, Tall: l.tall})
+	}
+
+	var b bytes.Buffer
+	if err := receiptHTML.Execute(&b, data); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func signedRupiah(n int) string {
+	if n < 0 {
+		return "-" + formatRupiah(-n)
+	}
+	return "+" + formatRupiah(n)
+}
@@ -0,0 +1,231 @@
+package services
+
+import (
+	"errors"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"kasir-api/repositories"
+	"log"
+	"net/mail"
+	"os"
+	"strconv"
+)
+
+const (
+	ReceiptFormatESCPOS = "escpos"
+	ReceiptFormatText   = "text"
+	ReceiptFormatHTML   = "html"
+	ReceiptFormatPDF    = "pdf"
+)
+
+// ReceiptTemplate = identitas toko yang dicetak di struk.
+type ReceiptTemplate struct {
+	StoreName string
+	Address   string
+	NPWP      string
+	Footer    string
+	LogoPath  string // PNG/JPEG, opsional
+	Paper     int    // 58 atau 80 (mm)
+}
+
+// ReceiptLinkConfig mengaktifkan link struk publik + QR di struk.
+// Kosong salah satu = fitur struk digital nonaktif.
+type ReceiptLinkConfig struct {
+	Secret  string
+	BaseURL string // mis. https://kasir.example.com
+}
+
+type ReceiptService struct {
+	repo   *repositories.TransactionRepository
+	tmpl   ReceiptTemplate
+	logo   image.Image
+	signer receiptSigner
+	mailer Mailer // nil = email nonaktif
+}
+
+func NewReceiptService(repo *repositories.TransactionRepository, tmpl ReceiptTemplate, links ReceiptLinkConfig, mailer Mailer) *ReceiptService {
+	s := &ReceiptService{
+		repo:   repo,
+		tmpl:   tmpl,
+		signer: receiptSigner{secret: []byte(links.Secret), baseURL: links.BaseURL},
+		mailer: mailer,
+	}
+	if tmpl.LogoPath != "" {
+		logo, err := loadImage(tmpl.LogoPath)
+		if err != nil {
+			log.Println("Logo struk tidak bisa dibaca:", err)
+		} else {
+			s.logo = logo
+		}
+	}
+	return s
+}
+
+func loadImage(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	return img, err
+}
+
+func (s *ReceiptService) qrURL(id int, receiptNo string) string {
+	if !s.signer.enabled() {
+		return ""
+	}
+	return s.signer.publicURL(id, receiptNo)
+}
+
+func (s *ReceiptService) paperOrDefault(paper int) (int, error) {
+	if paper == 0 {
+		paper = s.tmpl.Paper
+	}
+	if paper != Paper58mm && paper != Paper80mm {
+		return 0, errors.New("paper harus 58 atau 80")
+	}
+	return paper, nil
+}
+
+func encodeReceipt(doc *receiptDoc, format string) ([]byte, string, error) {
+	switch format {
+	case ReceiptFormatESCPOS:
+		return renderESCPOS(doc), "application/octet-stream", nil
+	case ReceiptFormatHTML:
+		out, err := renderHTML(doc)
+		return out, "text/html; charset=utf-8", err
+	case ReceiptFormatPDF:
+		out, err := renderPDF(doc)
+		return out, "application/pdf", err
+	default:
+		return renderText(doc), "text/plain; charset=utf-8", nil
+	}
+}
+
+// Render mengembalikan isi struk + content type. paper 0 = default template.
+// print = cetak sungguhan (dicatat); tanpa print hanya preview yang tidak mengubah hitungan.
+// Cetakan kedua dan seterusnya otomatis ditandai COPY (preview ikut COPY kalau sudah pernah dicetak);
+// PDF tidak dihitung sebagai cetakan karena dipakai untuk struk digital.
+func (s *ReceiptService) Render(transactionID int, format string, paper int, print bool) ([]byte, string, error) {
+	if format == "" {
+		format = ReceiptFormatText
+	}
+	switch format {
+	case ReceiptFormatESCPOS, ReceiptFormatText, ReceiptFormatHTML, ReceiptFormatPDF:
+	default:
+		return nil, "", errors.New("format harus escpos, text, html, atau pdf")
+	}
+	paper, err := s.paperOrDefault(paper)
+	if err != nil {
+		return nil, "", err
+	}
+
+	t, err := s.repo.GetByID(transactionID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	isCopy := false
+	switch {
+	case format == ReceiptFormatPDF:
+	case print:
+		printNo, err := s.repo.RecordReceiptPrint(transactionID, format)
+		if err != nil {
+			return nil, "", err
+		}
+		isCopy = printNo > 1
+	default:
+		printed, err := s.repo.ReceiptPrintCount(transactionID)
+		if err != nil {
+			return nil, "", err
+		}
+		isCopy = printed > 0
+	}
+
+	doc := buildReceipt(t, s.tmpl, s.logo, paper, isCopy, s.qrURL(t.ID, t.ReceiptNo))
+	return encodeReceipt(doc, format)
+}
+
+// RenderPublic = struk digital dari link/QR. Tanda tangan salah = dianggap tidak ada.
+func (s *ReceiptService) RenderPublic(transactionID int, sig, format string) ([]byte, string, error) {
+	if !s.signer.enabled() {
+		return nil, "", errors.New("struk digital belum diaktifkan")
+	}
+	if format == "" {
+		format = ReceiptFormatHTML
+	}
+	if format != ReceiptFormatHTML && format != ReceiptFormatPDF {
+		return nil, "", errors.New("format harus html atau pdf")
+	}
+
+	t, err := s.repo.GetByID(transactionID)
+	if err != nil || !s.signer.verify(t.ID, t.ReceiptNo, sig) {
+		return nil, "", errors.New("struk tidak ditemukan")
+	}
+
+	refund, err := s.repo.GetRefund(t.ID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	paper, _ := s.paperOrDefault(0)
+	doc := buildReceipt(t, s.tmpl, s.logo, paper, false, "")
+	if refund != nil {
+		// Struk asli tetap ditampilkan, tapi jangan sampai dipakai sebagai bukti bayar yang masih berlaku.
+		doc.divider()
+		doc.center("TRANSAKSI SUDAH DI-REFUND", true, true)
+		doc.leftRight("Refund", "Rp"+formatRupiah(refund.TotalAmount), true)
+		doc.leftRight("Tgl refund", outletTime(refund.CreatedAt, t.OutletTimezone).Format("02/01/2006 15:04"), false)
+		return encodeReceipt(doc, format)
+	}
+	doc.center("STRUK DIGITAL TERVERIFIKASI", true, false)
+	return encodeReceipt(doc, format)
+}
+
+// EmailReceipt mengirim struk PDF + link struk digital ke pelanggan.
+func (s *ReceiptService) EmailReceipt(transactionID int, to string) error {
+	if s.mailer == nil {
+		return errors.New("email belum dikonfigurasi (SMTP_HOST kosong)")
+	}
+	addr, err := mail.ParseAddress(to)
+	if err != nil {
+		return errors.New("email tidak valid")
+	}
+
+	t, err := s.repo.GetByID(transactionID)
+	if err != nil {
+		return err
+	}
+	paper, _ := s.paperOrDefault(0)
+	link := s.qrURL(t.ID, t.ReceiptNo)
+
+	pdf, err := renderPDF(buildReceipt(t, s.tmpl, s.logo, paper, false, link))
+	if err != nil {
+		return err
+	}
+
+	store := s.tmpl.StoreName
+	if store == "" {
+		store = "Kasir"
+	}
+	body := "Terima kasih sudah berbelanja di " + store + ".\n\n" +
+		"No. struk: " + t.ReceiptNo + "\n" +
+		"Total: Rp" + formatRupiah(t.TotalAmount) + "\n"
+	if link != "" {
+		body += "\nLihat & verifikasi struk: " + link + "\n"
+	}
+
+	return s.mailer.Send(Mail{
+		To:      []string{addr.Address},
+		Subject: "Struk " + t.ReceiptNo + " - " + store,
+		Body:    body,
+		Attachments: []MailAttachment{{
+			Filename:    "struk-" + strconv.Itoa(t.ID) + ".pdf",
+			ContentType: "application/pdf",
+			Data:        pdf,
+		}},
+	})
+}
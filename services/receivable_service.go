@@ -0,0 +1,149 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"kasir-api/models"
+	"kasir-api/repositories"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	StatementJSON = "json"
+	StatementCSV  = "csv"
+	StatementText = "text" // siap dikirim sebagai pengingat (WA/SMS)
+)
+
+type ReceivableService struct {
+	repo      *repositories.ReceivableRepository
+	reports   *repositories.ReportRepository // hari bisnis outlet untuk filter periode
+	storeName string
+}
+
+func NewReceivableService(repo *repositories.ReceivableRepository, reports *repositories.ReportRepository, storeName string) *ReceivableService {
+	return &ReceivableService{repo: repo, reports: reports, storeName: storeName}
+}
+
+func (s *ReceivableService) GetAll() ([]models.CustomerReceivable, error) { return s.repo.GetAll() }
+
+func (s *ReceivableService) GetByCustomer(customerID int) (*models.CustomerReceivable, error) {
+	return s.repo.GetByCustomer(customerID)
+}
+
+func (s *ReceivableService) RecordPayment(customerID int, req models.ReceivablePaymentRequest) (*models.ReceivableEntry, error) {
+	if req.ShiftID <= 0 {
+		return nil, errors.New("shift_id wajib diisi")
+	}
+	if req.Amount <= 0 {
+		return nil, errors.New("amount harus > 0")
+	}
+	if req.Method == "" {
+		req.Method = models.PaymentCash
+	}
+	switch req.Method {
+	case models.PaymentCash, models.PaymentCard, models.PaymentQRIS, models.PaymentTransfer:
+	default:
+		return nil, fmt.Errorf("metode pembayaran %q tidak bisa dipakai bayar kasbon", req.Method)
+	}
+	return s.repo.RecordPayment(customerID, req)
+}
+
+// Statement periode from..to (YYYY-MM-DD, hari bisnis outlet default, inklusif).
+// Default: awal bulan ini sampai hari ini.
+func (s *ReceivableService) Statement(customerID int, from, to string) (*models.Statement, error) {
+	start, end, err := businessRange(s.reports, from, to, nil, func(today time.Time) time.Time {
+		return time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, today.Location())
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.Statement(customerID, start, end)
+}
+
+var statementTypeLabel = map[string]string{
+	models.ReceivableCharge:  "Kasbon",
+	models.ReceivablePayment: "Bayar",
+	models.ReceivableRefund:  "Refund",
+}
+
+func statementRef(l models.StatementLine) string {
+	if l.ReceiptNo != "" {
+		return l.ReceiptNo
+	}
+	if l.Method != "" {
+		return strings.ToUpper(l.Method)
+	}
+	return ""
+}
+
+func (s *ReceivableService) StatementCSV(st *models.Statement) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+
+	_ = w.Write([]string{"tanggal", "jenis", "referensi", "debit", "kredit", "saldo", "catatan"})
+	_ = w.Write([]string{st.From.Format("2006-01-02"), "Saldo awal", "", "", "", strconv.Itoa(st.OpeningBalance), ""})
+	for _, l := range st.Lines {
+		debit, credit := "", ""
+		if l.Amount >= 0 {
+			debit = strconv.Itoa(l.Amount)
+		} else {
+			credit = strconv.Itoa(-l.Amount)
+		}
+		_ = w.Write([]string{
+			l.CreatedAt.Format("2006-01-02 15:04"), statementTypeLabel[l.Type], statementRef(l),
+			debit, credit, strconv.Itoa(l.Balance), l.Notes,
+		})
+	}
+	_ = w.Write([]string{st.To.AddDate(0, 0, -1).Format("2006-01-02"), "Saldo akhir", "", "", "", strconv.Itoa(st.ClosingBalance), ""})
+
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// StatementText = rekening koran singkat + pengingat, teks polos.
+func (s *ReceivableService) StatementText(st *models.Statement) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Yth. %s,\n\n", st.CustomerName)
+	store := s.storeName
+	if store == "" {
+		store = "toko kami"
+	}
+	fmt.Fprintf(&b, "Rincian kasbon di %s periode %s s/d %s:\n\n",
+		store, st.From.Format("02/01/2006"), st.To.AddDate(0, 0, -1).Format("02/01/2006"))
+	fmt.Fprintf(&b, "Saldo awal: Rp%s\n", formatRupiah(st.OpeningBalance))
+	for _, l := range st.Lines {
+		sign := "+"
+		if l.Amount < 0 {
+			sign = "-"
+		}
+		ref := statementRef(l)
+		if ref != "" {
+			ref = " " + ref
+		}
+		fmt.Fprintf(&b, "%s %s%s %sRp%s\n",
+			l.CreatedAt.Format("02/01"), statementTypeLabel[l.Type], ref, sign, formatRupiah(abs(l.Amount)))
+	}
+	fmt.Fprintf(&b, "Saldo akhir: Rp%s\n\n", formatRupiah(st.ClosingBalance))
+
+	if st.Balance > 0 {
+		fmt.Fprintf(&b, "Sisa kasbon saat ini Rp%s", formatRupiah(st.Balance))
+		if overdue := st.Aging.Days60 + st.Aging.Days90 + st.Aging.Over90; overdue > 0 {
+			fmt.Fprintf(&b, ", Rp%s di antaranya sudah lewat 30 hari", formatRupiah(overdue))
+		}
+		b.WriteString(". Mohon dilunasi, terima kasih.\n")
+	} else {
+		b.WriteString("Tidak ada sisa kasbon. Terima kasih.\n")
+	}
+	return b.String()
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
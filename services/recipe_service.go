@@ -0,0 +1,30 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+type RecipeService struct {
+	repo *repositories.RecipeRepository
+}
+
+func NewRecipeService(repo *repositories.RecipeRepository) *RecipeService {
+	return &RecipeService{repo: repo}
+}
+
+func (s *RecipeService) Get(productID int) ([]models.RecipeItem, error) { return s.repo.Get(productID) }
+
+func (s *RecipeService) Set(productID int, items []models.RecipeItem) error {
+	for _, it := range items {
+		if it.Quantity <= 0 {
+			return fmt.Errorf("quantity bahan harus > 0 (ingredient_id=%d)", it.IngredientID)
+		}
+		if it.IngredientID == productID {
+			return errors.New("produk tidak bisa jadi bahan dirinya sendiri")
+		}
+	}
+	return s.repo.Set(productID, items)
+}
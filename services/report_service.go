@@ -1,25 +1,325 @@
 package services
 
 import (
+	"errors"
+	"fmt"
+	"kasir-api/models"
 	"kasir-api/repositories"
+	"math"
 	"time"
 )
 
 type ReportService struct {
-	repo *repositories.ReportRepository
+	repo    *repositories.ReportRepository
+	taxRate float64 // persen PPN, harga sudah termasuk pajak
 }
 
-func NewReportService(repo *repositories.ReportRepository) *ReportService {
-	return &ReportService{repo: repo}
+func NewReportService(repo *repositories.ReportRepository, taxRate float64) *ReportService {
+	return &ReportService{repo: repo, taxRate: taxRate}
 }
 
-func (s *ReportService) HariIni() (repositories.TodayReport, error) {
-	now := time.Now()
-	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
-	end := start.Add(24 * time.Hour)
-	return s.repo.GetReportByDateRange(start, end)
+// businessRange mengubah from..to (YYYY-MM-DD, hari bisnis, inklusif) jadi rentang waktu [start, end)
+// menurut zona waktu & jam tutup buku outlet (nil = outlet default). to kosong = hari bisnis berjalan,
+// from kosong = defaultFrom(hari bisnis berjalan).
+func businessRange(repo *repositories.ReportRepository, from, to string, outletID *int, defaultFrom func(today time.Time) time.Time) (time.Time, time.Time, error) {
+	var first, last, today time.Time
+	if from == "" || to == "" {
+		var err error
+		if today, err = repo.Today(outletID); err != nil {
+			return first, last, err
+		}
+	}
+
+	first, last = defaultFrom(today), today
+	if from != "" {
+		d, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			return first, last, errors.New("format from harus YYYY-MM-DD")
+		}
+		first = d
+	}
+	if to != "" {
+		d, err := time.Parse("2006-01-02", to)
+		if err != nil {
+			return first, last, errors.New("format to harus YYYY-MM-DD")
+		}
+		last = d
+	}
+	if last.Before(first) {
+		return first, last, errors.New("to harus setelah from")
+	}
+	return repo.DayBounds(first, last.AddDate(0, 0, 1), outletID)
+}
+
+// InvalidParamError = parameter laporan tidak valid (handler menjawab 400);
+// error lain dari laporan berarti kegagalan server.
+type InvalidParamError struct {
+	msg string
+}
+
+func (e *InvalidParamError) Error() string { return e.msg }
+
+func invalidParam(format string, args ...any) error {
+	return &InvalidParamError{msg: fmt.Sprintf(format, args...)}
+}
+
+// ReportFilter = filter umum /api/report: outlet_id (kosong = semua outlet)
+// dan group_by=outlet untuk rincian per outlet.
+type ReportFilter struct {
+	OutletID *int
+	GroupBy  string
+}
+
+const GroupByOutlet = "outlet"
+
+func (f ReportFilter) byOutlet() bool { return f.GroupBy == GroupByOutlet }
+
+// HariIni = hari bisnis berjalan di outlet yang difilter (tanpa filter: outlet default).
+func (s *ReportService) HariIni(f ReportFilter) (repositories.TodayReport, error) {
+	today, err := s.repo.Today(f.OutletID)
+	if err != nil {
+		return repositories.TodayReport{}, err
+	}
+	return s.repo.GetReportByDateRange(today, today.AddDate(0, 0, 1), f.OutletID, f.byOutlet())
+}
+
+// Range: start/end = hari bisnis [start, end), tiap transaksi dihitung di hari bisnis outletnya.
+func (s *ReportService) Range(start, end time.Time, f ReportFilter) (repositories.TodayReport, error) {
+	return s.repo.GetReportByDateRange(start, end, f.OutletID, f.byOutlet())
+}
+
+// XReport = snapshot hari bisnis berjalan, tidak disimpan. Rincian per outlet selalu ikut.
+func (s *ReportService) XReport(f ReportFilter) (*models.DayReport, error) {
+	today, err := s.repo.Today(f.OutletID)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetDayReport(today.Format("2006-01-02"), f.OutletID, s.taxRate)
+}
+
+// IngredientUsage: start/end kosong = hari bisnis berjalan.
+func (s *ReportService) IngredientUsage(start, end time.Time, f ReportFilter) ([]models.IngredientUsage, error) {
+	if start.IsZero() || end.IsZero() {
+		today, err := s.repo.Today(f.OutletID)
+		if err != nil {
+			return nil, err
+		}
+		if start.IsZero() {
+			start = today
+		}
+		if end.IsZero() {
+			end = today.AddDate(0, 0, 1)
+		}
+	}
+	return s.repo.IngredientUsage(start, end, f.OutletID, f.byOutlet())
+}
+
+// ExpiringLots: days kosong (0) = 30 hari ke depan, maksimal setahun.
+func (s *ReportService) ExpiringLots(days int, outletID *int) ([]models.StockLot, error) {
+	if days <= 0 {
+		days = 30
+	}
+	if days > 365 {
+		days = 365
+	}
+	return s.repo.ExpiringLots(days, outletID)
+}
+
+// salesMaxDays membatasi panjang periode per granularity supaya deretnya tidak kebanyakan titik.
+var salesMaxDays = map[string]int{
+	models.BucketHour:  31,
+	models.BucketDay:   366,
+	models.BucketWeek:  5 * 366,
+	models.BucketMonth: 5 * 366,
+}
+
+// Sales = deret penjualan hari bisnis [start, end) plus periode pembanding yang sama panjang
+// tepat sebelumnya. start/end kosong = periode default granularity sampai hari ini:
+// hour = hari ini, day = 7 hari, week = 12 minggu, month = 12 bulan.
+func (s *ReportService) Sales(granularity string, start, end time.Time, outletID *int) (*models.SalesReport, error) {
+	if granularity == "" {
+		granularity = models.BucketDay
+	}
+	maxDays, ok := salesMaxDays[granularity]
+	if !ok {
+		return nil, invalidParam("granularity yang didukung: hour, day, week, month")
+	}
+
+	if start.IsZero() {
+		today, err := s.repo.Today(outletID)
+		if err != nil {
+			return nil, err
+		}
+		end = today.AddDate(0, 0, 1)
+		switch granularity {
+		case models.BucketHour:
+			start = today
+		case models.BucketDay:
+			start = today.AddDate(0, 0, -6)
+		case models.BucketWeek:
+			monday := today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+			start = monday.AddDate(0, 0, -7*11)
+		case models.BucketMonth:
+			start = time.Date(today.Year(), today.Month()-11, 1, 0, 0, 0, 0, today.Location())
+		}
+	}
+	if !end.After(start) {
+		return nil, invalidParam("end_date tidak boleh sebelum start_date")
+	}
+	days := int(end.Sub(start).Hours()/24 + 0.5)
+	if days > maxDays {
+		return nil, invalidParam("periode %s maksimal %d hari", granularity, maxDays)
+	}
+
+	// Periode bulan penuh dibandingkan dengan bulan-bulan sebelumnya, selain itu mundur sejumlah hari
+	prevStart := start.AddDate(0, 0, -days)
+	if start.Day() == 1 && end.Day() == 1 {
+		months := (end.Year()-start.Year())*12 + int(end.Month()-start.Month())
+		prevStart = start.AddDate(0, -months, 0)
+	}
+
+	current, err := s.salesPeriod(granularity, start, end, outletID)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := s.salesPeriod(granularity, prevStart, start, outletID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.SalesReport{
+		Granularity: granularity,
+		OutletID:    outletID,
+		Current:     current,
+		Previous:    previous,
+		Change: models.SalesChange{
+			TotalSales:     percentChange(current.Total.TotalSales, previous.Total.TotalSales),
+			TotalTransaksi: percentChange(current.Total.TotalTransaksi, previous.Total.TotalTransaksi),
+			ItemTerjual:    percentChange(current.Total.ItemTerjual, previous.Total.ItemTerjual),
+			AvgBasket:      percentChange(current.Total.AvgBasket, previous.Total.AvgBasket),
+		},
+	}, nil
 }
 
-func (s *ReportService) Range(start, end time.Time) (repositories.TodayReport, error) {
-	return s.repo.GetReportByDateRange(start, end)
+func (s *ReportService) salesPeriod(granularity string, start, end time.Time, outletID *int) (models.SalesPeriod, error) {
+	series, err := s.repo.SalesSeries(granularity, start, end, outletID)
+	if err != nil {
+		return models.SalesPeriod{}, err
+	}
+	p := models.SalesPeriod{
+		StartDate: start.Format("2006-01-02"),
+		EndDate:   end.AddDate(0, 0, -1).Format("2006-01-02"),
+		Series:    series,
+	}
+	for _, b := range series {
+		p.Total.TotalSales += b.TotalSales
+		p.Total.TotalTransaksi += b.TotalTransaksi
+		p.Total.ItemTerjual += b.ItemTerjual
+	}
+	if p.Total.TotalTransaksi > 0 {
+		p.Total.AvgBasket = p.Total.TotalSales / p.Total.TotalTransaksi
+	}
+	return p, nil
+}
+
+// percentChange dibulatkan 1 desimal; nil kalau pembandingnya 0.
+func percentChange(cur, prev int) *float64 {
+	if prev == 0 {
+		return nil
+	}
+	v := math.Round(float64(cur-prev)/float64(prev)*1000) / 10
+	return &v
+}
+
+// reportPeriod: start/end kosong = hari bisnis berjalan outlet (tanpa outlet: outlet default).
+func (s *ReportService) reportPeriod(start, end time.Time, outletID *int) (time.Time, time.Time, error) {
+	if start.IsZero() || end.IsZero() {
+		today, err := s.repo.Today(outletID)
+		if err != nil {
+			return start, end, err
+		}
+		if start.IsZero() {
+			start = today
+		}
+		if end.IsZero() {
+			end = today.AddDate(0, 0, 1)
+		}
+	}
+	if !end.After(start) {
+		return start, end, invalidParam("end_date tidak boleh sebelum start_date")
+	}
+	return start, end, nil
+}
+
+// ProductRanking: sortBy qty (default) / revenue, order top (default) / bottom, limit 1..100 (default 10).
+func (s *ReportService) ProductRanking(start, end time.Time, outletID *int, sortBy, order string, limit int) (*models.ProductRanking, error) {
+	if sortBy == "" {
+		sortBy = models.RankByQty
+	}
+	if sortBy != models.RankByQty && sortBy != models.RankByRevenue {
+		return nil, invalidParam("sort_by yang didukung: qty, revenue")
+	}
+	if order == "" {
+		order = "top"
+	}
+	if order != "top" && order != "bottom" {
+		return nil, invalidParam("order yang didukung: top, bottom")
+	}
+	if limit <= 0 {
+		limit = 10
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	start, end, err := s.reportPeriod(start, end, outletID)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := s.repo.ProductRanking(start, end, outletID, sortBy, order == "bottom", limit)
+	if err != nil {
+		return nil, err
+	}
+	return &models.ProductRanking{
+		StartDate: start.Format("2006-01-02"),
+		EndDate:   end.AddDate(0, 0, -1).Format("2006-01-02"),
+		OutletID:  outletID,
+		SortBy:    sortBy,
+		Order:     order,
+		Items:     items,
+	}, nil
+}
+
+// Breakdown = penjualan per category / cashier / payment, lengkap dengan porsinya.
+func (s *ReportService) Breakdown(by string, start, end time.Time, outletID *int) (*models.SalesBreakdown, error) {
+	switch by {
+	case models.BreakdownCategory, models.BreakdownCashier, models.BreakdownPayment:
+	default:
+		return nil, invalidParam("by yang didukung: category, cashier, payment")
+	}
+	start, end, err := s.reportPeriod(start, end, outletID)
+	if err != nil {
+		return nil, err
+	}
+
+	lines, err := s.repo.SalesBreakdown(by, start, end, outletID)
+	if err != nil {
+		return nil, err
+	}
+	rep := &models.SalesBreakdown{
+		By:        by,
+		StartDate: start.Format("2006-01-02"),
+		EndDate:   end.AddDate(0, 0, -1).Format("2006-01-02"),
+		OutletID:  outletID,
+		Lines:     lines,
+	}
+	for _, l := range lines {
+		rep.TotalSales += l.TotalSales
+	}
+	if rep.TotalSales > 0 {
+		for i := range rep.Lines {
+			rep.Lines[i].SharePct = math.Round(float64(rep.Lines[i].TotalSales)/float64(rep.TotalSales)*1000) / 10
+		}
+	}
+	return rep, nil
 }
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"kasir-api/repositories"
+	"log"
+	"time"
+)
+
+type SalesSummaryService struct {
+	repo *repositories.SalesSummaryRepository
+}
+
+func NewSalesSummaryService(repo *repositories.SalesSummaryRepository) *SalesSummaryService {
+	return &SalesSummaryService{repo: repo}
+}
+
+// RunSummaryJob meringkas hari bisnis yang sudah lewat saat start lalu tiap interval,
+// sampai ctx selesai.
+func (s *SalesSummaryService) RunSummaryJob(ctx context.Context, interval time.Duration) {
+	refresh := func() {
+		n, err := s.repo.Refresh()
+		if err != nil {
+			log.Printf("ringkasan penjualan gagal: %v", err)
+			return
+		}
+		log.Printf("ringkasan penjualan: %d hari-outlet diperbarui", n)
+	}
+
+	refresh()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			refresh()
+		}
+	}
+}
+
+// Rebuild menghitung ulang ringkasan mulai hari bisnis from (YYYY-MM-DD, kosong = semua data).
+func (s *SalesSummaryService) Rebuild(from string) (int, error) {
+	if from == "" {
+		return s.repo.Rebuild(nil)
+	}
+	d, err := time.Parse("2006-01-02", from)
+	if err != nil {
+		return 0, errors.New("format tanggal harus YYYY-MM-DD")
+	}
+	return s.repo.Rebuild(&d)
+}
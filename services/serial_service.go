@@ -0,0 +1,60 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"kasir-api/models"
+	"kasir-api/repositories"
+	"strings"
+)
+
+type SerialService struct {
+	repo *repositories.SerialRepository
+}
+
+func NewSerialService(repo *repositories.SerialRepository) *SerialService {
+	return &SerialService{repo: repo}
+}
+
+// normalizeSerials merapikan hasil scan: spasi dibuang, serial kosong / dobel ditolak.
+func normalizeSerials(serials []string) ([]string, error) {
+	if len(serials) == 0 {
+		return nil, nil
+	}
+	out := make([]string, 0, len(serials))
+	seen := make(map[string]bool, len(serials))
+	for _, sn := range serials {
+		sn = strings.TrimSpace(sn)
+		if sn == "" {
+			return nil, errors.New("serial number tidak boleh kosong")
+		}
+		if seen[sn] {
+			return nil, fmt.Errorf("serial %s disebut lebih dari sekali", sn)
+		}
+		seen[sn] = true
+		out = append(out, sn)
+	}
+	return out, nil
+}
+
+func (s *SerialService) GetAll(f models.SerialFilter) ([]models.ProductSerial, error) {
+	if f.Status != "" && f.Status != models.SerialInStock && f.Status != models.SerialSold {
+		return nil, errors.New("status yang didukung: in_stock, sold")
+	}
+	return s.repo.GetAll(f)
+}
+
+func (s *SerialService) Lookup(serialNumber string) ([]models.ProductSerial, error) {
+	serialNumber = strings.TrimSpace(serialNumber)
+	if serialNumber == "" {
+		return nil, errors.New("serial number wajib diisi")
+	}
+	out, err := s.repo.Lookup(serialNumber)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, errors.New("serial belum terdaftar")
+	}
+	return out, nil
+}
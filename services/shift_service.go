@@ -0,0 +1,65 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+type ShiftService struct {
+	repo *repositories.ShiftRepository
+}
+
+func NewShiftService(repo *repositories.ShiftRepository) *ShiftService {
+	return &ShiftService{repo: repo}
+}
+
+func (s *ShiftService) Open(req models.OpenShiftRequest) (*models.Shift, error) {
+	if req.CashierID <= 0 {
+		return nil, errors.New("cashier_id wajib diisi")
+	}
+	if req.OpeningFloat < 0 {
+		return nil, errors.New("opening_float tidak boleh negatif")
+	}
+	return s.repo.Open(req)
+}
+
+func (s *ShiftService) GetAll(status string) ([]models.Shift, error) {
+	return s.repo.GetAll(status)
+}
+
+func (s *ShiftService) GetByID(id int) (*models.Shift, error) { return s.repo.GetByID(id) }
+
+func (s *ShiftService) AddCashMovement(m *models.CashMovement) error {
+	if m.Type != models.CashPayIn && m.Type != models.CashPayOut {
+		return errors.New("type harus pay_in atau pay_out")
+	}
+	if m.Amount <= 0 {
+		return errors.New("amount harus > 0")
+	}
+	return s.repo.AddCashMovement(m)
+}
+
+func (s *ShiftService) GetCashMovements(shiftID int) ([]models.CashMovement, error) {
+	return s.repo.GetCashMovements(shiftID)
+}
+
+func (s *ShiftService) Summary(id int) (*models.ShiftSummary, error) { return s.repo.Summary(id) }
+
+func (s *ShiftService) Close(id int, req models.CloseShiftRequest) (*models.ShiftSummary, error) {
+	seen := make(map[int]bool, len(req.CashCounts))
+	for _, cc := range req.CashCounts {
+		if cc.Denomination <= 0 {
+			return nil, errors.New("denomination harus > 0")
+		}
+		if cc.Quantity < 0 {
+			return nil, fmt.Errorf("quantity tidak boleh negatif (denomination=%d)", cc.Denomination)
+		}
+		if seen[cc.Denomination] {
+			return nil, fmt.Errorf("denomination %d diinput lebih dari sekali", cc.Denomination)
+		}
+		seen[cc.Denomination] = true
+	}
+	return s.repo.Close(id, req)
+}
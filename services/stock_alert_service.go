@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+	"kasir-api/models"
+	"kasir-api/repositories"
+	"log"
+	"time"
+)
+
+// ReorderConfig = parameter saran pesan ulang.
+type ReorderConfig struct {
+	SalesDays int // jendela penjualan untuk kecepatan jual (default 28)
+	CoverDays int // stok yang ingin tersedia setelah barang datang, dalam hari (default 14)
+}
+
+type StockAlertService struct {
+	repo     *repositories.LowStockRepository
+	notifier StockNotifier
+	cfg      ReorderConfig
+}
+
+func NewStockAlertService(repo *repositories.LowStockRepository, notifier StockNotifier, cfg ReorderConfig) *StockAlertService {
+	if cfg.SalesDays <= 0 {
+		cfg.SalesDays = 28
+	}
+	if cfg.CoverDays <= 0 {
+		cfg.CoverDays = 14
+	}
+	if notifier == nil {
+		notifier = LogStockNotifier{}
+	}
+	return &StockAlertService{repo: repo, notifier: notifier, cfg: cfg}
+}
+
+// GetLow: outletID nil = semua outlet aktif.
+func (s *StockAlertService) GetLow(outletID *int) ([]models.LowStockItem, error) {
+	return s.repo.GetLow(outletID)
+}
+
+func (s *StockAlertService) RefreshSuggestions() (int, error) {
+	return s.repo.RefreshSuggestions(s.cfg.SalesDays, s.cfg.CoverDays)
+}
+
+// RunReorderJob menghitung ulang saran pesan ulang saat start lalu tiap interval, sampai ctx selesai.
+func (s *StockAlertService) RunReorderJob(ctx context.Context, interval time.Duration) {
+	refresh := func() {
+		n, err := s.RefreshSuggestions()
+		if err != nil {
+			log.Printf("reorder job gagal: %v", err)
+			return
+		}
+		log.Printf("reorder job: %d saran diperbarui", n)
+	}
+
+	refresh()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			refresh()
+		}
+	}
+}
+
+// Notify mengirim alert di latar belakang supaya checkout tidak menunggu notifier.
+func (s *StockAlertService) Notify(items []models.LowStockItem) {
+	if len(items) == 0 {
+		return
+	}
+	go func() {
+		if err := s.notifier.NotifyLowStock(items); err != nil {
+			log.Printf("alert stok menipis gagal dikirim: %v", err)
+		}
+	}()
+}
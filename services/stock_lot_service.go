@@ -0,0 +1,67 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"kasir-api/models"
+	"kasir-api/repositories"
+	"strings"
+	"time"
+)
+
+type StockLotService struct {
+	repo *repositories.StockLotRepository
+}
+
+func NewStockLotService(repo *repositories.StockLotRepository) *StockLotService {
+	return &StockLotService{repo: repo}
+}
+
+func (s *StockLotService) GetReceipts(outletID *int) ([]models.GoodsReceipt, error) {
+	return s.repo.GetReceipts(outletID)
+}
+
+func (s *StockLotService) GetReceipt(id int) (*models.GoodsReceipt, error) {
+	return s.repo.GetReceipt(id)
+}
+
+func (s *StockLotService) CreateReceipt(g *models.GoodsReceipt) (*models.GoodsReceipt, error) {
+	if len(g.Items) == 0 {
+		return nil, errors.New("items tidak boleh kosong")
+	}
+	g.Supplier = strings.TrimSpace(g.Supplier)
+	g.Note = strings.TrimSpace(g.Note)
+	for i := range g.Items {
+		it := &g.Items[i]
+		if it.Quantity <= 0 {
+			return nil, fmt.Errorf("quantity harus > 0 (product_id=%d)", it.ProductID)
+		}
+		it.LotNumber = strings.TrimSpace(it.LotNumber)
+		serials, err := normalizeSerials(it.SerialNumbers)
+		if err != nil {
+			return nil, err
+		}
+		it.SerialNumbers = serials
+		if it.ExpiryDate != nil {
+			if _, err := time.Parse("2006-01-02", *it.ExpiryDate); err != nil {
+				return nil, fmt.Errorf("format expiry_date harus YYYY-MM-DD (product_id=%d)", it.ProductID)
+			}
+			if it.LotNumber == "" {
+				return nil, fmt.Errorf("expiry_date butuh lot_number (product_id=%d)", it.ProductID)
+			}
+		}
+	}
+	return s.repo.CreateReceipt(g)
+}
+
+func (s *StockLotService) GetLots(outletID *int, productID int, all bool) ([]models.StockLot, error) {
+	return s.repo.GetLots(outletID, productID, all)
+}
+
+func (s *StockLotService) AdjustLot(id int, req models.LotAdjustRequest) (*models.StockLot, error) {
+	if req.Quantity < 0 {
+		return nil, errors.New("quantity tidak boleh negatif")
+	}
+	req.Note = strings.TrimSpace(req.Note)
+	return s.repo.AdjustLot(id, req)
+}
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"fmt"
+	"kasir-api/models"
+	"log"
+	"strings"
+)
+
+// StockNotifier = tujuan alert stok menipis. Default ke log;
+// bisa diganti (email, chat, webhook) tanpa mengubah StockAlertService.
+type StockNotifier interface {
+	NotifyLowStock(items []models.LowStockItem) error
+}
+
+type LogStockNotifier struct{}
+
+func (LogStockNotifier) NotifyLowStock(items []models.LowStockItem) error {
+	for _, it := range items {
+		log.Printf("stok menipis: %s", lowStockLine(it))
+	}
+	return nil
+}
+
+// MailStockNotifier mengirim alert lewat Mailer yang sama dengan struk digital.
+type MailStockNotifier struct {
+	mailer Mailer
+	to     []string
+}
+
+func NewMailStockNotifier(mailer Mailer, to []string) *MailStockNotifier {
+	return &MailStockNotifier{mailer: mailer, to: to}
+}
+
+func (n *MailStockNotifier) NotifyLowStock(items []models.LowStockItem) error {
+	var b strings.Builder
+	b.WriteString("Stok produk berikut sudah di bawah reorder point:\n\n")
+	for _, it := range items {
+		b.WriteString("- " + lowStockLine(it) + "\n")
+	}
+	return n.mailer.Send(Mail{
+		To:      n.to,
+		Subject: fmt.Sprintf("Stok menipis: %d produk", len(items)),
+		Body:    b.String(),
+	})
+}
+
+func lowStockLine(it models.LowStockItem) string {
+	line := fmt.Sprintf("[%s] %s stok %d%s (reorder point %d)", it.OutletCode, it.ProductName, it.Stock, it.Unit, it.ReorderPoint)
+	if it.SuggestedQty > 0 {
+		line += fmt.Sprintf(", saran pesan %d%s", it.SuggestedQty, it.Unit)
+	}
+	return line
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"kasir-api/models"
+	"kasir-api/repositories"
+	"strings"
+	"time"
+)
+
+type StockTransferService struct {
+	repo    *repositories.StockTransferRepository
+	reports *repositories.ReportRepository // hari bisnis outlet untuk filter periode
+}
+
+func NewStockTransferService(repo *repositories.StockTransferRepository, reports *repositories.ReportRepository) *StockTransferService {
+	return &StockTransferService{repo: repo, reports: reports}
+}
+
+func validateTransfer(t *models.StockTransfer) error {
+	if t.FromOutletID == 0 || t.ToOutletID == 0 {
+		return errors.New("from_outlet_id dan to_outlet_id wajib diisi")
+	}
+	if t.FromOutletID == t.ToOutletID {
+		return errors.New("outlet asal dan tujuan harus berbeda")
+	}
+	t.Note = strings.TrimSpace(t.Note)
+	for _, it := range t.Items {
+		if it.Quantity <= 0 {
+			return fmt.Errorf("quantity harus > 0 (product_id=%d)", it.ProductID)
+		}
+	}
+	return nil
+}
+
+func (s *StockTransferService) GetAll(status string, outletID int) ([]models.StockTransfer, error) {
+	return s.repo.GetAll(status, outletID)
+}
+
+func (s *StockTransferService) GetByID(id int) (*models.StockTransfer, error) {
+	return s.repo.GetByID(id)
+}
+
+func (s *StockTransferService) Create(t *models.StockTransfer) (*models.StockTransfer, error) {
+	if err := validateTransfer(t); err != nil {
+		return nil, err
+	}
+	return s.repo.Create(t)
+}
+
+func (s *StockTransferService) Update(t *models.StockTransfer) (*models.StockTransfer, error) {
+	if err := validateTransfer(t); err != nil {
+		return nil, err
+	}
+	return s.repo.Update(t)
+}
+
+func (s *StockTransferService) Send(id int) (*models.StockTransfer, error) { return s.repo.Send(id) }
+
+func (s *StockTransferService) Receive(id int, req models.ReceiveTransferRequest) (*models.StockTransfer, error) {
+	for _, it := range req.Items {
+		if it.ReceivedQuantity != nil && *it.ReceivedQuantity < 0 {
+			return nil, fmt.Errorf("received_quantity tidak boleh negatif (product_id=%d)", it.ProductID)
+		}
+	}
+	req.Note = strings.TrimSpace(req.Note)
+	return s.repo.Receive(id, req)
+}
+
+func (s *StockTransferService) Cancel(id int) (*models.StockTransfer, error) {
+	return s.repo.Cancel(id)
+}
+
+// Movements periode from..to (YYYY-MM-DD, hari bisnis outlet, inklusif). Default: 30 hari terakhir.
+func (s *StockTransferService) Movements(from, to string, outletID, productID int) ([]models.StockMovement, error) {
+	var outlet *int
+	if outletID > 0 {
+		outlet = &outletID
+	}
+	start, end, err := businessRange(s.reports, from, to, outlet, func(today time.Time) time.Time {
+		return today.AddDate(0, 0, -29)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.Movements(models.StockMovementFilter{
+		Start:     start,
+		End:       end,
+		OutletID:  outletID,
+		ProductID: productID,
+	})
+}
@@ -1,18 +1,68 @@
 package services
 
 import (
+	"errors"
 	"kasir-api/models"
 	"kasir-api/repositories"
 )
 
 type TransactionService struct {
-	repo *repositories.TransactionRepository
+	repo      *repositories.TransactionRepository
+	approvals *ApprovalService
+	alerts    *StockAlertService
 }
 
-func NewTransactionService(repo *repositories.TransactionRepository) *TransactionService {
-	return &TransactionService{repo: repo}
+func NewTransactionService(repo *repositories.TransactionRepository, approvals *ApprovalService, alerts *StockAlertService) *TransactionService {
+	return &TransactionService{repo: repo, approvals: approvals, alerts: alerts}
 }
 
-func (s *TransactionService) Checkout(items []models.CheckoutItem) (*models.Transaction, error) {
-	return s.repo.CreateTransaction(items)
+func (s *TransactionService) Checkout(req models.CheckoutRequest) (*models.Transaction, error) {
+	if req.CustomerPhone != "" {
+		req.CustomerPhone = NormalizePhone(req.CustomerPhone)
+	}
+	req.VoucherCode = NormalizeVoucherCode(req.VoucherCode)
+	for i := range req.Payments {
+		if req.Payments[i].Method == models.PaymentGiftCard {
+			req.Payments[i].Reference = NormalizeGiftCardCode(req.Payments[i].Reference)
+		}
+	}
+	for i := range req.Items {
+		serials, err := normalizeSerials(req.Items[i].SerialNumbers)
+		if err != nil {
+			return nil, err
+		}
+		req.Items[i].SerialNumbers = serials
+	}
+
+	// PIN manager dicek di sini; token approval dipakai di dalam tx checkout
+	approvedBy, err := s.approvals.CheckoutApproval(req.Approval, models.ApprovalSourceCheckout)
+	if err != nil {
+		return nil, err
+	}
+	req.ApprovedBy = approvedBy
+
+	t, err := s.repo.CreateTransaction(req)
+	if err != nil {
+		return nil, err
+	}
+	s.alerts.Notify(t.LowStock)
+	return t, nil
+}
+
+func (s *TransactionService) GetByID(id int) (*models.Transaction, error) {
+	return s.repo.GetByID(id)
+}
+
+func (s *TransactionService) GetAll(f models.TransactionFilter) ([]models.Transaction, error) {
+	if f.Limit <= 0 || f.Limit > 200 {
+		f.Limit = 50
+	}
+	return s.repo.GetAll(f)
+}
+
+func (s *TransactionService) Refund(id int, req models.RefundRequest) (*models.Refund, error) {
+	if req.ShiftID <= 0 {
+		return nil, errors.New("shift_id wajib diisi")
+	}
+	return s.repo.Refund(id, req)
 }
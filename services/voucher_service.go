@@ -0,0 +1,67 @@
+package services
+
+import (
+	"errors"
+	"kasir-api/models"
+	"kasir-api/repositories"
+	"strings"
+)
+
+type VoucherService struct {
+	repo *repositories.VoucherRepository
+}
+
+func NewVoucherService(repo *repositories.VoucherRepository) *VoucherService {
+	return &VoucherService{repo: repo}
+}
+
+// NormalizeVoucherCode: kode voucher tidak case-sensitive.
+func NormalizeVoucherCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func validateVoucher(v *models.Voucher) error {
+	v.Code = NormalizeVoucherCode(v.Code)
+	if v.Code == "" {
+		return errors.New("code wajib diisi")
+	}
+	switch v.Type {
+	case models.VoucherPercent:
+		if v.Value <= 0 || v.Value > 100 {
+			return errors.New("value voucher persen harus 1-100")
+		}
+	case models.VoucherFixed:
+		if v.Value <= 0 {
+			return errors.New("value harus > 0")
+		}
+	default:
+		return errors.New("type harus percent atau fixed")
+	}
+	if v.MinSpend < 0 || v.MaxDiscount < 0 || v.UsageLimit < 0 {
+		return errors.New("min_spend, max_discount dan usage_limit tidak boleh negatif")
+	}
+	if v.StartsAt != nil && v.EndsAt != nil && !v.EndsAt.After(*v.StartsAt) {
+		return errors.New("ends_at harus setelah starts_at")
+	}
+	return nil
+}
+
+func (s *VoucherService) GetAll() ([]models.Voucher, error) { return s.repo.GetAll() }
+
+func (s *VoucherService) Create(v *models.Voucher) error {
+	if err := validateVoucher(v); err != nil {
+		return err
+	}
+	return s.repo.Create(v)
+}
+
+func (s *VoucherService) GetByID(id int) (*models.Voucher, error) { return s.repo.GetByID(id) }
+
+func (s *VoucherService) Update(v *models.Voucher) error {
+	if err := validateVoucher(v); err != nil {
+		return err
+	}
+	return s.repo.Update(v)
+}
+
+func (s *VoucherService) Delete(id int) error { return s.repo.Delete(id) }